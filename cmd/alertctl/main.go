@@ -0,0 +1,268 @@
+// Command alertctl manages alert silences, acknowledgements and accepted DNS
+// records in the database used by domain-check-service. It connects with the same
+// INFO_DB_* environment variables.
+//
+//	alertctl silence create -network swan -duration 2h -author alice -reason "node upgrade"
+//	alertctl silence list [-all]
+//	alertctl silence expire ID
+//	alertctl alert list
+//	alertctl alert ack [-author NAME] FINGERPRINT
+//	alertctl dns accept NAME TYPE
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/swanchain/domain-check/pkg/alert"
+	"github.com/swanchain/domain-check/pkg/database"
+	"github.com/swanchain/domain-check/pkg/domaincheck"
+)
+
+const usage = `usage:
+  alertctl silence create [-check NAME] [-network NAME] [-domain NAME] [-wallet ADDRESS]
+                          [-start TIME] (-end TIME | -duration DURATION) -author NAME -reason TEXT
+  alertctl silence list [-all]
+  alertctl silence expire ID
+  alertctl alert list
+  alertctl alert ack [-author NAME] FINGERPRINT
+  alertctl dns accept NAME TYPE
+`
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "alertctl:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, out io.Writer) error {
+	if len(args) < 2 {
+		return errors.New(usage)
+	}
+	switch args[0] {
+	case "silence":
+		return runSilence(args[1:], out)
+	case "alert":
+		return runAlert(args[1:], out)
+	case "dns":
+		return runDNS(args[1:], out)
+	}
+	return errors.New(usage)
+}
+
+func runSilence(args []string, out io.Writer) error {
+	switch args[0] {
+	case "create":
+		silence, err := parseCreate(args[1:], time.Now())
+		if err != nil {
+			return err
+		}
+		db, err := database.ConnectToDB()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		silence, err = alert.CreateSilence(db, silence)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Created silence %d until %s\n", silence.ID, silence.EndsAt.Format(time.RFC3339))
+		return nil
+
+	case "list":
+		flags := flag.NewFlagSet("list", flag.ContinueOnError)
+		all := flags.Bool("all", false, "include silences that have ended")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		db, err := database.ConnectToDB()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		silences, err := alert.ListSilences(db, *all)
+		if err != nil {
+			return err
+		}
+		printSilences(out, silences, time.Now())
+		return nil
+
+	case "expire":
+		if len(args) != 2 {
+			return errors.New(usage)
+		}
+		id, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid silence ID %q", args[1])
+		}
+		db, err := database.ConnectToDB()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		if err := alert.ExpireSilence(db, id); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Expired silence %d\n", id)
+		return nil
+	}
+
+	return errors.New(usage)
+}
+
+func runAlert(args []string, out io.Writer) error {
+	switch args[0] {
+	case "list":
+		if len(args) != 1 {
+			return errors.New(usage)
+		}
+		db, err := database.ConnectToDB()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		states, err := alert.ListAlerts(db)
+		if err != nil {
+			return err
+		}
+		printAlerts(out, states)
+		return nil
+
+	case "ack":
+		flags := flag.NewFlagSet("ack", flag.ContinueOnError)
+		author := flags.String("author", os.Getenv("USER"), "who acknowledged the alert")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		if flags.NArg() != 1 {
+			return errors.New(usage)
+		}
+		if *author == "" {
+			return errors.New("-author is required")
+		}
+		db, err := database.ConnectToDB()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		state, err := alert.Acknowledge(db, flags.Arg(0), *author)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Acknowledged %s: %s\n", state.Fingerprint, state.Title)
+		return nil
+	}
+
+	return errors.New(usage)
+}
+
+func runDNS(args []string, out io.Writer) error {
+	if args[0] != "accept" || len(args) != 3 {
+		return errors.New(usage)
+	}
+	db, err := database.ConnectToDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	answers, err := domaincheck.AcceptDNS(db, args[1], args[2])
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Accepted %s records for %s: [%s]\n", strings.ToUpper(args[2]), args[1], strings.ReplaceAll(answers, "\n", " "))
+	return nil
+}
+
+// parseCreate reads the silence to create. Times are RFC 3339; the start
+// defaults to now.
+func parseCreate(args []string, now time.Time) (alert.Silence, error) {
+	var silence alert.Silence
+	var start, end string
+	var duration time.Duration
+
+	flags := flag.NewFlagSet("create", flag.ContinueOnError)
+	flags.StringVar(&silence.Check, "check", "", "check to silence, e.g. probe or ssl")
+	flags.StringVar(&silence.Network, "network", "", "network to silence, e.g. swan or sepolia")
+	flags.StringVar(&silence.Domain, "domain", "", "domain to silence, including its subdomains")
+	flags.StringVar(&silence.Wallet, "wallet", "", "wallet address to silence")
+	flags.StringVar(&start, "start", "", "start time (RFC 3339, default now)")
+	flags.StringVar(&end, "end", "", "end time (RFC 3339)")
+	flags.DurationVar(&duration, "duration", 0, "length of the silence, instead of -end")
+	flags.StringVar(&silence.Author, "author", os.Getenv("USER"), "who created the silence")
+	flags.StringVar(&silence.Reason, "reason", "", "why the silence is needed")
+	if err := flags.Parse(args); err != nil {
+		return silence, err
+	}
+	if flags.NArg() > 0 {
+		return silence, fmt.Errorf("unexpected argument %q", flags.Arg(0))
+	}
+
+	silence.StartsAt = now
+	if start != "" {
+		t, err := time.Parse(time.RFC3339, start)
+		if err != nil {
+			return silence, fmt.Errorf("invalid -start: %v", err)
+		}
+		silence.StartsAt = t
+	}
+
+	switch {
+	case end != "" && duration != 0:
+		return silence, errors.New("use either -end or -duration")
+	case end != "":
+		t, err := time.Parse(time.RFC3339, end)
+		if err != nil {
+			return silence, fmt.Errorf("invalid -end: %v", err)
+		}
+		silence.EndsAt = t
+	case duration != 0:
+		silence.EndsAt = silence.StartsAt.Add(duration)
+	default:
+		return silence, errors.New("-end or -duration is required")
+	}
+
+	return silence, silence.Validate()
+}
+
+func printSilences(out io.Writer, silences alert.Silences, now time.Time) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSTATUS\tMATCHES\tSTARTS\tENDS\tAUTHOR\tREASON")
+	for _, silence := range silences {
+		status := "active"
+		switch {
+		case !now.Before(silence.EndsAt):
+			status = "expired"
+		case now.Before(silence.StartsAt):
+			status = "pending"
+		}
+		matches := alert.Subject{Check: silence.Check, Network: silence.Network, Domain: silence.Domain, Wallet: silence.Wallet}.String()
+		if silence.Check == "" {
+			matches = "any check" + matches
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n", silence.ID, status, matches,
+			silence.StartsAt.Format(time.RFC3339), silence.EndsAt.Format(time.RFC3339), silence.Author, silence.Reason)
+	}
+	w.Flush()
+}
+
+func printAlerts(out io.Writer, states []alert.State) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "FINGERPRINT\tSTATE\tSEVERITY\tSUBJECT\tSINCE\tACKNOWLEDGED\tTITLE")
+	for _, state := range states {
+		acked := "-"
+		if state.AckedAt != nil {
+			acked = state.AckedBy + " at " + state.AckedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", state.Fingerprint, state.State, state.Severity, state.Subject(),
+			state.FirstSeen.Format(time.RFC3339), acked, state.Title)
+	}
+	w.Flush()
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/swanchain/domain-check/pkg/alert"
+)
+
+func TestParseCreate(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	silence, err := parseCreate([]string{"-network", "swan", "-duration", "2h", "-author", "alice", "-reason", "node upgrade"}, now)
+	if err != nil {
+		t.Fatalf("parseCreate() = %v", err)
+	}
+	if silence.Network != "swan" || !silence.StartsAt.Equal(now) || !silence.EndsAt.Equal(now.Add(2*time.Hour)) {
+		t.Errorf("parseCreate() = %+v", silence)
+	}
+
+	silence, err = parseCreate([]string{"-check", "ssl", "-domain", "swanchain.io", "-start", "2026-10-02T01:00:00Z",
+		"-end", "2026-10-02T03:00:00Z", "-author", "bob", "-reason", "certificate rollout"}, now)
+	if err != nil || silence.StartsAt.Hour() != 1 || silence.EndsAt.Hour() != 3 {
+		t.Errorf("parseCreate() = %+v, %v with -start and -end", silence, err)
+	}
+
+	for _, args := range [][]string{
+		{"-network", "swan", "-author", "alice", "-reason", "x"},
+		{"-network", "swan", "-duration", "1h", "-end", "2026-10-02T03:00:00Z", "-author", "alice", "-reason", "x"},
+		{"-duration", "1h", "-author", "alice", "-reason", "x"},
+		{"-network", "swan", "-duration", "1h", "-author", "alice"},
+		{"-network", "swan", "-duration", "-1h", "-author", "alice", "-reason", "x"},
+		{"-network", "swan", "-duration", "1h", "-author", "alice", "-reason", "x", "extra"},
+	} {
+		if _, err := parseCreate(args, now); err == nil {
+			t.Errorf("parseCreate(%q) succeeded", args)
+		}
+	}
+}
+
+func TestPrintSilences(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	silences := alert.Silences{
+		{ID: 1, Network: "swan", StartsAt: now.Add(-time.Hour), EndsAt: now.Add(time.Hour), Author: "alice", Reason: "node upgrade"},
+		{ID: 2, Check: "ssl", Domain: "swanchain.io", StartsAt: now.Add(time.Hour), EndsAt: now.Add(2 * time.Hour), Author: "bob", Reason: "rollout"},
+	}
+
+	var out bytes.Buffer
+	printSilences(&out, silences, now)
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("printSilences() printed %q", out.String())
+	}
+	if !strings.Contains(lines[1], "active") || !strings.Contains(lines[1], "any check network=swan") {
+		t.Errorf("Unexpected line %q", lines[1])
+	}
+	if !strings.Contains(lines[2], "pending") || !strings.Contains(lines[2], "ssl domain=swanchain.io") {
+		t.Errorf("Unexpected line %q", lines[2])
+	}
+}
+
+func TestPrintAlerts(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	states := []alert.State{
+		{Fingerprint: "dc88d10c6b0f4b1d9a15fb48325bacde", Check: "chain-status", State: alert.StateFiring, Severity: alert.SeverityCritical,
+			Network: "swan", Title: "Chain Status Warning", FirstSeen: now, AckedAt: &now, AckedBy: "alice"},
+		{Fingerprint: "0f1e", Check: "probe", State: alert.StatePending, Severity: alert.SeverityWarning, Network: "sepolia",
+			Title: "Probe Transaction Warning", FirstSeen: now},
+	}
+
+	var out bytes.Buffer
+	printAlerts(&out, states)
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("printAlerts() printed %q", out.String())
+	}
+	if !strings.Contains(lines[1], "chain-status severity=critical network=swan") || !strings.Contains(lines[1], "alice at 2026-10-01T12:00:00Z") {
+		t.Errorf("Unexpected line %q", lines[1])
+	}
+	if !strings.Contains(lines[2], "pending") || !strings.Contains(lines[2], "  -  ") {
+		t.Errorf("Unexpected line %q", lines[2])
+	}
+}
+
+func TestRunUsage(t *testing.T) {
+	for _, args := range [][]string{
+		nil, {"silence"}, {"silence", "mute"}, {"silence", "expire"}, {"silence", "expire", "x"},
+		{"alert"}, {"alert", "list", "x"}, {"alert", "ack"}, {"alert", "ack", "a", "b"},
+		{"dns"}, {"dns", "accept", "swanchain.io"}, {"dns", "reject", "swanchain.io", "A"},
+	} {
+		if err := run(args, &bytes.Buffer{}); err == nil {
+			t.Errorf("run(%q) succeeded", args)
+		}
+	}
+}
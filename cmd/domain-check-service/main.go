@@ -1,18 +1,24 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
 	"strings"
 	"time"
 
+	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
 	"github.com/robfig/cron"
+	"github.com/swanchain/domain-check/pkg/alert"
 	"github.com/swanchain/domain-check/pkg/chainstatus"
 	"github.com/swanchain/domain-check/pkg/database"
+	"github.com/swanchain/domain-check/pkg/domaincheck"
 	"github.com/swanchain/domain-check/pkg/model"
+	"github.com/swanchain/domain-check/pkg/notify"
+	"github.com/swanchain/domain-check/pkg/sslcert"
 	"github.com/swanchain/domain-check/pkg/wallet"
 )
 
@@ -89,6 +95,130 @@ func getTeamsWebhookURL(db *sqlx.DB) (string, error) {
 	return teamsWebhookURL, nil
 }
 
+// sendAlert delivers a notification to one target of its escalation policy.
+func sendAlert(db *sqlx.DB, target alert.Target, notification alert.Notification) {
+	switch target.Channel {
+	case alert.ChannelTeams:
+		webhookURL := target.Address
+		if webhookURL == "" {
+			var err error
+			if webhookURL, err = getTeamsWebhookURL(db); err != nil {
+				log.Println(err)
+				return
+			}
+		}
+		alert.SendTeamsNotification(webhookURL, notification, wallet.ExplorerURL(notification.State.Network, notification.State.Wallet))
+
+	case alert.ChannelEmail:
+		addresses := []string{target.Address}
+		if target.Address == "" {
+			recipients, err := getRecipients(db)
+			if err != nil {
+				log.Println(err)
+				return
+			}
+			addresses = nil
+			for _, recipient := range recipients {
+				addresses = append(addresses, recipient.Value)
+			}
+		}
+
+		emailConfig := sslcert.EmailConfig{
+			User: os.Getenv("ADMIN_EMAIL"),
+			Pass: os.Getenv("ADMIN_PW"),
+		}
+		body := notification.Text()
+		if facts := notification.State.Facts; len(facts) > 0 {
+			body += "\n\n" + alert.FactsText(facts)
+		}
+		for _, address := range addresses {
+			if err := sslcert.SendEmailWithSubject(emailConfig, address, notification.Title(), body); err != nil {
+				log.Printf("Error sending email to %s: %v", address, err)
+			}
+		}
+
+	case alert.ChannelSlack:
+		cfg, err := notify.GetSlackConfig(db)
+		if err != nil {
+			log.Println(err)
+			return
+		}
+		destination := target.Address
+		if destination == "" {
+			destination = cfg.Destination()
+		}
+		if destination == "" {
+			// Slack is part of the default policy but not configured.
+			return
+		}
+		notify.SendSlackAlert(db, notify.NewSlack(cfg.Token), destination, notification)
+
+	case alert.ChannelDiscord:
+		webhookURL := target.Address
+		if webhookURL == "" {
+			var err error
+			if webhookURL, err = notify.GetDiscordWebhook(db); err != nil {
+				log.Println(err)
+				return
+			}
+		}
+		notify.SendDiscordAlert(notify.NewDiscord(), webhookURL, notification)
+
+	case alert.ChannelTelegram:
+		cfg, err := notify.GetTelegramConfig(db)
+		if err != nil {
+			log.Println(err)
+			return
+		}
+		chatID := target.Address
+		if chatID == "" {
+			chatID = cfg.ChatID
+		}
+		notify.SendTelegramAlert(notify.NewTelegram(cfg.Token), chatID, notification)
+
+	case alert.ChannelPagerDuty:
+		routingKey := target.Address
+		if routingKey == "" {
+			var err error
+			if routingKey, err = notify.GetPagerDutyRoutingKey(db); err != nil {
+				log.Println(err)
+				return
+			}
+		}
+		notify.SendPagerDutyAlert(notify.NewPagerDuty(), routingKey, notification)
+
+	case alert.ChannelOpsgenie:
+		cfg, err := notify.GetOpsgenieConfig(db)
+		if err != nil {
+			log.Println(err)
+			return
+		}
+		if target.Address != "" {
+			cfg.APIKey = target.Address
+		}
+		notify.SendOpsgenieAlert(notify.NewOpsgenie(cfg), notification)
+
+	case alert.ChannelWebhook:
+		cfg, err := notify.GetWebhookConfig(db)
+		if err != nil {
+			log.Println(err)
+			return
+		}
+		url := target.Address
+		if url == "" {
+			url = cfg.URL
+		}
+		notify.SendWebhookAlert(notify.NewWebhook(cfg), url, notification)
+	}
+}
+
+func getNetworkRPCs() map[string]string {
+	return map[string]string{
+		"sepolia": wallet.GetSepoliaRPC(),
+		"swan":    wallet.GetSwanRPC(),
+	}
+}
+
 func main() {
 	db, err := database.ConnectToDB()
 	if err != nil {
@@ -98,44 +228,53 @@ func main() {
 	if err != nil {
 		log.Fatalln(err)
 	}
-	walletTask := func() {
-		log.Println("Wallet Scheduler started")
-		var messages []string
-		err := wallet.SetExplorerAndRpcVars(db)
+	alertConfig, err := alert.GetConfig(db)
+	if err != nil {
+		log.Println(err)
+	}
+	alertManager := alert.NewManager(db, alertConfig)
+	// processAlerts replaces the alerts check reported on its previous run
+	// with reported and sends what changed. Every task reports through it so
+	// that silences, routes and escalation apply to all of them.
+	processAlerts := func(check string, reported []alert.Alert) {
+		notifications, err := alertManager.Process(check, reported)
 		if err != nil {
 			log.Println(err)
-			return
 		}
-
-		l1Wallets, err := wallet.GetL1Wallet(db)
-		if err != nil {
-			log.Println(err)
+		alertManager.Prune(check)
+		if len(notifications) == 0 {
 			return
 		}
 
-		l2Wallets, err := wallet.GetL2Wallet(db)
+		for _, notification := range notifications {
+			log.Printf("%s: %s", notification.Title(), notification.Text())
+			for _, target := range notification.Targets {
+				sendAlert(db, target, notification)
+			}
+		}
+	}
+	walletTask := func() {
+		log.Println("Wallet Scheduler started")
+		err := wallet.SetExplorerAndRpcVars(db)
 		if err != nil {
 			log.Println(err)
 			return
 		}
 
-		emailConfig := wallet.EmailConfig{
-			User: os.Getenv("ADMIN_EMAIL"),
-			Pass: os.Getenv("ADMIN_PW"),
-		}
-
-		recipients, err := getRecipients(db)
+		l1Wallets, err := wallet.GetL1Wallet(db)
 		if err != nil {
 			log.Println(err)
 			return
 		}
 
-		teamsWebhookURL, err := getTeamsWebhookURL(db)
+		l2Wallets, err := wallet.GetL2Wallet(db)
 		if err != nil {
 			log.Println(err)
 			return
 		}
 
+		// The balances are reported as one event per run.
+		var facts []alert.Fact
 		for _, l1Wallet := range l1Wallets {
 			balance, err := wallet.CheckSepoliaBalance(l1Wallet.Value)
 			if err != nil {
@@ -154,8 +293,7 @@ func main() {
 				log.Println(err)
 				continue
 			}
-			message := fmt.Sprintf("Wallet balance for %s is %f.\nBalance change: %f\n", l1Wallet.Value, balance, balanceChange)
-			messages = append(messages, message)
+			facts = append(facts, wallet.BalanceFact("sepolia", l1Wallet.Value, balance, balanceChange))
 		}
 
 		for _, l2Wallet := range l2Wallets {
@@ -176,121 +314,647 @@ func main() {
 				log.Println(err)
 				continue
 			}
+			facts = append(facts, wallet.BalanceFact("swan", l2Wallet.Value, balance, balanceChange))
+		}
+
+		var summary []alert.Alert
+		if len(facts) > 0 {
+			summary = append(summary, alert.Alert{
+				Check:   "wallet",
+				Key:     "summary",
+				Title:   "Wallet Balance Change Update",
+				Message: fmt.Sprintf("Balances of %d wallets and their change since the last check.", len(facts)),
+				Event:   true,
+				Facts:   facts,
+			})
+		}
+		processAlerts("wallet", summary)
+
+		log.Println("Wallet Scheduler finished")
+	}
+
+	SSLtask := func() {
+		log.Println("SSL Scheduler started")
+
+		// cfg keeps the defaults for anything it could not read.
+		cfg, err := sslcert.GetSSLConfig(db)
+		if err != nil {
+			log.Println(err)
+		}
 
-			message := fmt.Sprintf("The balance for wallet %s is now %f.\nBalance change: %f\n", l2Wallet.Value, balance, balanceChange)
-			messages = append(messages, message)
+		domains, err := sslcert.GetDomains(db)
+		if err != nil {
+			log.Println(err)
+			return
 		}
+		log.Printf("Got %d domains", len(domains))
 
-		teamsMessage := strings.Join(messages, "\n")
-		err = wallet.SendTeamsNotification(teamsWebhookURL, teamsMessage, true)
+		pins, err := sslcert.GetPins(db)
 		if err != nil {
-			log.Printf("Error sending Teams notification: %v", err)
-		} else {
-			log.Println("Teams notification sent.")
+			log.Println(err)
 		}
 
-		emailBody := strings.Join(messages, "\n")
-		for _, recipient := range recipients {
-			err := wallet.SendWalletBalanceEmail(emailConfig, recipient.Value, emailBody)
+		// Each domain has an alert for the problems of its chains, a critical
+		// one for revocations and one for its earliest expiry; certificate
+		// changes are events per backend.
+		now := time.Now()
+		var alerts []alert.Alert
+		for _, domain := range domains {
+			reports, err := sslcert.InspectCertificate(domain.Value)
+			var problems []string
 			if err != nil {
-				log.Printf("Error sending email to %s: %v", recipient.Value, err)
-				continue
+				log.Println(err)
+				problems = append(problems, fmt.Sprintf("Could not check the SSL certificate for %s: %s", domain.Value, err))
+			}
+
+			var expiry time.Time
+			var expiring string
+			var revocations []string
+			for _, report := range reports {
+				if err := sslcert.RecordOCSP(db, report); err != nil {
+					log.Println(err)
+				}
+				problems = append(problems, report.Problems()...)
+				revocations = append(revocations, report.Revocations()...)
+				problems = append(problems, sslcert.CheckPins(report, pins[domain.Value])...)
+
+				change, err := sslcert.RecordFingerprint(db, report, pins[domain.Value])
+				if err != nil {
+					log.Println(err)
+				}
+				sslcert.PruneFingerprints(db, report.Domain, report.Address, now.Add(-cfg.Retention))
+				if change != nil {
+					changed := alert.Alert{
+						Check:    "ssl",
+						Key:      domain.Value + " " + report.Address + " change",
+						Severity: alert.SeverityCritical,
+						Domain:   domain.Value,
+						Title:    "SSL Certificate Changed Unexpectedly",
+						Message:  change.Message(),
+						Event:    true,
+					}
+					if change.Rotated {
+						changed.Severity, changed.Title = alert.SeverityWarning, "SSL Certificate Rotated"
+					}
+					alerts = append(alerts, changed)
+				}
+
+				if earliest := report.EarliestExpiry(); expiry.IsZero() || earliest.Before(expiry) {
+					expiry, expiring = earliest, report.Domain+" ("+report.Address+")"
+				}
+			}
+			problems = append(problems, sslcert.CompareBackends(reports)...)
+
+			if len(problems) > 0 {
+				for i, problem := range problems {
+					problems[i] = strings.TrimSuffix(problem, "\n")
+				}
+				log.Println(strings.Join(problems, " "))
+				alerts = append(alerts, alert.Alert{
+					Check:    "ssl",
+					Key:      domain.Value,
+					Severity: alert.SeverityWarning,
+					Domain:   domain.Value,
+					Title:    "SSL Certificate Warning",
+					Message:  strings.Join(problems, "\n"),
+				})
+			}
+
+			if len(revocations) > 0 {
+				log.Println(strings.Join(revocations, " "))
+				alerts = append(alerts, alert.Alert{
+					Check:    "ssl",
+					Key:      domain.Value + " revoked",
+					Severity: alert.SeverityCritical,
+					Domain:   domain.Value,
+					Title:    "SSL Certificate Revoked",
+					Message:  strings.Join(revocations, "\n"),
+				})
+			}
+
+			expiryAlert := alert.Alert{Check: "ssl", Key: domain.Value + " expiry", Severity: alert.SeverityWarning, Domain: domain.Value, Title: "SSL Certificate Expiration Warning"}
+			if len(reports) == 0 {
+				expiryAlert.Unknown = true
+				alerts = append(alerts, expiryAlert)
+			} else if window, ok := sslcert.WarningWindow(expiry, now, cfg.WarningWindows); ok {
+				expiryAlert.Message = strings.TrimSuffix(sslcert.ExpiryMessage(expiring, expiry, now, window), "\n")
+				expiryAlert.Level = sslcert.WarningLevel(expiry, now, cfg.WarningWindows)
+				alerts = append(alerts, expiryAlert)
 			}
-			log.Printf("Sent email to %s", recipient.Value)
 		}
+		processAlerts("ssl", alerts)
 
-		log.Println("Wallet Scheduler finished")
+		log.Println("SSL Scheduler finished")
+	}
+	chainStatusTask := func() {
+		swan_rpc := wallet.GetSwanRPC()
+		var firing []alert.Alert
+		if _, err := chainstatus.CheckChainStatus(swan_rpc); err != nil {
+			log.Println(err)
+			firing = append(firing, alert.Alert{Check: "chain-status", Key: "swan", Severity: alert.SeverityCritical, Network: "swan", Title: "Chain Status Warning", Message: err.Error()})
+		}
+		processAlerts("chain-status", firing)
 	}
-	/*
-		SSLtask := func() {
-			log.Println("SSL Scheduler started")
 
-			domains, err := sslcert.GetDomains(db)
+	l1PostingTask := func() {
+		cfg, err := chainstatus.GetPostingConfig(db)
+		if err != nil {
+			log.Println(err)
+			return
+		}
+		if cfg.BatchInbox == "" && cfg.OutputOracle == "" && cfg.DisputeGameFactory == "" {
+			return
+		}
+
+		postings, warnings, err := chainstatus.CheckL1Posting(wallet.GetSepoliaRPC(), cfg, time.Now())
+		if err != nil {
+			log.Println(err)
+			return
+		}
+
+		for _, posting := range postings {
+			if err := chainstatus.RecordPosting(db, posting); err != nil {
+				log.Println(err)
+			}
+		}
+
+		var firing []alert.Alert
+		if len(warnings) > 0 {
+			firing = append(firing, alert.Alert{Check: "l1-posting", Key: "sepolia", Severity: alert.SeverityCritical, Network: "sepolia", Title: "L1 Posting Warning", Message: strings.Join(warnings, "\n\n")})
+		}
+		processAlerts("l1-posting", firing)
+	}
+
+	gasMonitor := chainstatus.NewGasMonitor()
+	gasTask := func() {
+		cfg, err := chainstatus.GetGasConfig(db)
+		if err != nil {
+			log.Println(err)
+			return
+		}
+
+		var alerts []alert.Alert
+		for network, rpcURL := range getNetworkRPCs() {
+			gasAlert := alert.Alert{Check: "gas", Key: network, Severity: alert.SeverityWarning, Network: network, Title: "Gas Price Warning"}
+			sample, err := chainstatus.SampleGas(network, rpcURL)
 			if err != nil {
 				log.Println(err)
-				return
+				gasAlert.Unknown = true
+				alerts = append(alerts, gasAlert)
+				continue
 			}
-			log.Printf("Got %d domains", len(domains))
 
-			recipients, err := getRecipients(db)
+			if err := chainstatus.RecordGasSample(db, sample); err != nil {
+				log.Println(err)
+			}
+			chainstatus.PruneGasSamples(db, network, sample.SampledAt.Add(-cfg.Retention))
+
+			if warning := gasMonitor.Observe(sample, cfg); warning != "" {
+				gasAlert.Message = warning
+				alerts = append(alerts, gasAlert)
+			}
+		}
+		processAlerts("gas", alerts)
+	}
+
+	probeTask := func() {
+		cfg, err := chainstatus.GetProbeConfig(db)
+		if err != nil {
+			log.Println(err)
+			return
+		}
+
+		probes := []struct {
+			network string
+			rpcURL  string
+			key     string
+		}{
+			{"sepolia", wallet.GetSepoliaRPC(), os.Getenv("SEPOLIA_PROBE_KEY")},
+			{"swan", wallet.GetSwanRPC(), os.Getenv("SWAN_PROBE_KEY")},
+		}
+
+		var firing []alert.Alert
+		for _, probe := range probes {
+			if probe.key == "" {
+				continue
+			}
+
+			key, err := chainstatus.LoadProbeKey(probe.key)
 			if err != nil {
 				log.Println(err)
-				return
+				continue
 			}
-			log.Printf("Got %d recipients", len(recipients))
 
-			emailConfig := sslcert.EmailConfig{
-				User: os.Getenv("ADMIN_EMAIL"),
-				Pass: os.Getenv("ADMIN_PW"),
+			client, err := ethclient.Dial(probe.rpcURL)
+			if err != nil {
+				log.Println(err)
+				continue
 			}
 
-			teamsWebhookURL, err := getTeamsWebhookURL(db)
+			result, err := chainstatus.RunProbe(context.Background(), probe.network, client, key, cfg)
+			client.Close()
 			if err != nil {
 				log.Println(err)
-				return
+				result.Error = err.Error()
+				if result.SentAt.IsZero() {
+					result.SentAt = time.Now()
+				}
+				firing = append(firing, alert.Alert{Check: "probe", Key: probe.network, Severity: alert.SeverityWarning, Network: probe.network, Title: "Probe Transaction Warning", Message: err.Error()})
+			} else if warning := result.Warning(cfg); warning != "" {
+				firing = append(firing, alert.Alert{Check: "probe", Key: probe.network, Severity: alert.SeverityWarning, Network: probe.network, Title: "Probe Transaction Warning", Message: warning})
+			}
+
+			if err := chainstatus.RecordProbeResult(db, result); err != nil {
+				log.Println(err)
+			}
+			chainstatus.PruneProbeResults(db, probe.network, result.SentAt.Add(-cfg.Retention))
+		}
+		processAlerts("probe", firing)
+	}
+
+	contractTask := func() {
+		checks, err := chainstatus.GetContractChecks(db)
+		if err != nil {
+			log.Println(err)
+			return
+		}
+
+		rpcs := getNetworkRPCs()
+		var firing []alert.Alert
+		for _, check := range checks {
+			rpcURL, ok := rpcs[check.Network]
+			if !ok {
+				log.Printf("Unknown network %q for contract check %s", check.Network, check.Name)
+				continue
 			}
 
-			var emailMessages []string
-			var teamsMessages []string
-			for _, domain := range domains {
-				expireDate, err := sslcert.CheckCertificate(domain.Value)
+			result, checkErr := chainstatus.RunContractCheck(rpcURL, check, time.Now())
+			if err := chainstatus.RecordContractCheckResult(db, check, result, checkErr); err != nil {
+				log.Println(err)
+			}
+
+			if checkErr != nil {
+				firing = append(firing, alert.Alert{
+					Check:    "contract",
+					Key:      check.Name,
+					Severity: alert.SeverityCritical,
+					Network:  check.Network,
+					Title:    "Contract Check Warning",
+					Message:  fmt.Sprintf("Contract check %s on %s (%s) failed: %s.", check.Name, check.Network, check.Address, checkErr),
+				})
+			}
+		}
+		processAlerts("contract", firing)
+	}
+
+	tlsGradeTask := func() {
+		cfg, err := sslcert.GetSSLConfig(db)
+		if err != nil {
+			log.Println(err)
+		}
+
+		domains, err := sslcert.GetDomains(db)
+		if err != nil {
+			log.Println(err)
+			return
+		}
+
+		var regressions []alert.Alert
+		for _, domain := range domains {
+			scans, err := sslcert.ScanTLS(domain.Value)
+			if err != nil {
+				log.Println(err)
+			}
+
+			for _, scan := range scans {
+				log.Printf("TLS grade for %s (%s): %s", scan.Domain, scan.Address, scan.Grade)
+				found, err := sslcert.RecordTLSScan(db, scan)
 				if err != nil {
 					log.Println(err)
 					continue
 				}
-
-				if time.Until(expireDate) < 48*time.Hour {
-					expireMessage := fmt.Sprintf("The SSL certificate for %s will expire on %s.\n", domain.Value, expireDate.String())
-					log.Println(expireMessage)
-					emailMessages = append(emailMessages, expireMessage)
-					teamsMessages = append(teamsMessages, expireMessage)
+				sslcert.PruneTLSScans(db, scan.Domain, scan.Address, time.Now().Add(-cfg.Retention))
+				if len(found) == 0 {
+					continue
 				}
+				log.Println(strings.Join(found, " "))
+				regressions = append(regressions, alert.Alert{
+					Check:    "tls-grade",
+					Key:      domain.Value + " " + scan.Address,
+					Severity: alert.SeverityWarning,
+					Domain:   domain.Value,
+					Title:    "TLS Configuration Regression",
+					Message:  strings.Join(found, "\n\n"),
+					Event:    true,
+				})
 			}
+		}
+		processAlerts("tls-grade", regressions)
+	}
+
+	registrationTask := func() {
+		cfg, err := domaincheck.GetRegistrationConfig(db)
+		if err != nil {
+			log.Println(err)
+			return
+		}
+
+		domains, err := sslcert.GetDomains(db)
+		if err != nil {
+			log.Println(err)
+			return
+		}
 
-			if len(teamsMessages) > 0 {
-				teamsMessage := strings.Join(teamsMessages, "")
-				sslcert.SendTeamsNotification(teamsWebhookURL, teamsMessage, true)
-				log.Println("Teams notification sent.")
-			} else {
-				log.Println("No SSL certificates expiring in under 48 hours. No Teams notification sent.")
+		checker := domaincheck.NewRegistrationChecker()
+		checked := map[string]bool{}
+		now := time.Now()
+		var alerts []alert.Alert
+		for _, domain := range domains {
+			registrable, err := domaincheck.RegistrableDomain(domain.Value)
+			if err != nil {
+				log.Println(err)
+				continue
+			}
+			if checked[registrable] {
+				continue
+			}
+			checked[registrable] = true
+
+			expiring := alert.Alert{
+				Check:    "domain-registration",
+				Key:      registrable,
+				Severity: alert.SeverityWarning,
+				Domain:   registrable,
+				Title:    "Domain Registration Expiration Warning",
+			}
+			registration, err := checker.Lookup(registrable)
+			if err != nil {
+				log.Println(err)
+				expiring.Unknown = true
+				alerts = append(alerts, expiring)
+				continue
+			}
+			if err := domaincheck.RecordRegistration(db, registration); err != nil {
+				log.Println(err)
+			}
+
+			if message, ok := registration.ExpiryMessage(now, cfg.WarningWindows); ok {
+				log.Println(message)
+				expiring.Message = message
+				expiring.Level = sslcert.WarningLevel(registration.ExpiresAt, now, cfg.WarningWindows)
+				alerts = append(alerts, expiring)
+			}
+		}
+		processAlerts("domain-registration", alerts)
+	}
+
+	dnsTask := func() {
+		cfg, err := domaincheck.GetDNSConfig(db)
+		if err != nil {
+			log.Println(err)
+			return
+		}
+
+		domains, err := sslcert.GetDomains(db)
+		if err != nil {
+			log.Println(err)
+			return
+		}
+
+		records := map[string][]string{}
+		for _, domain := range domains {
+			host, err := domaincheck.Hostname(domain.Value)
+			if err != nil {
+				log.Println(err)
+				continue
 			}
+			records[host] = cfg.RecordTypes
+		}
+		for name, types := range cfg.Records {
+			records[name] = types
+		}
 
-			if len(emailMessages) > 0 {
-				emailBody := strings.Join(emailMessages, "")
-				for _, recipient := range recipients {
-					sslcert.SendEmail(emailConfig, recipient.Value, emailBody)
-					log.Printf("Sent email to %s", recipient.Value)
+		checker := domaincheck.NewDNSChecker(cfg.Resolvers)
+		var alerts []alert.Alert
+		for name, types := range records {
+			for _, result := range checker.Check(name, types) {
+				message, err := domaincheck.RecordDNS(db, result)
+				if err != nil {
+					log.Println(err)
+				}
+
+				warnings := result.Problems()
+				if message != "" {
+					warnings = append(warnings, message)
+				}
+				if len(warnings) == 0 {
+					continue
 				}
-			} else {
-				log.Println("No SSL certificates expiring in under 48 hours. No emails sent.")
+				log.Println(strings.Join(warnings, " "))
+				alerts = append(alerts, alert.Alert{
+					Check:    "dns",
+					Key:      name + " " + result.Type,
+					Severity: alert.SeverityWarning,
+					Domain:   name,
+					Title:    "DNS Record Warning",
+					Message:  strings.Join(warnings, "\n\n"),
+				})
+			}
+		}
+		processAlerts("dns", alerts)
+	}
+
+	httpTask := func() {
+		checks, err := domaincheck.GetHTTPChecks(db)
+		if err != nil {
+			log.Println(err)
+			return
+		}
+
+		domains, err := sslcert.GetDomains(db)
+		if err != nil {
+			log.Println(err)
+			return
+		}
+
+		seen := map[string]bool{}
+		for _, check := range checks {
+			seen[check.URL] = true
+		}
+		for _, domain := range domains {
+			if domaincheck.IsHTTPURL(domain.Value) && !seen[domain.Value] {
+				checks = append(checks, domaincheck.NewHTTPCheck(domain.Value))
+				seen[domain.Value] = true
 			}
+		}
 
-			log.Println("SSL Scheduler finished")
+		var alerts []alert.Alert
+		for _, check := range checks {
+			result := domaincheck.RunHTTPCheck(check)
+			if err := domaincheck.RecordHTTPCheckResult(db, check, result); err != nil {
+				log.Println(err)
+			}
+
+			if result.Err != nil {
+				message := fmt.Sprintf("%s is failing: %s.", check.URL, result.Err)
+				log.Println(message)
+				alerts = append(alerts, alert.Alert{
+					Check:    "http",
+					Key:      check.URL,
+					Severity: alert.SeverityWarning,
+					Domain:   check.URL,
+					Title:    "HTTP Check Warning",
+					Message:  message,
+				})
+			}
 		}
-	*/
-	chainStatusTask := func() {
-		swan_rpc := wallet.GetSwanRPC()
-		status, err := chainstatus.CheckChainStatus(swan_rpc)
+		processAlerts("http", alerts)
+	}
+
+	headerTask := func() {
+		domains, err := sslcert.GetDomains(db)
 		if err != nil {
 			log.Println(err)
-			teamsWebhookURL, err := getTeamsWebhookURL(db)
+			return
+		}
+
+		var changes []alert.Alert
+		for _, domain := range domains {
+			if !domaincheck.IsHTTPURL(domain.Value) {
+				continue
+			}
+
+			audit, err := domaincheck.AuditHeaders(domain.Value)
 			if err != nil {
 				log.Println(err)
-				return
+				continue
 			}
-			chainstatus.SendTeamsNotification(teamsWebhookURL, err.Error(), true)
+			messages, severity, err := domaincheck.RecordHeaderAudit(db, audit)
+			if err != nil {
+				log.Println(err)
+				continue
+			}
+			if len(messages) == 0 {
+				continue
+			}
+			log.Println(strings.Join(messages, " "))
+			changes = append(changes, alert.Alert{
+				Check:    "headers",
+				Key:      domain.Value,
+				Severity: severity,
+				Domain:   domain.Value,
+				Title:    "Security Header Audit",
+				Message:  strings.Join(messages, "\n\n"),
+				Event:    true,
+			})
+		}
+		processAlerts("headers", changes)
+	}
+
+	ctTask := func() {
+		cfg, err := domaincheck.GetCTConfig(db)
+		if err != nil {
+			log.Println(err)
 			return
 		}
 
-		if status != "healthy" {
-			log.Println("Less than 5 transactions in the last 10 blocks.")
-			teamsWebhookURL, err := getTeamsWebhookURL(db)
+		domains, err := sslcert.GetDomains(db)
+		if err != nil {
+			log.Println(err)
+			return
+		}
+
+		var registrable []string
+		seen := map[string]bool{}
+		for _, domain := range domains {
+			name, err := domaincheck.RegistrableDomain(domain.Value)
 			if err != nil {
 				log.Println(err)
-				return
+				continue
+			}
+			if !seen[name] {
+				seen[name] = true
+				registrable = append(registrable, name)
+			}
+		}
+
+		monitor := domaincheck.NewCTMonitor(cfg, registrable)
+		var found []domaincheck.CTCertificate
+		for _, logURL := range cfg.Logs {
+			from, ok, err := domaincheck.GetCTPosition(db, logURL)
+			if err != nil {
+				log.Println(err)
+				continue
+			}
+			if !ok {
+				from, err = monitor.TreeSize(logURL)
+				if err != nil {
+					log.Println(err)
+					continue
+				}
+			}
+
+			// A failed poll leaves the position where it was so the whole
+			// range is retried; certificates already found are recorded once
+			// either way. A new log still stores its starting point.
+			certs, next, err := monitor.PollLog(logURL, from)
+			found = append(found, certs...)
+			if err != nil {
+				log.Println(err)
+				if ok {
+					continue
+				}
+				next = from
+			}
+			if err := domaincheck.SetCTPosition(db, logURL, next); err != nil {
+				log.Println(err)
+			}
+		}
+
+		if cfg.CrtSh != "" {
+			for _, domain := range registrable {
+				source := "crtsh:" + domain
+				after, ok, err := domaincheck.GetCTPosition(db, source)
+				if err != nil {
+					log.Println(err)
+					continue
+				}
+
+				certs, latest, err := monitor.PollCrtSh(cfg.CrtSh, domain, after)
+				if err != nil {
+					log.Println(err)
+					continue
+				}
+				if ok {
+					found = append(found, certs...)
+				}
+				if err := domaincheck.SetCTPosition(db, source, latest); err != nil {
+					log.Println(err)
+				}
 			}
-			chainstatus.SendTeamsNotification(teamsWebhookURL, "Less than 5 transactions in the last 10 blocks.", true)
 		}
+
+		var unexpected []alert.Alert
+		for _, cert := range found {
+			isNew, err := domaincheck.RecordCTCertificate(db, cert)
+			if err != nil || !isNew {
+				continue
+			}
+			log.Printf("New certificate for %s from %s logged in %s", strings.Join(cert.Names, ", "), cert.Issuer, cert.Source)
+			if cert.ExpectedIssuer(cfg.Issuers) {
+				continue
+			}
+			unexpected = append(unexpected, alert.Alert{
+				Check:    "ct",
+				Key:      cert.Issuer + " " + strings.ToLower(cert.SerialNumber),
+				Severity: alert.SeverityWarning,
+				Domain:   cert.Names[0],
+				Title:    "Certificate Transparency Warning",
+				Message:  cert.Message(cfg.Issuers),
+				Event:    true,
+			})
+		}
+		processAlerts("ct", unexpected)
 	}
 
 	loc, err := time.LoadLocation("America/New_York")
@@ -299,7 +963,23 @@ func main() {
 	}
 	c := cron.NewWithLocation(loc)
 	c.AddFunc("0 30 9 * * *", walletTask)
-	//c.AddFunc("0 30 9 * * *", SSLtask)
+	sslConfig, err := sslcert.GetSSLConfig(db)
+	if err != nil {
+		log.Println(err)
+	}
+	if err := c.AddFunc(sslConfig.Schedule, SSLtask); err != nil {
+		log.Fatalf("Invalid SSL schedule %q: %v", sslConfig.Schedule, err)
+	}
+	c.AddFunc("0 */5 * * * *", l1PostingTask)
+	c.AddFunc("0 * * * * *", gasTask)
+	c.AddFunc("0 */10 * * * *", probeTask)
+	c.AddFunc("30 * * * * *", contractTask)
+	c.AddFunc("0 0 10 * * *", tlsGradeTask)
+	c.AddFunc("0 15 9 * * *", registrationTask)
+	c.AddFunc("0 */15 * * * *", dnsTask)
+	c.AddFunc("15 * * * * *", httpTask)
+	c.AddFunc("0 30 10 * * *", headerTask)
+	c.AddFunc("0 */10 * * * *", ctTask)
 	c.Start()
 	//lint:ignore ST1000 reason: using a ticker, so for { select {} } is appropriate here
 	go func() {
@@ -0,0 +1,646 @@
+package alert
+
+import (
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/swanchain/domain-check/pkg/model"
+	"github.com/swanchain/domain-check/pkg/teams"
+)
+
+// Alert states. A reported alert is pending until its check's Rule is met,
+// then firing until the check stops reporting it, resolved on the first run
+// after that and ok from then on.
+const (
+	StateOK       = "ok"
+	StatePending  = "pending"
+	StateFiring   = "firing"
+	StateResolved = "resolved"
+)
+
+// Notification kinds.
+const (
+	KindFiring       = "firing"
+	KindRepeat       = "repeat"
+	KindResolved     = "resolved"
+	KindFlapping     = "flapping"
+	KindFlapStopped  = "flap-stopped"
+	KindEscalated    = "escalated"
+	KindAcknowledged = "acknowledged"
+	KindEvent        = "event"
+)
+
+// Alert severities. Alerts without one are warnings.
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// Alert is a condition a check currently reports. Check and Key identify it
+// across runs, e.g. the contract check "contract" with the check's name as
+// Key, so the message may change without starting a new alert. Severity,
+// Network, Domain and Wallet say what it is about, for silences and routes.
+//
+// An Event is something that happened rather than a condition, such as a
+// certificate rotation or a balance update: it is notified every time it is
+// reported and never fires or resolves. An Unknown alert is one the check
+// could not evaluate this run, e.g. because a lookup failed; it keeps the
+// state it had instead of clearing. Level, if set, is how far the condition
+// has progressed, such as the number of warning windows an expiry has
+// entered: a firing alert is also notified again as soon as its level
+// rises. Facts are shown as a table with the
+// message.
+type Alert struct {
+	Check    string
+	Key      string
+	Title    string
+	Message  string
+	Severity string
+	Network  string
+	Domain   string
+	Wallet   string
+	Event    bool
+	Unknown  bool
+	Level    int
+	Facts    []Fact
+}
+
+// Fact is a row of an alert's table, such as a wallet and its balance.
+type Fact struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+}
+
+// FactsText lists the facts one per line, for channels without tables.
+func FactsText(facts []Fact) string {
+	lines := make([]string, len(facts))
+	for i, fact := range facts {
+		lines[i] = fact.Title + ": " + fact.Value
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (a Alert) Fingerprint() string {
+	sum := sha256.Sum256([]byte(a.Check + "\x00" + a.Key))
+	return hex.EncodeToString(sum[:16])
+}
+
+// timeList is stored as a JSON array.
+type timeList []time.Time
+
+func (l timeList) Value() (driver.Value, error) {
+	if l == nil {
+		l = timeList{}
+	}
+	encoded, err := json.Marshal([]time.Time(l))
+	return string(encoded), err
+}
+
+func (l *timeList) Scan(src interface{}) error {
+	var data []byte
+	switch v := src.(type) {
+	case nil:
+		*l = nil
+		return nil
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		return fmt.Errorf("cannot scan %T into a time list", src)
+	}
+	return json.Unmarshal(data, (*[]time.Time)(l))
+}
+
+// factList is stored as a JSON array.
+type factList []Fact
+
+func (l factList) Value() (driver.Value, error) {
+	if l == nil {
+		l = factList{}
+	}
+	encoded, err := json.Marshal([]Fact(l))
+	return string(encoded), err
+}
+
+func (l *factList) Scan(src interface{}) error {
+	var data []byte
+	switch v := src.(type) {
+	case nil:
+		*l = nil
+		return nil
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		return fmt.Errorf("cannot scan %T into a fact list", src)
+	}
+	return json.Unmarshal(data, (*[]Fact)(l))
+}
+
+// since returns the times at or after cutoff.
+func (l timeList) since(cutoff time.Time) timeList {
+	var recent timeList
+	for _, t := range l {
+		if !t.Before(cutoff) {
+			recent = append(recent, t)
+		}
+	}
+	return recent
+}
+
+// State is the persisted state of an alert in the alert_state table.
+// Failures holds the times of the current run of consecutive failures, up to
+// the number the rule needs, and Transitions the times the alert started
+// firing or resolved within the flap window. Escalation is the number of
+// steps of its escalation policy notified since it fired at FiredAt, and
+// Level the level it was last reported at.
+type State struct {
+	Fingerprint  string     `db:"fingerprint"`
+	Check        string     `db:"check_name"`
+	Key          string     `db:"alert_key"`
+	Title        string     `db:"title"`
+	State        string     `db:"state"`
+	Message      string     `db:"message"`
+	Severity     string     `db:"severity"`
+	Network      string     `db:"network"`
+	Domain       string     `db:"domain"`
+	Wallet       string     `db:"wallet"`
+	FirstSeen    time.Time  `db:"first_seen"`
+	LastSeen     time.Time  `db:"last_seen"`
+	LastNotified time.Time  `db:"last_notified"`
+	ResolvedAt   *time.Time `db:"resolved_at"`
+	Failures     timeList   `db:"failures"`
+	Transitions  timeList   `db:"transitions"`
+	Flapping     bool       `db:"flapping"`
+	FiredAt      time.Time  `db:"fired_at"`
+	Escalation   int        `db:"escalation"`
+	AckedAt      *time.Time `db:"acked_at"`
+	AckedBy      string     `db:"acked_by"`
+	Level        int        `db:"level"`
+	Facts        factList   `db:"facts"`
+}
+
+const stateColumns = `fingerprint, check_name, alert_key, title, state, message, severity, network, domain, wallet,
+	first_seen, last_seen, last_notified, resolved_at, failures, transitions, flapping, fired_at, escalation, acked_at, acked_by, level, facts`
+
+func (s State) Subject() Subject {
+	return Subject{Check: s.Check, Severity: s.Severity, Network: s.Network, Domain: s.Domain, Wallet: s.Wallet}
+}
+
+// Notification is something to send for an alert to Targets: that it
+// started firing, that it is still firing after the repeat interval, that it
+// escalated, that it was acknowledged, that it resolved, that it started or
+// stopped flapping, or an event.
+type Notification struct {
+	Kind    string
+	State   State
+	Targets []Target
+}
+
+// Resolved reports whether the notification says the alert is no longer
+// firing.
+func (n Notification) Resolved() bool {
+	return n.Kind == KindResolved || n.Kind == KindFlapStopped && n.State.State != StateFiring
+}
+
+func (n Notification) Title() string {
+	switch {
+	case n.Kind == KindEvent:
+		return n.State.Title
+	case n.Kind == KindFlapping:
+		return "[FLAPPING] " + n.State.Title
+	case n.Kind == KindEscalated:
+		return "[ESCALATED] " + n.State.Title
+	case n.Kind == KindAcknowledged:
+		return "[ACKNOWLEDGED] " + n.State.Title
+	case n.Resolved():
+		return "[RESOLVED] " + n.State.Title
+	default:
+		return "[FIRING] " + n.State.Title
+	}
+}
+
+func (n Notification) Text() string {
+	switch n.Kind {
+	case KindRepeat:
+		return fmt.Sprintf("%s\n\nFiring since %s.", n.State.Message, n.State.FirstSeen.Format(time.RFC3339))
+	case KindEscalated:
+		return fmt.Sprintf("%s\n\nFiring since %s and not acknowledged.", n.State.Message, n.State.FiredAt.Format(time.RFC3339))
+	case KindAcknowledged:
+		return fmt.Sprintf("Acknowledged by %s at %s: %s", n.State.AckedBy, n.State.AckedAt.Format(time.RFC3339), n.State.Message)
+	case KindResolved:
+		return fmt.Sprintf("Resolved after %s: %s", n.State.ResolvedAt.Sub(n.State.FirstSeen).Round(time.Second), n.State.Message)
+	case KindFlapping:
+		return fmt.Sprintf("Changed state %d times recently. Notifications are suppressed until it is stable.\n\nLatest: %s",
+			len(n.State.Transitions), n.State.Message)
+	case KindFlapStopped:
+		if n.State.State == StateFiring {
+			return fmt.Sprintf("Stopped flapping and is firing: %s", n.State.Message)
+		}
+		return fmt.Sprintf("Stopped flapping and is no longer firing. Latest: %s", n.State.Message)
+	default:
+		return n.State.Message
+	}
+}
+
+// Rule decides when a reported alert starts firing: after Failures
+// consecutive reports, all within Window if it is set.
+type Rule struct {
+	Failures int
+	Window   time.Duration
+}
+
+// defaultRules apply to checks without an 'alert-rule' row. Checks not listed
+// fire on the first report.
+var defaultRules = map[string]Rule{
+	"chain-status": {Failures: 3, Window: 5 * time.Minute},
+	"http":         {Failures: 3},
+}
+
+// Config is read from info rows of type 'alert' (repeat-minutes,
+// flap-window-minutes, flap-threshold and retention-days) and 'alert-rule',
+// keyed by check with a value of "N" or "N/M" for N consecutive failures
+// within M minutes.
+// An alert that starts firing or resolves FlapThreshold times within
+// FlapWindow is flapping: that is reported once and further notifications
+// are held back until it has not changed state for a whole FlapWindow.
+type Config struct {
+	RepeatInterval time.Duration
+	FlapWindow     time.Duration
+	FlapThreshold  int
+	Retention      time.Duration
+	Rules          map[string]Rule
+}
+
+func (c Config) Rule(check string) Rule {
+	if rule, ok := c.Rules[check]; ok {
+		return rule
+	}
+	if rule, ok := defaultRules[check]; ok {
+		return rule
+	}
+	return Rule{Failures: 1}
+}
+
+func GetConfig(db *sqlx.DB) (Config, error) {
+	cfg := Config{RepeatInterval: time.Hour, FlapWindow: time.Hour, FlapThreshold: 4, Retention: 30 * 24 * time.Hour, Rules: map[string]Rule{}}
+
+	var configs []model.Info
+	err := db.Select(&configs, "SELECT key, value FROM info WHERE is_active = true AND type = 'alert'")
+	if err != nil {
+		return cfg, err
+	}
+
+	for _, config := range configs {
+		switch config.Key {
+		case "repeat-minutes", "flap-window-minutes", "flap-threshold":
+			n, err := strconv.Atoi(config.Value)
+			if err != nil || n < 0 {
+				return cfg, fmt.Errorf("invalid alert %s %q", config.Key, config.Value)
+			}
+			switch config.Key {
+			case "repeat-minutes":
+				cfg.RepeatInterval = time.Duration(n) * time.Minute
+			case "flap-window-minutes":
+				cfg.FlapWindow = time.Duration(n) * time.Minute
+			case "flap-threshold":
+				cfg.FlapThreshold = n
+			}
+		case "retention-days":
+			days, err := strconv.Atoi(config.Value)
+			if err != nil || days < 1 {
+				return cfg, fmt.Errorf("invalid alert %s %q", config.Key, config.Value)
+			}
+			cfg.Retention = time.Duration(days) * 24 * time.Hour
+		}
+	}
+
+	var rules []model.Info
+	err = db.Select(&rules, "SELECT key, value FROM info WHERE is_active = true AND type = 'alert-rule'")
+	if err != nil {
+		return cfg, err
+	}
+	for _, rule := range rules {
+		parsed, err := parseRule(rule.Value)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid alert-rule for %s: %v", rule.Key, err)
+		}
+		cfg.Rules[rule.Key] = parsed
+	}
+
+	return cfg, nil
+}
+
+func parseRule(value string) (Rule, error) {
+	failures, minutes, hasWindow := strings.Cut(value, "/")
+	var rule Rule
+	var err error
+	if rule.Failures, err = strconv.Atoi(strings.TrimSpace(failures)); err != nil || rule.Failures < 1 {
+		return rule, fmt.Errorf("%q is not N or N/M", value)
+	}
+	if hasWindow {
+		m, err := strconv.Atoi(strings.TrimSpace(minutes))
+		if err != nil || m < 1 {
+			return rule, fmt.Errorf("%q is not N or N/M", value)
+		}
+		rule.Window = time.Duration(m) * time.Minute
+	}
+	return rule, nil
+}
+
+// Manager turns the alerts each check reports into notifications, sending
+// each alert once when it starts firing, again every RepeatInterval (never if
+// zero) and whenever its level rises while it keeps firing and is not
+// acknowledged, once on the first run after it is acknowledged and once when
+// it resolves. Events are sent each time they are reported. Notifications go
+// to the escalation policy the alert routes to: its first step when it fires,
+// later steps as their delays pass without an acknowledgement, and every step
+// notified so far after that.
+type Manager struct {
+	db     *sqlx.DB
+	config Config
+	now    func() time.Time
+}
+
+func NewManager(db *sqlx.DB, cfg Config) *Manager {
+	return &Manager{db: db, config: cfg, now: time.Now}
+}
+
+// Process records the alerts check reports now. Every alert of the check
+// that is not in reported has cleared, unless it is reported as Unknown.
+// Alerts change state as usual while silenced, but their notifications are
+// dropped. If the routes cannot be read, notifications go to the default
+// policy.
+func (m *Manager) Process(check string, reported []Alert) ([]Notification, error) {
+	routing, routingErr := GetRouting(m.db)
+	notifications, err := m.process(check, reported, routing)
+	err = errors.Join(routingErr, err)
+
+	silences, silenceErr := GetActiveSilences(m.db)
+	if silenceErr != nil {
+		return notifications, errors.Join(err, silenceErr)
+	}
+	var unsilenced []Notification
+	for _, notification := range notifications {
+		if !silences.Silenced(notification.State.Subject()) {
+			unsilenced = append(unsilenced, notification)
+		}
+	}
+	return unsilenced, err
+}
+
+func (m *Manager) process(check string, reported []Alert, routing Routing) ([]Notification, error) {
+	var states []State
+	err := m.db.Select(&states, "SELECT "+stateColumns+" FROM alert_state WHERE check_name = $1", check)
+	if err != nil {
+		log.Printf("Error retrieving alert state for %s: %s", check, err)
+		return nil, err
+	}
+	previous := map[string]State{}
+	for _, state := range states {
+		previous[state.Fingerprint] = state
+	}
+
+	now := m.now()
+	rule := m.config.Rule(check)
+	var notifications []Notification
+	current := map[string]bool{}
+	for _, a := range reported {
+		fingerprint := a.Fingerprint()
+		if current[fingerprint] {
+			continue
+		}
+		current[fingerprint] = true
+		if a.Unknown {
+			continue
+		}
+
+		state, ok := previous[fingerprint]
+		if !ok {
+			state = State{Fingerprint: fingerprint, Check: check, Key: a.Key, State: StateOK}
+		}
+		state.Title, state.Message, state.LastSeen = a.Title, a.Message, now
+		state.Severity, state.Network, state.Domain, state.Wallet = a.Severity, a.Network, a.Domain, a.Wallet
+		state.Facts = a.Facts
+		if state.Severity == "" {
+			state.Severity = SeverityWarning
+		}
+
+		var sent []Notification
+		if a.Event {
+			state.FirstSeen, state.FiredAt, state.LastNotified = now, now, now
+			sent = []Notification{{Kind: KindEvent, State: state, Targets: routing.Policy(state.Subject()).targets(0, 1)}}
+		} else {
+			state, sent = m.step(state, true, a.Level, rule, routing.Policy(state.Subject()), now)
+		}
+		notifications = append(notifications, sent...)
+		if err := m.save(state); err != nil {
+			return notifications, err
+		}
+	}
+
+	for fingerprint, state := range previous {
+		if current[fingerprint] || (state.State == StateOK && !state.Flapping) {
+			continue
+		}
+		state, sent := m.step(state, false, 0, rule, routing.Policy(state.Subject()), now)
+		notifications = append(notifications, sent...)
+		if err := m.save(state); err != nil {
+			return notifications, err
+		}
+	}
+
+	return notifications, nil
+}
+
+// Prune deletes the states of check that have been ok for longer than
+// Retention, such as those of events.
+func (m *Manager) Prune(check string) error {
+	_, err := m.db.Exec("DELETE FROM alert_state WHERE check_name = $1 AND state = $2 AND NOT flapping AND last_seen < $3",
+		check, StateOK, m.now().Add(-m.config.Retention))
+	if err != nil {
+		log.Printf("Error pruning alert state for %s: %s", check, err)
+		return err
+	}
+	return nil
+}
+
+// step advances one alert by one run of its check, reported at level.
+func (m *Manager) step(state State, reported bool, level int, rule Rule, policy Policy, now time.Time) (State, []Notification) {
+	var notifications []Notification
+	raised := level > state.Level
+	state.Level = level
+	// Everyone notified about an alert hears how it ends; an alert that was
+	// never notified, e.g. one fired before routing existed, reaches the
+	// first step.
+	notified := func() []Target {
+		return policy.targets(0, max(state.Escalation, 1))
+	}
+	notify := func(kind string) {
+		if !state.Flapping {
+			notifications = append(notifications, Notification{Kind: kind, State: state, Targets: notified()})
+		}
+	}
+	transition := func() {
+		state.Transitions = append(state.Transitions.since(now.Add(-m.config.FlapWindow)), now)
+		if m.config.FlapThreshold > 0 && !state.Flapping && len(state.Transitions) >= m.config.FlapThreshold {
+			state.Flapping = true
+			notifications = append(notifications, Notification{Kind: KindFlapping, State: state, Targets: notified()})
+		}
+	}
+
+	switch {
+	case reported && state.State == StateFiring:
+		// Alerts that fired before escalation existed notified the first step.
+		state.Escalation = max(state.Escalation, 1)
+		if state.FiredAt.IsZero() {
+			state.FiredAt = state.FirstSeen
+		}
+		if reached := policy.reached(now.Sub(state.FiredAt)); reached > state.Escalation && state.AckedAt == nil && !state.Flapping {
+			targets := policy.targets(state.Escalation, reached)
+			state.Escalation = reached
+			state.LastNotified = now
+			notifications = append(notifications, Notification{Kind: KindEscalated, State: state, Targets: targets})
+		}
+		// Acknowledgements are made outside the service; pass each one on
+		// once. Nothing is notified after it until the alert resolves.
+		if state.AckedAt != nil && state.AckedAt.After(state.LastNotified) {
+			state.LastNotified = now
+			notify(KindAcknowledged)
+		}
+		repeat := m.config.RepeatInterval > 0 && now.Sub(state.LastNotified) >= m.config.RepeatInterval
+		if (repeat || raised) && state.AckedAt == nil {
+			state.LastNotified = now
+			notify(KindRepeat)
+		}
+
+	case reported:
+		if state.State != StatePending {
+			state.Failures = nil
+		}
+		state.State = StatePending
+		state.Failures = append(state.Failures, now)
+		if len(state.Failures) > rule.Failures {
+			state.Failures = state.Failures[len(state.Failures)-rule.Failures:]
+		}
+		if len(state.Failures) == rule.Failures && (rule.Window == 0 || now.Sub(state.Failures[0]) <= rule.Window) {
+			state.State = StateFiring
+			state.FirstSeen = state.Failures[0]
+			state.FiredAt = now
+			state.LastNotified = now
+			state.ResolvedAt = nil
+			state.AckedAt, state.AckedBy = nil, ""
+			state.Escalation = 1
+			transition()
+			notify(KindFiring)
+		}
+
+	case state.State == StateFiring:
+		state.State = StateResolved
+		state.ResolvedAt = &now
+		state.Failures = nil
+		transition()
+		notify(KindResolved)
+
+	case state.State == StateResolved, state.State == StatePending:
+		state.State = StateOK
+		state.Failures = nil
+	}
+
+	// An alert stops flapping once it has not changed state for a whole
+	// flap window. Say where it ended up, since changes were held back.
+	if state.Flapping && len(state.Transitions.since(now.Add(-m.config.FlapWindow))) == 0 {
+		state.Flapping = false
+		state.Transitions = nil
+		state.LastNotified = now
+		notifications = append(notifications, Notification{Kind: KindFlapStopped, State: state, Targets: notified()})
+	}
+
+	return state, notifications
+}
+
+// save upserts the state. An acknowledgement made while the check ran is
+// kept unless the alert fired again.
+func (m *Manager) save(state State) error {
+	_, err := m.db.Exec(`
+		INSERT INTO alert_state (fingerprint, check_name, alert_key, title, state, message, severity, network, domain, wallet,
+			first_seen, last_seen, last_notified, resolved_at, failures, transitions, flapping, fired_at, escalation, acked_at, acked_by, level, facts)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23)
+		ON CONFLICT (fingerprint) DO UPDATE
+		SET title = EXCLUDED.title, state = EXCLUDED.state, message = EXCLUDED.message, severity = EXCLUDED.severity,
+			network = EXCLUDED.network, domain = EXCLUDED.domain, wallet = EXCLUDED.wallet, first_seen = EXCLUDED.first_seen,
+			last_seen = EXCLUDED.last_seen, last_notified = EXCLUDED.last_notified, resolved_at = EXCLUDED.resolved_at,
+			failures = EXCLUDED.failures, transitions = EXCLUDED.transitions, flapping = EXCLUDED.flapping,
+			fired_at = EXCLUDED.fired_at, escalation = EXCLUDED.escalation,
+			level = EXCLUDED.level, facts = EXCLUDED.facts,
+			acked_at = CASE WHEN EXCLUDED.fired_at = alert_state.fired_at
+				THEN COALESCE(EXCLUDED.acked_at, alert_state.acked_at) ELSE EXCLUDED.acked_at END,
+			acked_by = CASE WHEN EXCLUDED.fired_at = alert_state.fired_at AND EXCLUDED.acked_at IS NULL
+				THEN alert_state.acked_by ELSE EXCLUDED.acked_by END
+	`, state.Fingerprint, state.Check, state.Key, state.Title, state.State, state.Message, state.Severity, state.Network, state.Domain, state.Wallet,
+		state.FirstSeen, state.LastSeen, state.LastNotified, state.ResolvedAt, state.Failures, state.Transitions, state.Flapping,
+		state.FiredAt, state.Escalation, state.AckedAt, state.AckedBy, state.Level, state.Facts)
+	if err != nil {
+		log.Printf("Error recording alert state for %s/%s: %s", state.Check, state.Key, err)
+		return err
+	}
+	return nil
+}
+
+// TeamsCard lays out a notification as an Adaptive Card colored by what it
+// says, with what the alert is about as facts and a button to explorerURL,
+// if there is one.
+func TeamsCard(notification Notification, explorerURL string) *teams.Card {
+	state := notification.State
+	style := teams.StyleWarning
+	switch {
+	case notification.Resolved():
+		style = teams.StyleGood
+	case notification.Kind == KindFlapping:
+		style = teams.StyleDefault
+	case notification.Kind == KindAcknowledged, state.Severity == SeverityInfo:
+		style = teams.StyleAccent
+	case state.Severity == SeverityCritical:
+		style = teams.StyleAttention
+	}
+
+	card := teams.NewCard(notification.Title(), notification.Text(), style)
+	if len(state.Facts) > 0 {
+		table := make([]teams.Fact, len(state.Facts))
+		for i, fact := range state.Facts {
+			table[i] = teams.Fact{Title: fact.Title, Value: fact.Value}
+		}
+		card.AddFacts(table...)
+	}
+	facts := []teams.Fact{{Title: "Check", Value: state.Check}, {Title: "Severity", Value: state.Severity}}
+	for _, fact := range []teams.Fact{
+		{Title: "Network", Value: state.Network},
+		{Title: "Domain", Value: state.Domain},
+		{Title: "Wallet", Value: state.Wallet},
+		{Title: "Acknowledged by", Value: state.AckedBy},
+	} {
+		if fact.Value != "" {
+			facts = append(facts, fact)
+		}
+	}
+	facts = append(facts, teams.Fact{Title: "Fingerprint", Value: state.Fingerprint})
+	return card.AddFacts(facts...).AddLink("Open block explorer", explorerURL)
+}
+
+func SendTeamsNotification(webhookURL string, notification Notification, explorerURL string) {
+	if err := teams.Send(webhookURL, TeamsCard(notification, explorerURL)); err != nil {
+		log.Printf("Error sending %s to Teams: %s", notification.Title(), err)
+	}
+}
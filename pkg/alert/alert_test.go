@@ -0,0 +1,475 @@
+package alert
+
+import (
+	"database/sql/driver"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/swanchain/domain-check/pkg/teams"
+)
+
+func stateRow(s State) []driver.Value {
+	var resolvedAt, ackedAt driver.Value
+	if s.ResolvedAt != nil {
+		resolvedAt = *s.ResolvedAt
+	}
+	if s.AckedAt != nil {
+		ackedAt = *s.AckedAt
+	}
+	failures, _ := s.Failures.Value()
+	transitions, _ := s.Transitions.Value()
+	facts, _ := s.Facts.Value()
+	return []driver.Value{s.Fingerprint, s.Check, s.Key, s.Title, s.State, s.Message, s.Severity, s.Network, s.Domain, s.Wallet,
+		s.FirstSeen, s.LastSeen, s.LastNotified, resolvedAt, failures, transitions, s.Flapping, s.FiredAt, s.Escalation, ackedAt, s.AckedBy, s.Level, facts}
+}
+
+func newTestManager(t *testing.T, cfg Config, now time.Time) (*Manager, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	manager := NewManager(sqlx.NewDb(db, "sqlmock"), cfg)
+	manager.now = func() time.Time { return now }
+	return manager, mock
+}
+
+func expectStates(mock sqlmock.Sqlmock, check string, states ...State) {
+	rows := sqlmock.NewRows(strings.Fields(strings.ReplaceAll(stateColumns, ",", " ")))
+	for _, s := range states {
+		rows.AddRow(stateRow(s)...)
+	}
+	mock.ExpectQuery(regexp.QuoteMeta("FROM alert_state WHERE check_name = $1")).WithArgs(check).WillReturnRows(rows)
+}
+
+func expectSave(mock sqlmock.Sqlmock, state string) {
+	mock.ExpectExec("INSERT INTO alert_state").
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), state,
+			sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(),
+			sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(),
+			sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(),
+			sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+}
+
+// expectRouting expects the routes and escalation steps, given as policy,
+// delay in minutes, channel and target.
+func expectRouting(mock sqlmock.Sqlmock, routes []Route, steps ...[]driver.Value) {
+	routeRows := sqlmock.NewRows([]string{"id", "priority", "check_name", "severity", "network", "domain", "wallet", "policy"})
+	for _, r := range routes {
+		routeRows.AddRow(r.ID, r.Priority, r.Check, r.Severity, r.Network, r.Domain, r.Wallet, r.Policy)
+	}
+	mock.ExpectQuery("FROM alert_route").WillReturnRows(routeRows)
+	stepRows := sqlmock.NewRows([]string{"policy", "delay_minutes", "channel", "target"})
+	for _, step := range steps {
+		stepRows.AddRow(step...)
+	}
+	mock.ExpectQuery("FROM escalation_step").WillReturnRows(stepRows)
+}
+
+func expectSilences(mock sqlmock.Sqlmock, silences ...Silence) {
+	rows := sqlmock.NewRows(strings.Split(silenceColumns, ", "))
+	for _, s := range silences {
+		rows.AddRow(s.ID, s.Check, s.Network, s.Domain, s.Wallet, s.StartsAt, s.EndsAt, s.Author, s.Reason, s.CreatedAt)
+	}
+	mock.ExpectQuery(regexp.QuoteMeta("FROM silence WHERE ends_at > $1")).WillReturnRows(rows)
+}
+
+func TestProcessLifecycle(t *testing.T) {
+	start := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	chain := Alert{Check: "chain-status", Key: "swan", Title: "Chain Status Warning", Message: "less than 5 transactions in the last 10 blocks"}
+	firing := State{
+		Fingerprint:  chain.Fingerprint(),
+		Check:        chain.Check,
+		Key:          chain.Key,
+		Title:        chain.Title,
+		State:        StateFiring,
+		Message:      chain.Message,
+		FirstSeen:    start,
+		LastSeen:     start,
+		LastNotified: start,
+	}
+	// Fire on the first report to follow a single alert through its states.
+	cfg := Config{RepeatInterval: time.Hour, Rules: map[string]Rule{"chain-status": {Failures: 1}}}
+
+	// A new alert fires once.
+	manager, mock := newTestManager(t, cfg, start)
+	expectRouting(mock, nil)
+	expectStates(mock, "chain-status")
+	expectSave(mock, StateFiring)
+	expectSilences(mock)
+	notifications, err := manager.Process("chain-status", []Alert{chain, chain})
+	if err != nil || len(notifications) != 1 || notifications[0].Kind != KindFiring {
+		t.Fatalf("Process() = %+v, %v for a new alert", notifications, err)
+	}
+	if notifications[0].Title() != "[FIRING] Chain Status Warning" || notifications[0].Text() != chain.Message {
+		t.Errorf("Unexpected notification: %s %s", notifications[0].Title(), notifications[0].Text())
+	}
+
+	// It stays quiet inside the repeat interval.
+	manager, mock = newTestManager(t, cfg, start.Add(20*time.Second))
+	expectRouting(mock, nil)
+	expectStates(mock, "chain-status", firing)
+	expectSave(mock, StateFiring)
+	expectSilences(mock)
+	if notifications, err = manager.Process("chain-status", []Alert{chain}); err != nil || len(notifications) != 0 {
+		t.Errorf("Process() = %+v, %v inside the repeat interval", notifications, err)
+	}
+
+	// It repeats once the interval has passed.
+	manager, mock = newTestManager(t, cfg, start.Add(time.Hour))
+	expectRouting(mock, nil)
+	expectStates(mock, "chain-status", firing)
+	expectSave(mock, StateFiring)
+	expectSilences(mock)
+	notifications, err = manager.Process("chain-status", []Alert{chain})
+	if err != nil || len(notifications) != 1 || notifications[0].Kind != KindRepeat || !strings.Contains(notifications[0].Text(), "Firing since 2026-10-01T12:00:00Z") {
+		t.Errorf("Process() = %+v, %v after the repeat interval", notifications, err)
+	}
+
+	// It resolves when the check no longer reports it.
+	manager, mock = newTestManager(t, cfg, start.Add(90*time.Minute))
+	expectRouting(mock, nil)
+	expectStates(mock, "chain-status", firing)
+	expectSave(mock, StateResolved)
+	expectSilences(mock)
+	notifications, err = manager.Process("chain-status", nil)
+	if err != nil || len(notifications) != 1 || notifications[0].Kind != KindResolved {
+		t.Fatalf("Process() = %+v, %v after the alert cleared", notifications, err)
+	}
+	if notifications[0].Title() != "[RESOLVED] Chain Status Warning" || !strings.HasPrefix(notifications[0].Text(), "Resolved after 1h30m0s") {
+		t.Errorf("Unexpected notification: %s %s", notifications[0].Title(), notifications[0].Text())
+	}
+
+	// A resolved alert goes back to ok without another notification.
+	resolved := firing
+	resolved.State = StateResolved
+	resolvedAt := start.Add(90 * time.Minute)
+	resolved.ResolvedAt = &resolvedAt
+	manager, mock = newTestManager(t, cfg, start.Add(100*time.Minute))
+	expectRouting(mock, nil)
+	expectStates(mock, "chain-status", resolved)
+	expectSave(mock, StateOK)
+	expectSilences(mock)
+	if notifications, err = manager.Process("chain-status", nil); err != nil || len(notifications) != 0 {
+		t.Errorf("Process() = %+v, %v for a resolved alert", notifications, err)
+	}
+
+	// An ok alert that fires again starts a new episode.
+	ok := resolved
+	ok.State = StateOK
+	manager, mock = newTestManager(t, cfg, start.Add(2*time.Hour))
+	expectRouting(mock, nil)
+	expectStates(mock, "chain-status", ok)
+	expectSave(mock, StateFiring)
+	expectSilences(mock)
+	notifications, err = manager.Process("chain-status", []Alert{chain})
+	if err != nil || len(notifications) != 1 || notifications[0].Kind != KindFiring || !notifications[0].State.FirstSeen.Equal(start.Add(2*time.Hour)) {
+		t.Errorf("Process() = %+v, %v for a recurring alert", notifications, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unmet expectations: %v", err)
+	}
+}
+
+func TestProcessEventAndUnknown(t *testing.T) {
+	start := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	rotated := Alert{Check: "ssl", Key: "example.com 1.2.3.4 change", Title: "SSL Certificate Rotated", Message: "new certificate", Event: true}
+	expiry := Alert{Check: "ssl", Key: "example.com expiry", Title: "SSL Certificate Expiry Warning", Message: "expires in 7 days"}
+	firing := State{Fingerprint: expiry.Fingerprint(), Check: expiry.Check, Key: expiry.Key, Title: expiry.Title, State: StateFiring,
+		Message: expiry.Message, FirstSeen: start, LastSeen: start, LastNotified: start, FiredAt: start, Escalation: 1}
+	cfg := Config{RepeatInterval: time.Hour}
+
+	// An event is sent as is and leaves its alert ok; an unknown alert keeps
+	// firing without a notification.
+	manager, mock := newTestManager(t, cfg, start.Add(time.Minute))
+	expectRouting(mock, nil)
+	expectStates(mock, "ssl", firing)
+	expectSave(mock, StateOK)
+	expectSilences(mock)
+	notifications, err := manager.Process("ssl", []Alert{rotated, {Check: expiry.Check, Key: expiry.Key, Unknown: true}})
+	if err != nil || len(notifications) != 1 || notifications[0].Kind != KindEvent {
+		t.Fatalf("Process() = %+v, %v for an event", notifications, err)
+	}
+	if notifications[0].Title() != "SSL Certificate Rotated" || notifications[0].Text() != rotated.Message || len(notifications[0].Targets) == 0 {
+		t.Errorf("Unexpected notification: %+v", notifications[0])
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unmet expectations: %v", err)
+	}
+
+	// The event does not resolve once it is no longer reported.
+	event := notifications[0].State
+	manager, mock = newTestManager(t, cfg, start.Add(2*time.Minute))
+	expectRouting(mock, nil)
+	expectStates(mock, "ssl", event)
+	expectSilences(mock)
+	if notifications, err = manager.Process("ssl", nil); err != nil || len(notifications) != 0 {
+		t.Errorf("Process() = %+v, %v after an event", notifications, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unmet expectations: %v", err)
+	}
+}
+
+func TestProcessLevel(t *testing.T) {
+	start := time.Date(2026, 10, 1, 9, 30, 0, 0, time.UTC)
+	expiry := Alert{Check: "ssl", Key: "example.com expiry", Title: "SSL Certificate Expiration Warning", Message: "expires in 20 days (30 day warning)", Level: 1}
+	cfg := Config{RepeatInterval: 48 * time.Hour}
+
+	run := func(at time.Time, previous []State, reported Alert) []Notification {
+		t.Helper()
+		manager, mock := newTestManager(t, cfg, at)
+		expectRouting(mock, nil)
+		expectStates(mock, "ssl", previous...)
+		expectSave(mock, StateFiring)
+		expectSilences(mock)
+		notifications, err := manager.Process("ssl", []Alert{reported})
+		if err != nil {
+			t.Fatalf("Process() at %s: %v", at, err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unmet expectations at %s: %v", at, err)
+		}
+		return notifications
+	}
+
+	n := run(start, nil, expiry)
+	if len(n) != 1 || n[0].Kind != KindFiring || n[0].State.Level != 1 {
+		t.Fatalf("Process() = %+v for a new alert", n)
+	}
+	firing := n[0].State
+
+	// It stays quiet in the same window inside the repeat interval.
+	if n := run(start.Add(24*time.Hour), []State{firing}, expiry); len(n) != 0 {
+		t.Errorf("Process() = %+v inside the same window", n)
+	}
+
+	// It repeats in the same window once the interval has passed.
+	n = run(start.Add(48*time.Hour), []State{firing}, expiry)
+	if len(n) != 1 || n[0].Kind != KindRepeat || n[0].State.Level != 1 {
+		t.Errorf("Process() = %+v after the repeat interval", n)
+	}
+
+	// Entering a narrower window notifies inside the interval.
+	raised := expiry
+	raised.Message, raised.Level = "expires in 13 days (14 day warning)", 2
+	n = run(start.Add(time.Hour), []State{firing}, raised)
+	if len(n) != 1 || n[0].Kind != KindRepeat || !strings.Contains(n[0].Text(), "14 day warning") || n[0].State.Level != 2 {
+		t.Errorf("Process() = %+v after entering a narrower window", n)
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	a := Alert{Check: "contract", Key: "bridge-not-paused", Message: "one"}
+	b := Alert{Check: "contract", Key: "bridge-not-paused", Message: "two"}
+	c := Alert{Check: "contract", Key: "oracle-fresh", Message: "one"}
+
+	if a.Fingerprint() != b.Fingerprint() {
+		t.Errorf("Fingerprint depends on the message")
+	}
+	if a.Fingerprint() == c.Fingerprint() || len(a.Fingerprint()) != 32 {
+		t.Errorf("Unexpected fingerprints %s and %s", a.Fingerprint(), c.Fingerprint())
+	}
+}
+
+func TestGetConfig(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT key, value FROM info WHERE is_active = true AND type = 'alert'").
+		WillReturnRows(sqlmock.NewRows([]string{"key", "value"}).
+			AddRow("repeat-minutes", "15").
+			AddRow("flap-threshold", "6").
+			AddRow("retention-days", "7"))
+	mock.ExpectQuery("SELECT key, value FROM info WHERE is_active = true AND type = 'alert-rule'").
+		WillReturnRows(sqlmock.NewRows([]string{"key", "value"}).
+			AddRow("probe", "2").
+			AddRow("contract", "3/10"))
+
+	cfg, err := GetConfig(sqlx.NewDb(db, "sqlmock"))
+	if err != nil || cfg.RepeatInterval != 15*time.Minute || cfg.FlapThreshold != 6 || cfg.FlapWindow != time.Hour || cfg.Retention != 7*24*time.Hour {
+		t.Errorf("GetConfig() = %+v, %v", cfg, err)
+	}
+	if rule := cfg.Rule("probe"); rule != (Rule{Failures: 2}) {
+		t.Errorf("Rule(probe) = %+v", rule)
+	}
+	if rule := cfg.Rule("contract"); rule != (Rule{Failures: 3, Window: 10 * time.Minute}) {
+		t.Errorf("Rule(contract) = %+v", rule)
+	}
+	if rule := cfg.Rule("chain-status"); rule != (Rule{Failures: 3, Window: 5 * time.Minute}) {
+		t.Errorf("Rule(chain-status) = %+v, want the default", rule)
+	}
+	if rule := cfg.Rule("l1-posting"); rule != (Rule{Failures: 1}) {
+		t.Errorf("Rule(l1-posting) = %+v", rule)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	manager, mock := newTestManager(t, Config{Retention: 30 * 24 * time.Hour}, now)
+
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM alert_state WHERE check_name = $1 AND state = $2 AND NOT flapping AND last_seen < $3")).
+		WithArgs("ct", StateOK, now.Add(-30*24*time.Hour)).WillReturnResult(sqlmock.NewResult(0, 5))
+	if err := manager.Prune("ct"); err != nil {
+		t.Errorf("Prune() returned error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestParseRule(t *testing.T) {
+	for _, value := range []string{"", "0", "x", "3/", "3/0", "-1/5"} {
+		if _, err := parseRule(value); err == nil {
+			t.Errorf("parseRule(%q) succeeded", value)
+		}
+	}
+}
+
+func TestProcessThreshold(t *testing.T) {
+	start := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	chain := Alert{Check: "chain-status", Key: "swan", Title: "Chain Status Warning", Message: "rpc timeout"}
+	cfg := Config{RepeatInterval: time.Hour}
+	state := State{Fingerprint: chain.Fingerprint(), Check: chain.Check, Key: chain.Key, Title: chain.Title, State: StateOK}
+
+	run := func(at time.Time, reported []Alert, want string) []Notification {
+		t.Helper()
+		manager, mock := newTestManager(t, cfg, at)
+		expectRouting(mock, nil)
+		expectStates(mock, "chain-status", state)
+		expectSave(mock, want)
+		expectSilences(mock)
+		notifications, err := manager.Process("chain-status", reported)
+		if err != nil {
+			t.Fatalf("Process() at %s: %v", at, err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Fatalf("Unmet expectations at %s: %v", at, err)
+		}
+		state, _ = manager.step(state, len(reported) > 0, 0, cfg.Rule("chain-status"), defaultPolicy, at)
+		return notifications
+	}
+
+	// A single timeout stays pending, and a success clears it.
+	if n := run(start, []Alert{chain}, StatePending); len(n) != 0 {
+		t.Errorf("Process() = %+v for the first failure", n)
+	}
+	if n := run(start.Add(time.Minute), nil, StateOK); len(n) != 0 {
+		t.Errorf("Process() = %+v after recovering", n)
+	}
+
+	// Failures spread wider than the window do not fire.
+	run(start.Add(10*time.Minute), []Alert{chain}, StatePending)
+	run(start.Add(13*time.Minute), []Alert{chain}, StatePending)
+	if n := run(start.Add(16*time.Minute), []Alert{chain}, StatePending); len(n) != 0 {
+		t.Errorf("Process() = %+v for failures outside the window", n)
+	}
+
+	// The last three are within five minutes and fire.
+	n := run(start.Add(18*time.Minute), []Alert{chain}, StateFiring)
+	if len(n) != 1 || n[0].Kind != KindFiring || !n[0].State.FirstSeen.Equal(start.Add(13*time.Minute)) {
+		t.Errorf("Process() = %+v after three failures within the window", n)
+	}
+}
+
+func TestProcessFlapping(t *testing.T) {
+	start := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	probe := Alert{Check: "probe", Key: "swan", Title: "Probe Transaction Warning", Message: "receipt timeout"}
+	cfg := Config{RepeatInterval: time.Hour, FlapWindow: 30 * time.Minute, FlapThreshold: 4}
+	state := State{Fingerprint: probe.Fingerprint(), Check: probe.Check, Key: probe.Key, Title: probe.Title, State: StateOK}
+
+	var kinds []string
+	run := func(at time.Time, reported bool) {
+		t.Helper()
+		var alerts []Alert
+		if reported {
+			alerts = []Alert{probe}
+		}
+		manager, mock := newTestManager(t, cfg, at)
+		expectRouting(mock, nil)
+		expectStates(mock, "probe", state)
+		mock.MatchExpectationsInOrder(false)
+		mock.ExpectExec("INSERT INTO alert_state").WillReturnResult(sqlmock.NewResult(1, 1))
+		expectSilences(mock)
+		notifications, err := manager.Process("probe", alerts)
+		if err != nil {
+			t.Fatalf("Process() at %s: %v", at, err)
+		}
+		for _, n := range notifications {
+			kinds = append(kinds, n.Kind)
+		}
+		state, _ = manager.step(state, reported, 0, cfg.Rule("probe"), defaultPolicy, at)
+	}
+
+	// Fire, resolve, fire, resolve: the fourth transition is reported as
+	// flapping instead, and the ones after it are suppressed.
+	at := start
+	for i := 0; i < 5; i++ {
+		run(at, true)
+		run(at.Add(time.Minute), false)
+		run(at.Add(2*time.Minute), false)
+		at = at.Add(3 * time.Minute)
+	}
+	want := []string{KindFiring, KindResolved, KindFiring, KindFlapping}
+	if strings.Join(kinds, ",") != strings.Join(want, ",") {
+		t.Errorf("Notifications while flapping = %v, want %v", kinds, want)
+	}
+	if !state.Flapping {
+		t.Fatalf("State is not flapping: %+v", state)
+	}
+
+	// Firing steadily for a whole window ends the flapping with its state.
+	kinds = nil
+	run(at, true)
+	run(at.Add(31*time.Minute), true)
+	if strings.Join(kinds, ",") != KindFlapStopped || state.Flapping || state.State != StateFiring {
+		t.Errorf("Notifications after settling = %v, state %+v", kinds, state)
+	}
+}
+
+func TestTeamsCard(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	state := State{Fingerprint: "0f1e", Check: "chain-status", Title: "Chain Status Warning", Message: "rpc timeout",
+		Severity: SeverityCritical, Network: "swan", State: StateFiring, FiredAt: now}
+	resolved := state
+	resolved.State, resolved.ResolvedAt = StateResolved, &now
+
+	tests := []struct {
+		notification Notification
+		style        string
+	}{
+		{Notification{Kind: KindFiring, State: state}, teams.StyleAttention},
+		{Notification{Kind: KindFiring, State: State{Severity: SeverityWarning}}, teams.StyleWarning},
+		{Notification{Kind: KindEvent, State: State{Severity: SeverityInfo}}, teams.StyleAccent},
+		{Notification{Kind: KindFlapping, State: state}, teams.StyleDefault},
+		{Notification{Kind: KindResolved, State: resolved}, teams.StyleGood},
+	}
+	for _, tt := range tests {
+		if card := TeamsCard(tt.notification, ""); card.Body[0].Style != tt.style || len(card.Actions) != 0 {
+			t.Errorf("TeamsCard(%s, %s) has style %s and actions %+v", tt.notification.Kind, tt.notification.State.Severity, card.Body[0].Style, card.Actions)
+		}
+	}
+
+	card := TeamsCard(Notification{Kind: KindFiring, State: state}, "https://explorer.swanchain.io")
+	facts := card.Body[len(card.Body)-1].Facts
+	if len(facts) != 4 || facts[2] != (teams.Fact{Title: "Network", Value: "swan"}) || card.Actions[0].URL != "https://explorer.swanchain.io" {
+		t.Errorf("Unexpected card %+v", card)
+	}
+
+	// An alert's own facts come as a table before the alert's details.
+	state.Facts = factList{{Title: "0x1234 (swan)", Value: "12.500000 (change -0.500000)"}}
+	card = TeamsCard(Notification{Kind: KindEvent, State: state}, "")
+	if table := card.Body[len(card.Body)-2]; table.Type != "FactSet" || len(table.Facts) != 1 || table.Facts[0] != (teams.Fact{Title: "0x1234 (swan)", Value: "12.500000 (change -0.500000)"}) {
+		t.Errorf("Unexpected card %+v", card)
+	}
+}
@@ -0,0 +1,187 @@
+package alert
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Notification channels. An empty address means the default for the
+// channel: the teams-webhook info row, every email recipient, the Slack
+// channel or webhook of the 'slack' info rows, the 'discord' webhook, the
+// 'telegram' chat-id, the 'pagerduty' routing-key, the 'opsgenie' api-key or
+// the 'webhook' url.
+const (
+	ChannelTeams     = "teams"
+	ChannelEmail     = "email"
+	ChannelSlack     = "slack"
+	ChannelDiscord   = "discord"
+	ChannelTelegram  = "telegram"
+	ChannelPagerDuty = "pagerduty"
+	ChannelOpsgenie  = "opsgenie"
+	ChannelWebhook   = "webhook"
+)
+
+// Target is where a notification is sent.
+type Target struct {
+	Channel string `db:"channel"`
+	Address string `db:"target"`
+}
+
+// Route sends alerts matching all of its non-empty fields to the escalation
+// policy named Policy. Routes are tried in order of Priority.
+type Route struct {
+	ID       int    `db:"id"`
+	Priority int    `db:"priority"`
+	Check    string `db:"check_name"`
+	Severity string `db:"severity"`
+	Network  string `db:"network"`
+	Domain   string `db:"domain"`
+	Wallet   string `db:"wallet"`
+	Policy   string `db:"policy"`
+}
+
+func (r Route) Matches(subject Subject) bool {
+	return matchSubject(Subject{Check: r.Check, Severity: r.Severity, Network: r.Network, Domain: r.Domain, Wallet: r.Wallet}, subject)
+}
+
+// Step is one level of an escalation policy. Its targets are notified once
+// the alert has been firing for Delay without being acknowledged.
+type Step struct {
+	Delay   time.Duration
+	Targets []Target
+}
+
+// Policy is a list of steps ordered by delay. The first step is notified as
+// soon as an alert fires, whatever its delay.
+type Policy []Step
+
+// defaultPolicy is used for alerts no route matches: the default Teams
+// webhook and, if Slack is configured, the default Slack destination.
+var defaultPolicy = Policy{{Targets: []Target{{Channel: ChannelTeams}, {Channel: ChannelSlack}}}}
+
+// targets returns the targets of steps from up to but not including to.
+func (p Policy) targets(from, to int) []Target {
+	var targets []Target
+	for _, step := range p[from:min(to, len(p))] {
+		targets = append(targets, step.Targets...)
+	}
+	return targets
+}
+
+// reached returns the number of steps due after the alert has been firing
+// for elapsed, at least one.
+func (p Policy) reached(elapsed time.Duration) int {
+	n := 1
+	for n < len(p) && p[n].Delay <= elapsed {
+		n++
+	}
+	return min(n, len(p))
+}
+
+// Routing holds the routes and escalation policies from the alert_route and
+// escalation_step tables.
+type Routing struct {
+	Routes   []Route
+	Policies map[string]Policy
+}
+
+func GetRouting(db *sqlx.DB) (Routing, error) {
+	routing := Routing{Policies: map[string]Policy{}}
+	err := db.Select(&routing.Routes, `
+		SELECT id, priority, check_name, severity, network, domain, wallet, policy
+		FROM alert_route WHERE is_active = true ORDER BY priority, id
+	`)
+	if err != nil {
+		log.Printf("Error retrieving alert routes: %s", err)
+		return routing, err
+	}
+
+	var steps []struct {
+		Policy       string `db:"policy"`
+		DelayMinutes int    `db:"delay_minutes"`
+		Target
+	}
+	err = db.Select(&steps, "SELECT policy, delay_minutes, channel, target FROM escalation_step ORDER BY policy, delay_minutes, id")
+	if err != nil {
+		log.Printf("Error retrieving escalation steps: %s", err)
+		return routing, err
+	}
+	for _, step := range steps {
+		switch step.Channel {
+		case ChannelTeams, ChannelEmail, ChannelSlack, ChannelDiscord, ChannelTelegram, ChannelPagerDuty, ChannelOpsgenie, ChannelWebhook:
+		default:
+			log.Printf("Skipping escalation step of %s with unknown channel %q", step.Policy, step.Channel)
+			continue
+		}
+		policy := routing.Policies[step.Policy]
+		delay := time.Duration(step.DelayMinutes) * time.Minute
+		if len(policy) == 0 || policy[len(policy)-1].Delay != delay {
+			policy = append(policy, Step{Delay: delay})
+		}
+		policy[len(policy)-1].Targets = append(policy[len(policy)-1].Targets, step.Target)
+		routing.Policies[step.Policy] = policy
+	}
+	return routing, nil
+}
+
+// Policy returns the escalation policy of the first route matching subject.
+// Without one, or if its policy has no steps, the default policy is used.
+func (r Routing) Policy(subject Subject) Policy {
+	for _, route := range r.Routes {
+		if route.Matches(subject) {
+			if policy := r.Policies[route.Policy]; len(policy) > 0 {
+				return policy
+			}
+			log.Printf("Alert route %d uses escalation policy %q without steps", route.ID, route.Policy)
+			break
+		}
+	}
+	return defaultPolicy
+}
+
+// Acknowledge stops escalation and repeats of the firing alert whose
+// fingerprint starts with prefix, until it resolves. It returns the alert.
+func Acknowledge(db *sqlx.DB, prefix, author string) (State, error) {
+	if prefix == "" {
+		return State{}, errors.New("an alert fingerprint is required")
+	}
+	var states []State
+	err := db.Select(&states, "SELECT "+stateColumns+" FROM alert_state WHERE state = 'firing' AND fingerprint LIKE $1",
+		strings.ToLower(prefix)+"%")
+	if err != nil {
+		log.Printf("Error retrieving alert %s: %s", prefix, err)
+		return State{}, err
+	}
+	switch {
+	case len(states) == 0:
+		return State{}, fmt.Errorf("no firing alert matches %q", prefix)
+	case len(states) > 1:
+		return State{}, fmt.Errorf("%d firing alerts match %q", len(states), prefix)
+	}
+
+	state := states[0]
+	now := time.Now()
+	state.AckedAt, state.AckedBy = &now, author
+	_, err = db.Exec("UPDATE alert_state SET acked_at = $2, acked_by = $3 WHERE fingerprint = $1", state.Fingerprint, now, author)
+	if err != nil {
+		log.Printf("Error acknowledging alert %s: %s", state.Fingerprint, err)
+		return state, err
+	}
+	return state, nil
+}
+
+// ListAlerts returns the alerts that are pending or firing.
+func ListAlerts(db *sqlx.DB) ([]State, error) {
+	var states []State
+	err := db.Select(&states, "SELECT "+stateColumns+" FROM alert_state WHERE state IN ('pending', 'firing') ORDER BY first_seen")
+	if err != nil {
+		log.Printf("Error retrieving alerts: %s", err)
+		return nil, err
+	}
+	return states, nil
+}
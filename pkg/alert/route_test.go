@@ -0,0 +1,215 @@
+package alert
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+)
+
+var onCallRoutes = []Route{
+	{ID: 1, Priority: 1, Severity: SeverityCritical, Network: "swan", Policy: "on-call"},
+	{ID: 2, Priority: 2, Check: "contract", Policy: "contracts"},
+}
+
+var onCallSteps = [][]driver.Value{
+	{"on-call", 0, "teams", ""},
+	{"on-call", 15, "email", "oncall@swanchain.io"},
+	{"on-call", 15, "email", "lead@swanchain.io"},
+	{"on-call", 30, "sms", "+15550100"},
+	{"on-call", 45, "teams", "https://teams.example/escalation"},
+}
+
+func TestGetRouting(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	expectRouting(mock, onCallRoutes, onCallSteps...)
+	routing, err := GetRouting(sqlx.NewDb(db, "sqlmock"))
+	if err != nil {
+		t.Fatalf("GetRouting() = %v", err)
+	}
+
+	want := Policy{
+		{Targets: []Target{{ChannelTeams, ""}}},
+		{Delay: 15 * time.Minute, Targets: []Target{{ChannelEmail, "oncall@swanchain.io"}, {ChannelEmail, "lead@swanchain.io"}}},
+		{Delay: 45 * time.Minute, Targets: []Target{{ChannelTeams, "https://teams.example/escalation"}}},
+	}
+	if got := routing.Policy(Subject{Check: "chain-status", Severity: SeverityCritical, Network: "swan"}); !reflect.DeepEqual(got, want) {
+		t.Errorf("Policy() = %+v, want %+v", got, want)
+	}
+
+	// A warning on the same network falls through to the default; a matching
+	// route whose policy has no steps does too.
+	if got := routing.Policy(Subject{Check: "chain-status", Severity: SeverityWarning, Network: "swan"}); !reflect.DeepEqual(got, defaultPolicy) {
+		t.Errorf("Policy() = %+v for a warning", got)
+	}
+	if got := routing.Policy(Subject{Check: "contract", Severity: SeverityWarning, Network: "sepolia"}); !reflect.DeepEqual(got, defaultPolicy) {
+		t.Errorf("Policy() = %+v for a route without steps", got)
+	}
+}
+
+func TestProcessEscalation(t *testing.T) {
+	start := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	chain := Alert{Check: "chain-status", Key: "swan", Title: "Chain Status Warning", Message: "rpc timeout", Severity: SeverityCritical, Network: "swan"}
+	cfg := Config{RepeatInterval: 0, Rules: map[string]Rule{"chain-status": {Failures: 1}}}
+	policy := Policy{
+		{Targets: []Target{{ChannelTeams, ""}}},
+		{Delay: 15 * time.Minute, Targets: []Target{{ChannelEmail, "oncall@swanchain.io"}, {ChannelEmail, "lead@swanchain.io"}}},
+		{Delay: 45 * time.Minute, Targets: []Target{{ChannelTeams, "https://teams.example/escalation"}}},
+	}
+	var state State
+
+	// run processes one check run and then replays it on state, which the
+	// next run reads back.
+	run := func(at time.Time, reported bool) []Notification {
+		t.Helper()
+		var alerts []Alert
+		var states []State
+		if reported {
+			alerts = []Alert{chain}
+		}
+		if state.Fingerprint != "" {
+			states = append(states, state)
+		}
+		manager, mock := newTestManager(t, cfg, at)
+		mock.MatchExpectationsInOrder(false)
+		expectRouting(mock, onCallRoutes, onCallSteps...)
+		expectStates(mock, "chain-status", states...)
+		mock.ExpectExec("INSERT INTO alert_state").WillReturnResult(sqlmock.NewResult(1, 1))
+		expectSilences(mock)
+		notifications, err := manager.Process("chain-status", alerts)
+		if err != nil {
+			t.Fatalf("Process() at %s: %v", at, err)
+		}
+		if state.Fingerprint == "" {
+			state = State{Fingerprint: chain.Fingerprint(), Check: chain.Check, Key: chain.Key, State: StateOK}
+		}
+		state.Title, state.Message, state.Severity, state.Network = chain.Title, chain.Message, chain.Severity, chain.Network
+		state, _ = manager.step(state, reported, 0, cfg.Rule("chain-status"), policy, at)
+		return notifications
+	}
+	targets := func(notifications []Notification) string {
+		var addresses []string
+		for _, n := range notifications {
+			for _, target := range n.Targets {
+				addresses = append(addresses, n.Kind+":"+target.Channel+":"+target.Address)
+			}
+		}
+		return strings.Join(addresses, ",")
+	}
+
+	if got := targets(run(start, true)); got != "firing:teams:" {
+		t.Errorf("Firing notified %s", got)
+	}
+	if got := targets(run(start.Add(10*time.Minute), true)); got != "" {
+		t.Errorf("Notified %s before the first escalation", got)
+	}
+	if got := targets(run(start.Add(16*time.Minute), true)); got != "escalated:email:oncall@swanchain.io,escalated:email:lead@swanchain.io" {
+		t.Errorf("First escalation notified %s", got)
+	}
+
+	// An acknowledgement is passed on to everyone notified once and stops
+	// further escalation.
+	ackedAt := start.Add(20 * time.Minute)
+	state.AckedAt, state.AckedBy = &ackedAt, "alice"
+	if got := targets(run(start.Add(21*time.Minute), true)); got != "acknowledged:teams:,acknowledged:email:oncall@swanchain.io,acknowledged:email:lead@swanchain.io" {
+		t.Errorf("Acknowledgement notified %s", got)
+	}
+	if got := targets(run(start.Add(50*time.Minute), true)); got != "" {
+		t.Errorf("Notified %s after the alert was acknowledged", got)
+	}
+
+	// Everyone notified hears that it resolved.
+	if got := targets(run(start.Add(55*time.Minute), false)); got != "resolved:teams:,resolved:email:oncall@swanchain.io,resolved:email:lead@swanchain.io" {
+		t.Errorf("Resolution notified %s", got)
+	}
+
+	// Firing again clears the acknowledgement and starts over.
+	run(start.Add(60*time.Minute), false)
+	if got := targets(run(start.Add(2*time.Hour), true)); got != "firing:teams:" || state.AckedAt != nil || state.Escalation != 1 {
+		t.Errorf("Firing again notified %s with state %+v", got, state)
+	}
+	if got := targets(run(start.Add(3*time.Hour), true)); !strings.Contains(got, "https://teams.example/escalation") {
+		t.Errorf("Late escalation notified %s, want every remaining step", got)
+	}
+}
+
+func TestProcessEventRouting(t *testing.T) {
+	update := Alert{Check: "wallet", Key: "swan/0x1234", Title: "Wallet Balance Change Update", Message: "balance changed", Wallet: "0x1234", Event: true}
+	routes := []Route{{ID: 1, Priority: 1, Check: "wallet", Policy: "treasury"}}
+	steps := [][]driver.Value{
+		{"treasury", 0, "pagerduty", ""},
+		{"treasury", 0, "webhook", "https://hooks.example/treasury"},
+		{"treasury", 30, "opsgenie", ""},
+	}
+
+	// Events reach the first step of the policy they route to like any other
+	// alert.
+	manager, mock := newTestManager(t, Config{}, time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC))
+	expectRouting(mock, routes, steps...)
+	expectStates(mock, "wallet")
+	expectSave(mock, StateOK)
+	expectSilences(mock)
+	notifications, err := manager.Process("wallet", []Alert{update})
+	if err != nil || len(notifications) != 1 {
+		t.Fatalf("Process() = %+v, %v", notifications, err)
+	}
+	want := []Target{{ChannelPagerDuty, ""}, {ChannelWebhook, "https://hooks.example/treasury"}}
+	if !reflect.DeepEqual(notifications[0].Targets, want) {
+		t.Errorf("Targets = %+v, want %+v", notifications[0].Targets, want)
+	}
+}
+
+func TestAcknowledge(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	firing := State{Fingerprint: "dc88d10c6b0f4b1d9a15fb48325bacde", Check: "chain-status", Key: "swan", State: StateFiring}
+	rows := func(states ...State) *sqlmock.Rows {
+		r := sqlmock.NewRows(strings.Fields(strings.ReplaceAll(stateColumns, ",", " ")))
+		for _, s := range states {
+			r.AddRow(stateRow(s)...)
+		}
+		return r
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta("FROM alert_state WHERE state = 'firing' AND fingerprint LIKE $1")).
+		WithArgs("dc88%").WillReturnRows(rows(firing))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE alert_state SET acked_at = $2, acked_by = $3")).
+		WithArgs(firing.Fingerprint, sqlmock.AnyArg(), "alice").WillReturnResult(sqlmock.NewResult(0, 1))
+	state, err := Acknowledge(sqlxDB, "DC88", "alice")
+	if err != nil || state.AckedBy != "alice" || state.AckedAt == nil {
+		t.Errorf("Acknowledge() = %+v, %v", state, err)
+	}
+
+	other := firing
+	other.Fingerprint = "dc88ffffffffffffffffffffffffffff"
+	mock.ExpectQuery("FROM alert_state").WithArgs("dc%").WillReturnRows(rows(firing, other))
+	if _, err := Acknowledge(sqlxDB, "dc", "alice"); err == nil {
+		t.Errorf("Acknowledge() succeeded for an ambiguous prefix")
+	}
+	mock.ExpectQuery("FROM alert_state").WithArgs("ff%").WillReturnRows(rows())
+	if _, err := Acknowledge(sqlxDB, "ff", "alice"); err == nil {
+		t.Errorf("Acknowledge() succeeded without a matching alert")
+	}
+	if _, err := Acknowledge(sqlxDB, "", "alice"); err == nil {
+		t.Errorf("Acknowledge() succeeded without a fingerprint")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unmet expectations: %v", err)
+	}
+}
@@ -0,0 +1,198 @@
+package alert
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Subject is what a notification is about. Empty fields do not apply to it.
+type Subject struct {
+	Check    string
+	Severity string
+	Network  string
+	Domain   string
+	Wallet   string
+}
+
+func (s Subject) String() string {
+	parts := []string{s.Check}
+	for _, part := range []struct{ name, value string }{
+		{"severity", s.Severity}, {"network", s.Network}, {"domain", s.Domain}, {"wallet", s.Wallet},
+	} {
+		if part.value != "" {
+			parts = append(parts, part.name+"="+part.value)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// Silence suppresses notifications between StartsAt and EndsAt for every
+// subject matching all of its non-empty fields. A domain also matches its
+// subdomains and URLs on them, and a wallet matches case-insensitively.
+type Silence struct {
+	ID        int       `db:"id"`
+	Check     string    `db:"check_name"`
+	Network   string    `db:"network"`
+	Domain    string    `db:"domain"`
+	Wallet    string    `db:"wallet"`
+	StartsAt  time.Time `db:"starts_at"`
+	EndsAt    time.Time `db:"ends_at"`
+	Author    string    `db:"author"`
+	Reason    string    `db:"reason"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+func (s Silence) Active(now time.Time) bool {
+	return !now.Before(s.StartsAt) && now.Before(s.EndsAt)
+}
+
+func (s Silence) Matches(subject Subject) bool {
+	return matchSubject(Subject{Check: s.Check, Network: s.Network, Domain: s.Domain, Wallet: s.Wallet}, subject)
+}
+
+// matchSubject reports whether subject matches every non-empty field of
+// pattern.
+func matchSubject(pattern, subject Subject) bool {
+	s := pattern
+	switch {
+	case s.Check != "" && s.Check != subject.Check:
+		return false
+	case s.Severity != "" && s.Severity != subject.Severity:
+		return false
+	case s.Network != "" && !strings.EqualFold(s.Network, subject.Network):
+		return false
+	case s.Wallet != "" && !strings.EqualFold(s.Wallet, subject.Wallet):
+		return false
+	case s.Domain != "" && !matchesDomain(s.Domain, subject.Domain):
+		return false
+	}
+	return true
+}
+
+// matchesDomain reports whether domain, a host name or URL, is silenced or
+// one of its subdomains.
+func matchesDomain(silenced, domain string) bool {
+	host := strings.ToLower(domain)
+	if _, rest, ok := strings.Cut(host, "://"); ok {
+		host = rest
+	}
+	if i := strings.IndexAny(host, "/?#"); i >= 0 {
+		host = host[:i]
+	}
+	if h, _, ok := strings.Cut(host, ":"); ok {
+		host = h
+	}
+	silenced = strings.ToLower(silenced)
+	return host == silenced || strings.HasSuffix(host, "."+silenced)
+}
+
+// Validate checks a silence before it is created.
+func (s Silence) Validate() error {
+	switch {
+	case s.Check == "" && s.Network == "" && s.Domain == "" && s.Wallet == "":
+		return errors.New("a silence needs a check, network, domain or wallet")
+	case !s.EndsAt.After(s.StartsAt):
+		return errors.New("a silence must end after it starts")
+	case strings.TrimSpace(s.Author) == "":
+		return errors.New("a silence needs an author")
+	case strings.TrimSpace(s.Reason) == "":
+		return errors.New("a silence needs a reason")
+	}
+	return nil
+}
+
+// Silences is a set of silences loaded together, usually once per task run.
+type Silences []Silence
+
+// Match returns the first silence active at now that matches subject.
+func (silences Silences) Match(subject Subject, now time.Time) (Silence, bool) {
+	for _, silence := range silences {
+		if silence.Active(now) && silence.Matches(subject) {
+			return silence, true
+		}
+	}
+	return Silence{}, false
+}
+
+// Silenced reports whether subject is silenced now and logs the silence if so.
+func (silences Silences) Silenced(subject Subject) bool {
+	silence, ok := silences.Match(subject, time.Now())
+	if ok {
+		log.Printf("Notification for %s suppressed by silence %d until %s (%s: %s)",
+			subject, silence.ID, silence.EndsAt.Format(time.RFC3339), silence.Author, silence.Reason)
+	}
+	return ok
+}
+
+const silenceColumns = "id, check_name, network, domain, wallet, starts_at, ends_at, author, reason, created_at"
+
+// GetActiveSilences returns the silences that have not ended by now, so
+// silences starting later in a task run are included.
+func GetActiveSilences(db *sqlx.DB) (Silences, error) {
+	var silences Silences
+	err := db.Select(&silences, "SELECT "+silenceColumns+" FROM silence WHERE ends_at > $1 ORDER BY starts_at, id", time.Now())
+	if err != nil {
+		log.Printf("Error retrieving silences: %s", err)
+		return nil, err
+	}
+	return silences, nil
+}
+
+// ListSilences returns the silences that have not ended, or all of them.
+func ListSilences(db *sqlx.DB, all bool) (Silences, error) {
+	if !all {
+		return GetActiveSilences(db)
+	}
+	var silences Silences
+	err := db.Select(&silences, "SELECT "+silenceColumns+" FROM silence ORDER BY starts_at, id")
+	if err != nil {
+		log.Printf("Error retrieving silences: %s", err)
+		return nil, err
+	}
+	return silences, nil
+}
+
+func CreateSilence(db *sqlx.DB, silence Silence) (Silence, error) {
+	if err := silence.Validate(); err != nil {
+		return silence, err
+	}
+	silence.CreatedAt = time.Now()
+	err := db.Get(&silence.ID, `
+		INSERT INTO silence (check_name, network, domain, wallet, starts_at, ends_at, author, reason, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id
+	`, silence.Check, silence.Network, silence.Domain, silence.Wallet, silence.StartsAt, silence.EndsAt,
+		silence.Author, silence.Reason, silence.CreatedAt)
+	if err != nil {
+		log.Printf("Error creating silence: %s", err)
+		return silence, err
+	}
+	return silence, nil
+}
+
+// ExpireSilence ends a silence now. It fails if the silence does not exist
+// or has already ended.
+func ExpireSilence(db *sqlx.DB, id int) error {
+	now := time.Now()
+	result, err := db.Exec(`
+		UPDATE silence SET ends_at = $2, starts_at = LEAST(starts_at, $2)
+		WHERE id = $1 AND ends_at > $2
+	`, id, now)
+	if err != nil {
+		log.Printf("Error expiring silence %d: %s", id, err)
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return fmt.Errorf("silence %d does not exist or has already ended", id)
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package alert
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+)
+
+func TestSilenceMatches(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name    string
+		silence Silence
+		subject Subject
+		want    bool
+	}{
+		{"check", Silence{Check: "probe"}, Subject{Check: "probe", Network: "swan"}, true},
+		{"other check", Silence{Check: "probe"}, Subject{Check: "gas", Network: "swan"}, false},
+		{"network across checks", Silence{Network: "swan"}, Subject{Check: "gas", Network: "Swan"}, true},
+		{"check and network", Silence{Check: "probe", Network: "swan"}, Subject{Check: "probe", Network: "sepolia"}, false},
+		{"subdomain", Silence{Domain: "swanchain.io"}, Subject{Check: "ssl", Domain: "rpc.swanchain.io"}, true},
+		{"url", Silence{Domain: "swanchain.io"}, Subject{Check: "http", Domain: "https://api.swanchain.io:8443/health"}, true},
+		{"url with query", Silence{Domain: "swanchain.io"}, Subject{Check: "tls", Domain: "https://rpc.swanchain.io?sni=rpc"}, true},
+		{"url with fragment", Silence{Domain: "swanchain.io"}, Subject{Check: "http", Domain: "https://swanchain.io#status"}, true},
+		{"suffix only", Silence{Domain: "swanchain.io"}, Subject{Check: "ssl", Domain: "notswanchain.io"}, false},
+		{"wallet", Silence{Wallet: "0xABC"}, Subject{Check: "wallet", Wallet: "0xabc"}, true},
+		{"wallet on another subject", Silence{Wallet: "0xabc"}, Subject{Check: "gas", Network: "swan"}, false},
+	}
+
+	for _, tt := range tests {
+		tt.silence.StartsAt, tt.silence.EndsAt = now.Add(-time.Minute), now.Add(time.Hour)
+		if _, got := (Silences{tt.silence}).Match(tt.subject, now); got != tt.want {
+			t.Errorf("%s: Match(%s) = %v, want %v", tt.name, tt.subject, got, tt.want)
+		}
+	}
+
+	silence := Silence{Check: "probe", StartsAt: now.Add(time.Hour), EndsAt: now.Add(2 * time.Hour)}
+	if _, ok := (Silences{silence}).Match(Subject{Check: "probe"}, now); ok {
+		t.Errorf("A silence matched before it started")
+	}
+	if _, ok := (Silences{silence}).Match(Subject{Check: "probe"}, now.Add(2*time.Hour)); ok {
+		t.Errorf("A silence matched when it ended")
+	}
+}
+
+func TestSilenceValidate(t *testing.T) {
+	now := time.Now()
+	valid := Silence{Network: "swan", StartsAt: now, EndsAt: now.Add(time.Hour), Author: "ops", Reason: "node upgrade"}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Validate() = %v for a valid silence", err)
+	}
+
+	noScope := valid
+	noScope.Network = ""
+	backwards := valid
+	backwards.EndsAt = now.Add(-time.Hour)
+	noAuthor := valid
+	noAuthor.Author = " "
+	noReason := valid
+	noReason.Reason = ""
+	for _, silence := range []Silence{noScope, backwards, noAuthor, noReason} {
+		if err := silence.Validate(); err == nil {
+			t.Errorf("Validate() succeeded for %+v", silence)
+		}
+	}
+}
+
+func TestProcessSilenced(t *testing.T) {
+	start := time.Now()
+	probe := Alert{Check: "probe", Key: "swan", Network: "swan", Title: "Probe Transaction Warning", Message: "receipt timeout"}
+	upgrade := Silence{ID: 7, Network: "swan", StartsAt: start.Add(-time.Minute), EndsAt: start.Add(time.Hour), Author: "ops", Reason: "node upgrade"}
+
+	manager, mock := newTestManager(t, Config{RepeatInterval: time.Hour}, start)
+	expectRouting(mock, nil)
+	expectStates(mock, "probe")
+	expectSave(mock, StateFiring)
+	expectSilences(mock, upgrade)
+	notifications, err := manager.Process("probe", []Alert{probe})
+	if err != nil || len(notifications) != 0 {
+		t.Errorf("Process() = %+v, %v for a silenced alert", notifications, err)
+	}
+
+	sepolia := probe
+	sepolia.Key, sepolia.Network = "sepolia", "sepolia"
+	manager, mock = newTestManager(t, Config{RepeatInterval: time.Hour}, start)
+	expectRouting(mock, nil)
+	expectStates(mock, "probe")
+	expectSave(mock, StateFiring)
+	expectSilences(mock, upgrade)
+	notifications, err = manager.Process("probe", []Alert{sepolia})
+	if err != nil || len(notifications) != 1 {
+		t.Errorf("Process() = %+v, %v for an alert on another network", notifications, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unmet expectations: %v", err)
+	}
+}
+
+func TestCreateAndExpireSilence(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	now := time.Now()
+	silence := Silence{Domain: "swanchain.io", StartsAt: now, EndsAt: now.Add(time.Hour), Author: "ops", Reason: "certificate rollout"}
+	mock.ExpectQuery("INSERT INTO silence").
+		WithArgs("", "", "swanchain.io", "", silence.StartsAt, silence.EndsAt, "ops", "certificate rollout", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+	created, err := CreateSilence(sqlxDB, silence)
+	if err != nil || created.ID != 3 {
+		t.Errorf("CreateSilence() = %+v, %v", created, err)
+	}
+
+	if _, err := CreateSilence(sqlxDB, Silence{StartsAt: now, EndsAt: now.Add(time.Hour), Author: "ops", Reason: "x"}); err == nil {
+		t.Errorf("CreateSilence() succeeded without a check, network, domain or wallet")
+	}
+
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE silence SET ends_at")).WithArgs(3, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+	if err := ExpireSilence(sqlxDB, 3); err != nil {
+		t.Errorf("ExpireSilence() = %v", err)
+	}
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE silence SET ends_at")).WithArgs(3, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 0))
+	if err := ExpireSilence(sqlxDB, 3); err == nil {
+		t.Errorf("ExpireSilence() succeeded for an ended silence")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unmet expectations: %v", err)
+	}
+}
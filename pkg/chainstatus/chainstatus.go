@@ -1,28 +1,21 @@
 package chainstatus
 
 import (
-	"bytes"
-	"encoding/json"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
+	"time"
 
 	"github.com/onrik/ethrpc"
 )
 
-type TeamsMessage struct {
-	Type     string `json:"@type"`
-	Context  string `json:"@context"`
-	Summary  string `json:"summary"`
-	Title    string `json:"title"`
-	Text     string `json:"text"`
-	Markdown bool   `json:"markdown"`
-}
+// rpcClient bounds every JSON-RPC request of the checks, so a node that stops
+// answering cannot hold a run until the next one starts.
+var rpcClient = &http.Client{Timeout: 30 * time.Second}
 
 func CheckChainStatus(swan_rpc string) (string, error) {
 	log.Printf("Connecting to Swan Chain node at: %s", swan_rpc)
-	client := ethrpc.New(swan_rpc)
+	client := ethrpc.New(swan_rpc, ethrpc.WithHttpClient(rpcClient))
 
 	blockNumber, err := client.EthBlockNumber()
 	if err != nil {
@@ -53,32 +46,3 @@ func CheckChainStatus(swan_rpc string) (string, error) {
 
 	return "", fmt.Errorf("less than 5 transactions in the last 10 blocks")
 }
-
-func SendTeamsNotification(webhookURL string, message string, isMarkdown bool) {
-	msg := TeamsMessage{
-		Type:     "MessageCard",
-		Context:  "http://schema.org/extensions",
-		Summary:  "Chain Status Warning",
-		Title:    "Chain Status Warning",
-		Text:     message,
-		Markdown: isMarkdown,
-	}
-
-	msgBytes, err := json.Marshal(msg)
-	if err != nil {
-		log.Printf("json marshal error: %s", err)
-		return
-	}
-
-	resp, err := http.Post(webhookURL, "application/json", bytes.NewBuffer(msgBytes))
-	if err != nil {
-		log.Printf("http post error: %s", err)
-		return
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		log.Printf("teams webhook error: %s", bodyBytes)
-	}
-}
@@ -0,0 +1,153 @@
+package chainstatus
+
+import (
+	"encoding/hex"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/onrik/ethrpc"
+)
+
+// ContractCheck is an eth_call declared in the contract_check table together
+// with the condition its result must satisfy.
+//
+// The result is read as a sequence of 32-byte words and WordIndex selects the
+// word to compare. ValueType is uint256 (the default) or int256, in which case
+// the word is read as a two's complement signed integer. Comparison is one of:
+//
+//	eq, ne, gt, gte, lt, lte  compare the word with Expected (decimal or 0x hex, may be negative for int256)
+//	nonzero                   the word is not zero; use the length word of a dynamic return value to check it is non-empty
+//	fresh                     the word is a unix timestamp no older than MaxAgeSeconds
+type ContractCheck struct {
+	ID            int    `db:"id"`
+	Name          string `db:"name"`
+	Network       string `db:"network"`
+	Address       string `db:"address"`
+	CallData      string `db:"call_data"`
+	Comparison    string `db:"comparison"`
+	Expected      string `db:"expected"`
+	WordIndex     int    `db:"word_index"`
+	MaxAgeSeconds int    `db:"max_age_seconds"`
+	ValueType     string `db:"value_type"`
+}
+
+// twoTo256 is 2^256, subtracted from words with the top bit set to read them
+// as int256.
+var twoTo256 = new(big.Int).Lsh(big.NewInt(1), 256)
+
+func GetContractChecks(db *sqlx.DB) ([]ContractCheck, error) {
+	var checks []ContractCheck
+	err := db.Select(&checks, `
+		SELECT id, name, network, address, call_data, comparison, expected, word_index, max_age_seconds, value_type
+		FROM contract_check WHERE is_active = true ORDER BY id
+	`)
+	if err != nil {
+		log.Printf("Error retrieving contract checks: %s", err)
+		return nil, err
+	}
+	return checks, nil
+}
+
+// Evaluate returns nil if the hex-encoded eth_call result satisfies the check.
+func (c ContractCheck) Evaluate(result string, now time.Time) error {
+	data, err := hex.DecodeString(strings.TrimPrefix(result, "0x"))
+	if err != nil {
+		return fmt.Errorf("invalid call result %q: %v", result, err)
+	}
+
+	start := c.WordIndex * 32
+	if c.WordIndex < 0 || len(data) < start+32 {
+		return fmt.Errorf("call returned %d bytes, word %d is out of range", len(data), c.WordIndex)
+	}
+	word := new(big.Int).SetBytes(data[start : start+32])
+	switch c.ValueType {
+	case "", "uint256":
+	case "int256":
+		if word.Bit(255) == 1 {
+			word.Sub(word, twoTo256)
+		}
+	default:
+		return fmt.Errorf("unknown value type %q", c.ValueType)
+	}
+
+	switch c.Comparison {
+	case "nonzero":
+		if word.Sign() == 0 {
+			return fmt.Errorf("word %d is zero", c.WordIndex)
+		}
+		return nil
+	case "fresh":
+		if !word.IsInt64() {
+			return fmt.Errorf("word %d is %s, not a unix timestamp", c.WordIndex, word)
+		}
+		age := now.Sub(time.Unix(word.Int64(), 0))
+		if age > time.Duration(c.MaxAgeSeconds)*time.Second {
+			return fmt.Errorf("timestamp %d is %s old, max age is %ds", word.Int64(), age.Round(time.Second), c.MaxAgeSeconds)
+		}
+		return nil
+	}
+
+	expected, ok := new(big.Int).SetString(c.Expected, 0)
+	if !ok {
+		return fmt.Errorf("invalid expected value %q", c.Expected)
+	}
+
+	cmp := word.Cmp(expected)
+	var pass bool
+	switch c.Comparison {
+	case "eq":
+		pass = cmp == 0
+	case "ne":
+		pass = cmp != 0
+	case "gt":
+		pass = cmp > 0
+	case "gte":
+		pass = cmp >= 0
+	case "lt":
+		pass = cmp < 0
+	case "lte":
+		pass = cmp <= 0
+	default:
+		return fmt.Errorf("unknown comparison %q", c.Comparison)
+	}
+
+	if !pass {
+		return fmt.Errorf("got %s, want %s %s", word, c.Comparison, expected)
+	}
+	return nil
+}
+
+// RunContractCheck performs the check's eth_call and evaluates the result. It
+// returns the raw result along with the outcome so it can be recorded.
+func RunContractCheck(rpcURL string, check ContractCheck, now time.Time) (string, error) {
+	client := ethrpc.New(rpcURL, ethrpc.WithHttpClient(rpcClient))
+
+	result, err := client.EthCall(ethrpc.T{To: check.Address, Data: check.CallData}, "latest")
+	if err != nil {
+		return "", fmt.Errorf("eth_call to %s failed: %v", check.Address, err)
+	}
+
+	return result, check.Evaluate(result, now)
+}
+
+func RecordContractCheckResult(db *sqlx.DB, check ContractCheck, result string, checkErr error) error {
+	var lastError string
+	if checkErr != nil {
+		lastError = checkErr.Error()
+	}
+
+	_, err := db.Exec(`
+		UPDATE contract_check
+		SET last_result = $1, last_error = $2, last_checked_at = $3
+		WHERE id = $4
+	`, result, lastError, time.Now(), check.ID)
+	if err != nil {
+		log.Printf("Error recording result for contract check %s: %s", check.Name, err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package chainstatus
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+)
+
+func word(v int64) string {
+	return fmt.Sprintf("%064x", v)
+}
+
+func TestContractCheckEvaluate(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	// latestRoundData(): roundId, answer, startedAt, updatedAt, answeredInRound
+	roundData := "0x" + word(5) + word(2000) + word(1699999000) + word(1699999900) + word(5)
+	minusOne := strings.Repeat("f", 64)
+
+	tests := []struct {
+		name   string
+		check  ContractCheck
+		result string
+		pass   bool
+	}{
+		{"not paused", ContractCheck{Comparison: "eq", Expected: "0"}, "0x" + word(0), true},
+		{"paused", ContractCheck{Comparison: "eq", Expected: "0"}, "0x" + word(1), false},
+		{"hex expected", ContractCheck{Comparison: "gte", Expected: "0x10"}, "0x" + word(16), true},
+		{"above minimum", ContractCheck{Comparison: "gt", Expected: "1500", WordIndex: 1}, roundData, true},
+		{"not below maximum", ContractCheck{Comparison: "lt", Expected: "1500", WordIndex: 1}, roundData, false},
+		{"negative as uint256", ContractCheck{Comparison: "gt", Expected: "0"}, "0x" + minusOne, true},
+		{"negative as int256", ContractCheck{Comparison: "gt", Expected: "0", ValueType: "int256"}, "0x" + minusOne, false},
+		{"negative expected", ContractCheck{Comparison: "eq", Expected: "-1", ValueType: "int256"}, "0x" + minusOne, true},
+		{"positive int256", ContractCheck{Comparison: "gte", Expected: "2000", WordIndex: 1, ValueType: "int256"}, roundData, true},
+		{"unknown value type", ContractCheck{Comparison: "eq", Expected: "0", ValueType: "int8"}, "0x" + word(0), false},
+		{"fresh", ContractCheck{Comparison: "fresh", WordIndex: 3, MaxAgeSeconds: 3600}, roundData, true},
+		{"stale", ContractCheck{Comparison: "fresh", WordIndex: 3, MaxAgeSeconds: 60}, roundData, false},
+		{"timestamp overflow", ContractCheck{Comparison: "fresh", MaxAgeSeconds: 3600}, "0x" + minusOne, false},
+		{"non-empty array", ContractCheck{Comparison: "nonzero", WordIndex: 1}, "0x" + word(32) + word(2) + word(7) + word(8), true},
+		{"empty array", ContractCheck{Comparison: "nonzero", WordIndex: 1}, "0x" + word(32) + word(0), false},
+		{"short result", ContractCheck{Comparison: "nonzero", WordIndex: 2}, "0x" + word(1), false},
+		{"unknown comparison", ContractCheck{Comparison: "approx", Expected: "1"}, "0x" + word(1), false},
+	}
+
+	for _, tt := range tests {
+		err := tt.check.Evaluate(tt.result, now)
+		if (err == nil) != tt.pass {
+			t.Errorf("%s: Evaluate() = %v, want pass %v", tt.name, err, tt.pass)
+		}
+	}
+}
+
+func TestRunContractCheck(t *testing.T) {
+	server := newFakeRPC(t, map[string]rpcHandler{
+		"eth_call": func(params []json.RawMessage) (interface{}, error) {
+			var call struct {
+				To   string `json:"to"`
+				Data string `json:"data"`
+			}
+			json.Unmarshal(params[0], &call)
+			if call.To != "0xbridge" || call.Data != "0x5c975abb" {
+				return nil, fmt.Errorf("unexpected call %+v", call)
+			}
+			return "0x" + word(1), nil
+		},
+	})
+
+	check := ContractCheck{Name: "bridge-paused", Address: "0xbridge", CallData: "0x5c975abb", Comparison: "eq", Expected: "0"}
+	result, err := RunContractCheck(server.URL, check, time.Now())
+	if result != "0x"+word(1) {
+		t.Errorf("Unexpected result: %s", result)
+	}
+	if err == nil || !strings.Contains(err.Error(), "got 1, want eq 0") {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestGetContractChecks(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	rows := sqlmock.NewRows([]string{"id", "name", "network", "address", "call_data", "comparison", "expected", "word_index", "max_age_seconds", "value_type"}).
+		AddRow(1, "bridge-paused", "sepolia", "0xbridge", "0x5c975abb", "eq", "0", 0, 0, "uint256")
+	mock.ExpectQuery("SELECT id, name, network, address, call_data, comparison, expected, word_index, max_age_seconds, value_type FROM contract_check WHERE is_active = true").WillReturnRows(rows)
+
+	checks, err := GetContractChecks(sqlxDB)
+	if err != nil {
+		t.Fatalf("GetContractChecks() returned error: %v", err)
+	}
+
+	if len(checks) != 1 || checks[0].Name != "bridge-paused" || checks[0].Network != "sepolia" || checks[0].Comparison != "eq" {
+		t.Errorf("Unexpected checks: %+v", checks)
+	}
+}
@@ -0,0 +1,207 @@
+package chainstatus
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/big"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/onrik/ethrpc"
+	"github.com/swanchain/domain-check/pkg/model"
+)
+
+// GasSample is a single reading of a network's fee market, in gwei.
+type GasSample struct {
+	Network         string    `db:"network"`
+	GasPriceGwei    float64   `db:"gas_price_gwei"`
+	PriorityFeeGwei float64   `db:"priority_fee_gwei"`
+	BaseFeeGwei     float64   `db:"base_fee_gwei"`
+	SampledAt       time.Time `db:"sampled_at"`
+}
+
+// EffectiveGwei is the price a transaction would pay to be included in the
+// next block: the legacy gas price or base fee plus tip, whichever is higher.
+func (s GasSample) EffectiveGwei() float64 {
+	eip1559 := s.BaseFeeGwei + s.PriorityFeeGwei
+	if eip1559 > s.GasPriceGwei {
+		return eip1559
+	}
+	return s.GasPriceGwei
+}
+
+// GasConfig holds the per-network fee ceilings in gwei, how long a ceiling
+// must be exceeded before we alert and how long samples are kept.
+type GasConfig struct {
+	Ceilings  map[string]float64
+	Sustain   time.Duration
+	Retention time.Duration
+}
+
+func GetGasConfig(db *sqlx.DB) (GasConfig, error) {
+	cfg := GasConfig{
+		Ceilings:  map[string]float64{},
+		Sustain:   15 * time.Minute,
+		Retention: 30 * 24 * time.Hour,
+	}
+
+	var configs []model.Info
+	err := db.Select(&configs, "SELECT key, value FROM info WHERE is_active = true AND type = 'gas'")
+	if err != nil {
+		return cfg, err
+	}
+
+	for _, config := range configs {
+		switch {
+		case config.Key == "sustain-minutes":
+			minutes, err := strconv.Atoi(config.Value)
+			if err != nil {
+				return cfg, fmt.Errorf("invalid %s %q: %v", config.Key, config.Value, err)
+			}
+			cfg.Sustain = time.Duration(minutes) * time.Minute
+		case config.Key == "retention-days":
+			days, err := strconv.Atoi(config.Value)
+			if err != nil || days < 1 {
+				return cfg, fmt.Errorf("invalid %s %q", config.Key, config.Value)
+			}
+			cfg.Retention = time.Duration(days) * 24 * time.Hour
+		case strings.HasSuffix(config.Key, "-ceiling-gwei"):
+			ceiling, err := strconv.ParseFloat(config.Value, 64)
+			if err != nil {
+				return cfg, fmt.Errorf("invalid %s %q: %v", config.Key, config.Value, err)
+			}
+			cfg.Ceilings[strings.TrimSuffix(config.Key, "-ceiling-gwei")] = ceiling
+		}
+	}
+
+	return cfg, nil
+}
+
+func weiToGwei(wei *big.Int) float64 {
+	gwei, _ := new(big.Float).Quo(new(big.Float).SetInt(wei), big.NewFloat(1e9)).Float64()
+	return gwei
+}
+
+func parseHexWei(s string) (*big.Int, error) {
+	wei, ok := new(big.Int).SetString(strings.TrimPrefix(s, "0x"), 16)
+	if !ok {
+		return nil, fmt.Errorf("invalid hex quantity %q", s)
+	}
+	return wei, nil
+}
+
+// SampleGas reads eth_gasPrice, eth_maxPriorityFeePerGas and the pending base
+// fee from eth_feeHistory for a network.
+func SampleGas(network, rpcURL string) (GasSample, error) {
+	client := ethrpc.New(rpcURL, ethrpc.WithHttpClient(rpcClient))
+	sample := GasSample{Network: network, SampledAt: time.Now()}
+
+	gasPrice, err := client.EthGasPrice()
+	if err != nil {
+		return sample, fmt.Errorf("failed to get gas price for %s: %v", network, err)
+	}
+	sample.GasPriceGwei = weiToGwei(&gasPrice)
+
+	raw, err := client.Call("eth_maxPriorityFeePerGas")
+	if err != nil {
+		return sample, fmt.Errorf("failed to get max priority fee for %s: %v", network, err)
+	}
+	var tip string
+	if err := json.Unmarshal(raw, &tip); err != nil {
+		return sample, err
+	}
+	tipWei, err := parseHexWei(tip)
+	if err != nil {
+		return sample, err
+	}
+	sample.PriorityFeeGwei = weiToGwei(tipWei)
+
+	raw, err = client.Call("eth_feeHistory", "0x1", "latest", []float64{})
+	if err != nil {
+		return sample, fmt.Errorf("failed to get fee history for %s: %v", network, err)
+	}
+	var history struct {
+		BaseFeePerGas []string `json:"baseFeePerGas"`
+	}
+	if err := json.Unmarshal(raw, &history); err != nil {
+		return sample, err
+	}
+	// The last entry is the base fee of the block after the newest one returned.
+	if n := len(history.BaseFeePerGas); n > 0 {
+		baseFee, err := parseHexWei(history.BaseFeePerGas[n-1])
+		if err != nil {
+			return sample, err
+		}
+		sample.BaseFeeGwei = weiToGwei(baseFee)
+	}
+
+	log.Printf("%s gas: price %.3f gwei, base fee %.3f gwei, priority fee %.3f gwei",
+		network, sample.GasPriceGwei, sample.BaseFeeGwei, sample.PriorityFeeGwei)
+	return sample, nil
+}
+
+func RecordGasSample(db *sqlx.DB, sample GasSample) error {
+	_, err := db.Exec(`
+		INSERT INTO gas_sample (network, gas_price_gwei, priority_fee_gwei, base_fee_gwei, sampled_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`, sample.Network, sample.GasPriceGwei, sample.PriorityFeeGwei, sample.BaseFeeGwei, sample.SampledAt)
+	if err != nil {
+		log.Printf("Error recording gas sample for %s: %s", sample.Network, err)
+		return err
+	}
+	return nil
+}
+
+// PruneGasSamples deletes the samples of a network taken before cutoff.
+func PruneGasSamples(db *sqlx.DB, network string, cutoff time.Time) error {
+	_, err := db.Exec("DELETE FROM gas_sample WHERE network = $1 AND sampled_at < $2", network, cutoff)
+	if err != nil {
+		log.Printf("Error pruning gas samples for %s: %s", network, err)
+		return err
+	}
+	return nil
+}
+
+// GasMonitor tracks how long each network has been above its ceiling. It is
+// safe for concurrent use, since a slow run of the gas job can overlap the
+// next one.
+type GasMonitor struct {
+	mu            sync.Mutex
+	exceededSince map[string]time.Time
+}
+
+func NewGasMonitor() *GasMonitor {
+	return &GasMonitor{exceededSince: map[string]time.Time{}}
+}
+
+// Observe records a sample and returns a warning while a network has stayed
+// above its ceiling for the sustain period. The alert manager turns the
+// repeated warnings into a single alert that resolves once fees drop.
+func (m *GasMonitor) Observe(sample GasSample, cfg GasConfig) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ceiling, ok := cfg.Ceilings[sample.Network]
+	if !ok || sample.EffectiveGwei() <= ceiling {
+		delete(m.exceededSince, sample.Network)
+		return ""
+	}
+
+	since, ok := m.exceededSince[sample.Network]
+	if !ok {
+		since = sample.SampledAt
+		m.exceededSince[sample.Network] = since
+	}
+
+	if sample.SampledAt.Sub(since) < cfg.Sustain {
+		return ""
+	}
+
+	return fmt.Sprintf("Gas on %s has been above the %.2f gwei ceiling since %s: currently %.2f gwei (base fee %.2f, priority fee %.2f, gas price %.2f).",
+		sample.Network, ceiling, since.Format(time.RFC3339), sample.EffectiveGwei(),
+		sample.BaseFeeGwei, sample.PriorityFeeGwei, sample.GasPriceGwei)
+}
@@ -0,0 +1,143 @@
+package chainstatus
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+)
+
+func TestSampleGas(t *testing.T) {
+	server := newFakeRPC(t, map[string]rpcHandler{
+		"eth_gasPrice": func(params []json.RawMessage) (interface{}, error) {
+			return "0x2540be400", nil // 10 gwei
+		},
+		"eth_maxPriorityFeePerGas": func(params []json.RawMessage) (interface{}, error) {
+			return "0x77359400", nil // 2 gwei
+		},
+		"eth_feeHistory": func(params []json.RawMessage) (interface{}, error) {
+			return map[string]interface{}{
+				"oldestBlock":   "0x10",
+				"baseFeePerGas": []string{"0x1dcd65000", "0x2540be400"}, // 8 gwei, 10 gwei
+			}, nil
+		},
+	})
+
+	sample, err := SampleGas("sepolia", server.URL)
+	if err != nil {
+		t.Fatalf("SampleGas() returned error: %v", err)
+	}
+
+	if sample.GasPriceGwei != 10 || sample.PriorityFeeGwei != 2 || sample.BaseFeeGwei != 10 {
+		t.Errorf("Unexpected sample: %+v", sample)
+	}
+	if sample.EffectiveGwei() != 12 {
+		t.Errorf("EffectiveGwei() = %v, want 12", sample.EffectiveGwei())
+	}
+}
+
+func TestGasMonitorObserve(t *testing.T) {
+	cfg := GasConfig{Ceilings: map[string]float64{"sepolia": 50}, Sustain: 10 * time.Minute}
+	m := NewGasMonitor()
+	start := time.Now()
+
+	sample := func(gwei float64, after time.Duration) GasSample {
+		return GasSample{Network: "sepolia", GasPriceGwei: gwei, SampledAt: start.Add(after)}
+	}
+
+	if got := m.Observe(sample(80, 0), cfg); got != "" {
+		t.Errorf("Observe() warned before sustain period: %q", got)
+	}
+	if got := m.Observe(sample(80, 5*time.Minute), cfg); got != "" {
+		t.Errorf("Observe() warned before sustain period: %q", got)
+	}
+	if got := m.Observe(sample(80, 10*time.Minute), cfg); !strings.Contains(got, "above the 50.00 gwei ceiling") {
+		t.Errorf("Observe() did not warn after sustain period: %q", got)
+	}
+	if got := m.Observe(sample(80, 11*time.Minute), cfg); !strings.Contains(got, "since "+start.Format(time.RFC3339)) {
+		t.Errorf("Observe() stopped warning during the same episode: %q", got)
+	}
+
+	// Dropping below the ceiling resets the episode.
+	m.Observe(sample(20, 12*time.Minute), cfg)
+	if got := m.Observe(sample(80, 13*time.Minute), cfg); got != "" {
+		t.Errorf("Observe() warned at the start of a new episode: %q", got)
+	}
+	if got := m.Observe(sample(80, 23*time.Minute), cfg); got == "" {
+		t.Errorf("Observe() did not warn for the new episode")
+	}
+
+	if got := m.Observe(GasSample{Network: "swan", GasPriceGwei: 1000, SampledAt: start}, cfg); got != "" {
+		t.Errorf("Observe() warned for a network without a ceiling: %q", got)
+	}
+}
+
+func TestGetGasConfig(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	rows := sqlmock.NewRows([]string{"key", "value"}).
+		AddRow("sepolia-ceiling-gwei", "75.5").
+		AddRow("sustain-minutes", "5").
+		AddRow("retention-days", "7")
+	mock.ExpectQuery("SELECT key, value FROM info WHERE is_active = true AND type = 'gas'").WillReturnRows(rows)
+
+	cfg, err := GetGasConfig(sqlxDB)
+	if err != nil {
+		t.Fatalf("GetGasConfig() returned error: %v", err)
+	}
+
+	if cfg.Ceilings["sepolia"] != 75.5 || cfg.Sustain != 5*time.Minute || cfg.Retention != 7*24*time.Hour {
+		t.Errorf("Unexpected config: %+v", cfg)
+	}
+
+	mock.ExpectQuery("SELECT key, value FROM info").WillReturnRows(sqlmock.NewRows([]string{"key", "value"}).AddRow("retention-days", "0"))
+	if _, err := GetGasConfig(sqlxDB); err == nil {
+		t.Errorf("GetGasConfig() accepted a retention of 0 days")
+	}
+}
+
+func TestPruneGasSamples(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	cutoff := time.Now().Add(-30 * 24 * time.Hour)
+	mock.ExpectExec("DELETE FROM gas_sample WHERE network = \\$1 AND sampled_at < \\$2").
+		WithArgs("sepolia", cutoff).WillReturnResult(sqlmock.NewResult(0, 12))
+	if err := PruneGasSamples(sqlx.NewDb(db, "sqlmock"), "sepolia", cutoff); err != nil {
+		t.Errorf("PruneGasSamples() returned error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestGasMonitorConcurrent(t *testing.T) {
+	cfg := GasConfig{Ceilings: map[string]float64{"sepolia": 50, "swan": 5}, Sustain: time.Minute}
+	m := NewGasMonitor()
+	start := time.Now()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for _, network := range []string{"sepolia", "swan"} {
+				m.Observe(GasSample{Network: network, GasPriceGwei: float64(i * 10), SampledAt: start.Add(time.Duration(i) * time.Second)}, cfg)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
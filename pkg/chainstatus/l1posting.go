@@ -0,0 +1,255 @@
+package chainstatus
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/onrik/ethrpc"
+	"github.com/swanchain/domain-check/pkg/model"
+)
+
+const (
+	// keccak256("OutputProposed(bytes32,uint256,uint256,uint256)"), emitted by L2OutputOracle.
+	outputProposedTopic = "0xa7aaf2512769da4e444e3de247be2564225c2e7a8f74cfe528e46e17d24868e2"
+	// keccak256("DisputeGameCreated(address,uint32,bytes32)"), emitted by DisputeGameFactory.
+	disputeGameCreatedTopic = "0x5b565efe82411da98814f356d0e7bcb8f0219b8d970307c5afb4a6903a8b2e35"
+
+	l1BlockTime = 12 * time.Second
+
+	PostingKindBatch  = "batch"
+	PostingKindOutput = "output"
+)
+
+// PostingConfig describes the L1 contracts the batcher and proposer post to
+// and how stale each kind of posting may become before we alert.
+type PostingConfig struct {
+	BatchInbox         string
+	OutputOracle       string
+	DisputeGameFactory string
+	MaxBatchAge        time.Duration
+	MaxOutputAge       time.Duration
+}
+
+// Posting is the most recent batch or output root seen on L1.
+type Posting struct {
+	Kind        string    `db:"kind"`
+	Address     string    `db:"address"`
+	BlockNumber int       `db:"block_number"`
+	TxHash      string    `db:"tx_hash"`
+	PostedAt    time.Time `db:"posted_at"`
+}
+
+func GetPostingConfig(db *sqlx.DB) (PostingConfig, error) {
+	cfg := PostingConfig{
+		MaxBatchAge:  30 * time.Minute,
+		MaxOutputAge: 90 * time.Minute,
+	}
+
+	var configs []model.Info
+	err := db.Select(&configs, "SELECT key, value FROM info WHERE is_active = true AND type = 'l1-posting'")
+	if err != nil {
+		return cfg, err
+	}
+
+	for _, config := range configs {
+		switch config.Key {
+		case "batch-inbox":
+			cfg.BatchInbox = config.Value
+		case "l2-output-oracle":
+			cfg.OutputOracle = config.Value
+		case "dispute-game-factory":
+			cfg.DisputeGameFactory = config.Value
+		case "batch-max-age-minutes", "output-max-age-minutes":
+			minutes, err := strconv.Atoi(config.Value)
+			if err != nil {
+				return cfg, fmt.Errorf("invalid %s %q: %v", config.Key, config.Value, err)
+			}
+			if config.Key == "batch-max-age-minutes" {
+				cfg.MaxBatchAge = time.Duration(minutes) * time.Minute
+			} else {
+				cfg.MaxOutputAge = time.Duration(minutes) * time.Minute
+			}
+		}
+	}
+
+	return cfg, nil
+}
+
+// lookbackBlocks returns how many L1 blocks cover maxAge, plus a small margin,
+// so a posting that is not found in that range is known to be too old.
+func lookbackBlocks(maxAge time.Duration) int {
+	return int(maxAge/l1BlockTime) + 10
+}
+
+// FindLastBatch scans L1 blocks backwards from the head and returns the newest
+// transaction sent to the batch inbox, or nil if none was found within maxAge.
+func FindLastBatch(client *ethrpc.EthRPC, inbox string, maxAge time.Duration) (*Posting, error) {
+	head, err := client.EthBlockNumber()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the latest block number: %v", err)
+	}
+
+	stop := head - lookbackBlocks(maxAge)
+	if stop < 0 {
+		stop = 0
+	}
+
+	for i := head; i >= stop; i-- {
+		block, err := client.EthGetBlockByNumber(i, true)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get block %d: %v", i, err)
+		}
+		if block == nil {
+			continue
+		}
+
+		for _, tx := range block.Transactions {
+			if strings.EqualFold(tx.To, inbox) {
+				return &Posting{
+					Kind:        PostingKindBatch,
+					Address:     inbox,
+					BlockNumber: block.Number,
+					TxHash:      tx.Hash,
+					PostedAt:    time.Unix(int64(block.Timestamp), 0).UTC(),
+				}, nil
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+// FindLastOutput returns the newest output root proposal, read from either the
+// L2OutputOracle or the DisputeGameFactory depending on which is configured.
+func FindLastOutput(client *ethrpc.EthRPC, cfg PostingConfig) (*Posting, error) {
+	address, topic := cfg.OutputOracle, outputProposedTopic
+	if cfg.DisputeGameFactory != "" {
+		address, topic = cfg.DisputeGameFactory, disputeGameCreatedTopic
+	}
+
+	head, err := client.EthBlockNumber()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the latest block number: %v", err)
+	}
+
+	from := head - lookbackBlocks(cfg.MaxOutputAge)
+	if from < 0 {
+		from = 0
+	}
+
+	logs, err := client.EthGetLogs(ethrpc.FilterParams{
+		FromBlock: ethrpc.IntToHex(from),
+		ToBlock:   "latest",
+		Address:   []string{address},
+		Topics:    [][]string{{topic}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get logs for %s: %v", address, err)
+	}
+
+	var last *ethrpc.Log
+	for i := range logs {
+		if logs[i].Removed {
+			continue
+		}
+		if last == nil || logs[i].BlockNumber > last.BlockNumber ||
+			(logs[i].BlockNumber == last.BlockNumber && logs[i].LogIndex > last.LogIndex) {
+			last = &logs[i]
+		}
+	}
+	if last == nil {
+		return nil, nil
+	}
+
+	block, err := client.EthGetBlockByNumber(last.BlockNumber, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get block %d: %v", last.BlockNumber, err)
+	}
+	if block == nil {
+		return nil, fmt.Errorf("block %d not found", last.BlockNumber)
+	}
+
+	return &Posting{
+		Kind:        PostingKindOutput,
+		Address:     address,
+		BlockNumber: last.BlockNumber,
+		TxHash:      last.TransactionHash,
+		PostedAt:    time.Unix(int64(block.Timestamp), 0).UTC(),
+	}, nil
+}
+
+// CheckL1Posting looks up the last batch and output root posted to L1 and
+// returns the postings it found together with a warning for each one that is
+// missing or older than its threshold.
+func CheckL1Posting(sepolia_rpc string, cfg PostingConfig, now time.Time) ([]Posting, []string, error) {
+	log.Printf("Checking L1 postings via: %s", sepolia_rpc)
+	client := ethrpc.New(sepolia_rpc, ethrpc.WithHttpClient(rpcClient))
+
+	var postings []Posting
+	var warnings []string
+
+	if cfg.BatchInbox != "" {
+		batch, err := FindLastBatch(client, cfg.BatchInbox, cfg.MaxBatchAge)
+		if err != nil {
+			return nil, nil, err
+		}
+		if batch != nil {
+			postings = append(postings, *batch)
+		}
+		if warning := postingWarning(PostingKindBatch, cfg.BatchInbox, batch, cfg.MaxBatchAge, now); warning != "" {
+			warnings = append(warnings, warning)
+		}
+	}
+
+	if cfg.OutputOracle != "" || cfg.DisputeGameFactory != "" {
+		output, err := FindLastOutput(client, cfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		if output != nil {
+			postings = append(postings, *output)
+		}
+		address := cfg.OutputOracle
+		if cfg.DisputeGameFactory != "" {
+			address = cfg.DisputeGameFactory
+		}
+		if warning := postingWarning(PostingKindOutput, address, output, cfg.MaxOutputAge, now); warning != "" {
+			warnings = append(warnings, warning)
+		}
+	}
+
+	return postings, warnings, nil
+}
+
+func postingWarning(kind, address string, posting *Posting, maxAge time.Duration, now time.Time) string {
+	if posting == nil {
+		return fmt.Sprintf("No %s posted to %s in the last %s.", kind, address, maxAge)
+	}
+
+	age := now.Sub(posting.PostedAt)
+	if age <= maxAge {
+		return ""
+	}
+	return fmt.Sprintf("Last %s posted to %s at %s (block %d, tx %s) is %s old, threshold is %s.",
+		kind, address, posting.PostedAt.Format(time.RFC3339), posting.BlockNumber, posting.TxHash,
+		age.Round(time.Second), maxAge)
+}
+
+func RecordPosting(db *sqlx.DB, posting Posting) error {
+	_, err := db.Exec(`
+		INSERT INTO l1_posting (kind, address, block_number, tx_hash, posted_at, checked_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (kind, address) DO UPDATE
+		SET block_number = EXCLUDED.block_number, tx_hash = EXCLUDED.tx_hash,
+			posted_at = EXCLUDED.posted_at, checked_at = EXCLUDED.checked_at
+	`, posting.Kind, posting.Address, posting.BlockNumber, posting.TxHash, posting.PostedAt, time.Now())
+	if err != nil {
+		log.Printf("Error recording %s posting for %s: %s", posting.Kind, posting.Address, err)
+		return err
+	}
+	return nil
+}
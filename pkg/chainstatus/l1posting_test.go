@@ -0,0 +1,170 @@
+package chainstatus
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+)
+
+type rpcHandler func(params []json.RawMessage) (interface{}, error)
+
+// newFakeRPC starts a JSON-RPC server that answers each method with the given handler.
+func newFakeRPC(t *testing.T, handlers map[string]rpcHandler) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     int               `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("Failed to decode RPC request: %v", err)
+			return
+		}
+
+		resp := map[string]interface{}{"jsonrpc": "2.0", "id": req.ID}
+		handler, ok := handlers[req.Method]
+		if !ok {
+			resp["error"] = map[string]interface{}{"code": -32601, "message": "method not found: " + req.Method}
+		} else if result, err := handler(req.Params); err != nil {
+			resp["error"] = map[string]interface{}{"code": -32000, "message": err.Error()}
+		} else {
+			resp["result"] = result
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func hexInt(i int64) string {
+	return fmt.Sprintf("0x%x", i)
+}
+
+func TestCheckL1Posting(t *testing.T) {
+	inbox := "0xff00000000000000000000000000000000000001"
+	oracle := "0x0000000000000000000000000000000000000002"
+	now := time.Unix(1700003600, 0)
+
+	// Block n has timestamp 1700000000 + 12n; the batch is in block 290, the output in block 200.
+	server := newFakeRPC(t, map[string]rpcHandler{
+		"eth_blockNumber": func(params []json.RawMessage) (interface{}, error) {
+			return hexInt(300), nil
+		},
+		"eth_getBlockByNumber": func(params []json.RawMessage) (interface{}, error) {
+			var number string
+			json.Unmarshal(params[0], &number)
+			var n int64
+			fmt.Sscanf(number, "0x%x", &n)
+
+			txs := []interface{}{}
+			if n == 290 {
+				txs = append(txs, map[string]interface{}{"hash": "0xbatch", "to": strings.ToUpper(inbox[:2]) + inbox[2:], "from": "0xbatcher"})
+			}
+			return map[string]interface{}{
+				"number":       hexInt(n),
+				"timestamp":    hexInt(1700000000 + 12*n),
+				"transactions": txs,
+			}, nil
+		},
+		"eth_getLogs": func(params []json.RawMessage) (interface{}, error) {
+			var filter struct {
+				Address []string   `json:"address"`
+				Topics  [][]string `json:"topics"`
+			}
+			json.Unmarshal(params[0], &filter)
+			if filter.Address[0] != oracle || filter.Topics[0][0] != outputProposedTopic {
+				return nil, fmt.Errorf("unexpected filter %v", filter)
+			}
+			return []interface{}{
+				map[string]interface{}{"blockNumber": hexInt(150), "logIndex": "0x0", "transactionHash": "0xold", "address": oracle},
+				map[string]interface{}{"blockNumber": hexInt(200), "logIndex": "0x1", "transactionHash": "0xoutput", "address": oracle},
+			}, nil
+		},
+	})
+
+	cfg := PostingConfig{
+		BatchInbox:   inbox,
+		OutputOracle: oracle,
+		MaxBatchAge:  30 * time.Minute,
+		MaxOutputAge: 10 * time.Minute,
+	}
+
+	postings, warnings, err := CheckL1Posting(server.URL, cfg, now)
+	if err != nil {
+		t.Fatalf("CheckL1Posting() returned error: %v", err)
+	}
+
+	if len(postings) != 2 {
+		t.Fatalf("Unexpected number of postings: got %d, want 2", len(postings))
+	}
+	if postings[0].Kind != PostingKindBatch || postings[0].BlockNumber != 290 || postings[0].TxHash != "0xbatch" {
+		t.Errorf("Unexpected batch posting: %+v", postings[0])
+	}
+	if postings[1].Kind != PostingKindOutput || postings[1].BlockNumber != 200 || postings[1].TxHash != "0xoutput" {
+		t.Errorf("Unexpected output posting: %+v", postings[1])
+	}
+	if !postings[1].PostedAt.Equal(time.Unix(1700000000+12*200, 0)) {
+		t.Errorf("Unexpected output PostedAt: %v", postings[1].PostedAt)
+	}
+
+	// The batch is 10 minutes old and within its threshold; the output is 20 minutes old.
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "Last output posted") {
+		t.Errorf("Unexpected warnings: %v", warnings)
+	}
+}
+
+func TestCheckL1PostingMissingBatch(t *testing.T) {
+	server := newFakeRPC(t, map[string]rpcHandler{
+		"eth_blockNumber": func(params []json.RawMessage) (interface{}, error) {
+			return hexInt(20), nil
+		},
+		"eth_getBlockByNumber": func(params []json.RawMessage) (interface{}, error) {
+			return map[string]interface{}{"number": "0x1", "timestamp": "0x1", "transactions": []interface{}{}}, nil
+		},
+	})
+
+	cfg := PostingConfig{BatchInbox: "0xff00000000000000000000000000000000000001", MaxBatchAge: time.Minute}
+	postings, warnings, err := CheckL1Posting(server.URL, cfg, time.Now())
+	if err != nil {
+		t.Fatalf("CheckL1Posting() returned error: %v", err)
+	}
+
+	if len(postings) != 0 {
+		t.Errorf("Unexpected postings: %v", postings)
+	}
+	if len(warnings) != 1 || !strings.HasPrefix(warnings[0], "No batch posted") {
+		t.Errorf("Unexpected warnings: %v", warnings)
+	}
+}
+
+func TestGetPostingConfig(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	rows := sqlmock.NewRows([]string{"key", "value"}).
+		AddRow("batch-inbox", "0xinbox").
+		AddRow("dispute-game-factory", "0xfactory").
+		AddRow("batch-max-age-minutes", "15")
+	mock.ExpectQuery("SELECT key, value FROM info WHERE is_active = true AND type = 'l1-posting'").WillReturnRows(rows)
+
+	cfg, err := GetPostingConfig(sqlxDB)
+	if err != nil {
+		t.Fatalf("GetPostingConfig() returned error: %v", err)
+	}
+
+	if cfg.BatchInbox != "0xinbox" || cfg.DisputeGameFactory != "0xfactory" || cfg.MaxBatchAge != 15*time.Minute || cfg.MaxOutputAge != 90*time.Minute {
+		t.Errorf("Unexpected config: %+v", cfg)
+	}
+}
@@ -0,0 +1,228 @@
+package chainstatus
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/jmoiron/sqlx"
+	"github.com/swanchain/domain-check/pkg/model"
+)
+
+// ProbeClient is the part of ethclient.Client used by the probe. The client of
+// go-ethereum's simulated backend implements it as well.
+type ProbeClient interface {
+	ChainID(ctx context.Context) (*big.Int, error)
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+	PendingNonceAt(ctx context.Context, account common.Address) (uint64, error)
+	SuggestGasPrice(ctx context.Context) (*big.Int, error)
+	SuggestGasTipCap(ctx context.Context) (*big.Int, error)
+	SendTransaction(ctx context.Context, tx *types.Transaction) error
+	TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error)
+}
+
+type ProbeConfig struct {
+	Timeout      time.Duration
+	MaxInclusion time.Duration
+	PollInterval time.Duration
+	Retention    time.Duration
+}
+
+// ProbeResult is the outcome of one synthetic self-transfer.
+type ProbeResult struct {
+	Network       string
+	TxHash        string
+	Status        uint64
+	BlockNumber   uint64
+	InclusionTime time.Duration
+	SentAt        time.Time
+	Error         string
+}
+
+func GetProbeConfig(db *sqlx.DB) (ProbeConfig, error) {
+	cfg := ProbeConfig{
+		Timeout:      5 * time.Minute,
+		MaxInclusion: time.Minute,
+		PollInterval: 2 * time.Second,
+		Retention:    30 * 24 * time.Hour,
+	}
+
+	var configs []model.Info
+	err := db.Select(&configs, "SELECT key, value FROM info WHERE is_active = true AND type = 'probe'")
+	if err != nil {
+		return cfg, err
+	}
+
+	for _, config := range configs {
+		switch config.Key {
+		case "timeout-seconds", "max-inclusion-seconds":
+			seconds, err := strconv.Atoi(config.Value)
+			if err != nil || seconds < 1 {
+				return cfg, fmt.Errorf("invalid %s %q", config.Key, config.Value)
+			}
+			if config.Key == "timeout-seconds" {
+				cfg.Timeout = time.Duration(seconds) * time.Second
+			} else {
+				cfg.MaxInclusion = time.Duration(seconds) * time.Second
+			}
+		case "retention-days":
+			days, err := strconv.Atoi(config.Value)
+			if err != nil || days < 1 {
+				return cfg, fmt.Errorf("invalid %s %q", config.Key, config.Value)
+			}
+			cfg.Retention = time.Duration(days) * 24 * time.Hour
+		}
+	}
+
+	return cfg, nil
+}
+
+func LoadProbeKey(hexKey string) (*ecdsa.PrivateKey, error) {
+	key, err := crypto.HexToECDSA(strings.TrimPrefix(hexKey, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid probe key: %v", err)
+	}
+	return key, nil
+}
+
+// RunProbe sends a zero-value transfer from the probe key to itself and waits
+// for its receipt. Sending and waiting each take at most cfg.Timeout. A
+// non-nil error means the probe could not be sent at all; timeouts and
+// reverted transactions are reported in the result.
+func RunProbe(ctx context.Context, network string, client ProbeClient, key *ecdsa.PrivateKey, cfg ProbeConfig) (ProbeResult, error) {
+	result := ProbeResult{Network: network}
+	from := crypto.PubkeyToAddress(key.PublicKey)
+
+	sendCtx, cancelSend := context.WithTimeout(ctx, cfg.Timeout)
+	defer cancelSend()
+
+	chainID, err := client.ChainID(sendCtx)
+	if err != nil {
+		return result, fmt.Errorf("failed to get chain id for %s: %v", network, err)
+	}
+
+	nonce, err := client.PendingNonceAt(sendCtx, from)
+	if err != nil {
+		return result, fmt.Errorf("failed to get nonce for %s on %s: %v", from.Hex(), network, err)
+	}
+
+	head, err := client.HeaderByNumber(sendCtx, nil)
+	if err != nil {
+		return result, fmt.Errorf("failed to get the latest header for %s: %v", network, err)
+	}
+
+	var txData types.TxData
+	if head.BaseFee != nil {
+		tip, err := client.SuggestGasTipCap(sendCtx)
+		if err != nil {
+			return result, fmt.Errorf("failed to get gas tip for %s: %v", network, err)
+		}
+		feeCap := new(big.Int).Add(new(big.Int).Mul(head.BaseFee, big.NewInt(2)), tip)
+		txData = &types.DynamicFeeTx{
+			ChainID:   chainID,
+			Nonce:     nonce,
+			GasTipCap: tip,
+			GasFeeCap: feeCap,
+			Gas:       21000,
+			To:        &from,
+			Value:     big.NewInt(0),
+		}
+	} else {
+		gasPrice, err := client.SuggestGasPrice(sendCtx)
+		if err != nil {
+			return result, fmt.Errorf("failed to get gas price for %s: %v", network, err)
+		}
+		txData = &types.LegacyTx{
+			Nonce:    nonce,
+			GasPrice: gasPrice,
+			Gas:      21000,
+			To:       &from,
+			Value:    big.NewInt(0),
+		}
+	}
+
+	tx, err := types.SignNewTx(key, types.LatestSignerForChainID(chainID), txData)
+	if err != nil {
+		return result, fmt.Errorf("failed to sign probe transaction: %v", err)
+	}
+
+	result.TxHash = tx.Hash().Hex()
+	result.SentAt = time.Now()
+	if err := client.SendTransaction(sendCtx, tx); err != nil {
+		return result, fmt.Errorf("failed to send probe transaction on %s: %v", network, err)
+	}
+	log.Printf("Sent probe transaction %s on %s", result.TxHash, network)
+
+	waitCtx, cancel := context.WithTimeout(ctx, cfg.Timeout)
+	defer cancel()
+	ticker := time.NewTicker(cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		receipt, err := client.TransactionReceipt(waitCtx, tx.Hash())
+		if err == nil {
+			result.InclusionTime = time.Since(result.SentAt)
+			result.Status = receipt.Status
+			result.BlockNumber = receipt.BlockNumber.Uint64()
+			return result, nil
+		}
+		if !errors.Is(err, ethereum.NotFound) {
+			log.Printf("Error getting probe receipt %s on %s: %s", result.TxHash, network, err)
+		}
+
+		select {
+		case <-waitCtx.Done():
+			result.InclusionTime = time.Since(result.SentAt)
+			result.Error = fmt.Sprintf("not included after %s", cfg.Timeout)
+			return result, nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// PruneProbeResults deletes the probe results of a network sent before cutoff.
+func PruneProbeResults(db *sqlx.DB, network string, cutoff time.Time) error {
+	_, err := db.Exec("DELETE FROM probe_result WHERE network = $1 AND sent_at < $2", network, cutoff)
+	if err != nil {
+		log.Printf("Error pruning probe results for %s: %s", network, err)
+		return err
+	}
+	return nil
+}
+
+// Warning describes why a probe result needs attention, or returns "" if the
+// transaction succeeded within the inclusion threshold.
+func (r ProbeResult) Warning(cfg ProbeConfig) string {
+	switch {
+	case r.Error != "":
+		return fmt.Sprintf("Probe transaction %s on %s failed: %s.", r.TxHash, r.Network, r.Error)
+	case r.Status != types.ReceiptStatusSuccessful:
+		return fmt.Sprintf("Probe transaction %s on %s was included in block %d but reverted.", r.TxHash, r.Network, r.BlockNumber)
+	case r.InclusionTime > cfg.MaxInclusion:
+		return fmt.Sprintf("Probe transaction %s on %s took %s to be included, threshold is %s.",
+			r.TxHash, r.Network, r.InclusionTime.Round(time.Second), cfg.MaxInclusion)
+	}
+	return ""
+}
+
+func RecordProbeResult(db *sqlx.DB, result ProbeResult) error {
+	_, err := db.Exec(`
+		INSERT INTO probe_result (network, tx_hash, status, block_number, inclusion_ms, sent_at, error)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, result.Network, result.TxHash, result.Status, result.BlockNumber, result.InclusionTime.Milliseconds(), result.SentAt, result.Error)
+	if err != nil {
+		log.Printf("Error recording probe result for %s: %s", result.Network, err)
+		return err
+	}
+	return nil
+}
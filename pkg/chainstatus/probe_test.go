@@ -0,0 +1,175 @@
+package chainstatus
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+	"github.com/jmoiron/sqlx"
+)
+
+func TestRunProbe(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	backend := simulated.NewBackend(types.GenesisAlloc{
+		crypto.PubkeyToAddress(key.PublicKey): {Balance: big.NewInt(1e18)},
+	})
+	defer backend.Close()
+
+	// The simulated chain only mines when told to, so commit blocks until the probe returns.
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(50 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				backend.Commit()
+			}
+		}
+	}()
+
+	cfg := ProbeConfig{Timeout: 10 * time.Second, MaxInclusion: 5 * time.Second, PollInterval: 20 * time.Millisecond}
+	result, err := RunProbe(context.Background(), "simulated", backend.Client(), key, cfg)
+	if err != nil {
+		t.Fatalf("RunProbe() returned error: %v", err)
+	}
+
+	if result.Error != "" || result.Status != types.ReceiptStatusSuccessful || result.BlockNumber == 0 {
+		t.Errorf("Unexpected probe result: %+v", result)
+	}
+	if warning := result.Warning(cfg); warning != "" {
+		t.Errorf("Unexpected warning: %q", warning)
+	}
+}
+
+func TestRunProbeNotIncluded(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	backend := simulated.NewBackend(types.GenesisAlloc{
+		crypto.PubkeyToAddress(key.PublicKey): {Balance: big.NewInt(1e18)},
+	})
+	defer backend.Close()
+
+	cfg := ProbeConfig{Timeout: 200 * time.Millisecond, MaxInclusion: time.Second, PollInterval: 20 * time.Millisecond}
+	result, err := RunProbe(context.Background(), "simulated", backend.Client(), key, cfg)
+	if err != nil {
+		t.Fatalf("RunProbe() returned error: %v", err)
+	}
+
+	if result.Error == "" {
+		t.Fatalf("Expected a timeout, got %+v", result)
+	}
+	if warning := result.Warning(cfg); !strings.Contains(warning, "not included") {
+		t.Errorf("Unexpected warning: %q", warning)
+	}
+}
+
+func TestProbeResultWarning(t *testing.T) {
+	cfg := ProbeConfig{MaxInclusion: time.Minute}
+
+	slow := ProbeResult{Network: "swan", TxHash: "0x1", Status: types.ReceiptStatusSuccessful, InclusionTime: 2 * time.Minute}
+	if warning := slow.Warning(cfg); !strings.Contains(warning, "took 2m0s") {
+		t.Errorf("Unexpected warning for slow inclusion: %q", warning)
+	}
+
+	reverted := ProbeResult{Network: "swan", TxHash: "0x1", Status: types.ReceiptStatusFailed, BlockNumber: 7}
+	if warning := reverted.Warning(cfg); !strings.Contains(warning, "reverted") {
+		t.Errorf("Unexpected warning for reverted probe: %q", warning)
+	}
+}
+
+func TestGetProbeConfig(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	// Rows other than the durations, such as the probe's address, are ignored.
+	mock.ExpectQuery("SELECT key, value FROM info WHERE is_active = true AND type = 'probe'").
+		WillReturnRows(sqlmock.NewRows([]string{"key", "value"}).
+			AddRow("timeout-seconds", "120").
+			AddRow("max-inclusion-seconds", "30").
+			AddRow("retention-days", "7").
+			AddRow("sepolia-address", "0x71C7656EC7ab88b098defB751B7401B5f6d8976F"))
+	cfg, err := GetProbeConfig(sqlxDB)
+	if err != nil || cfg.Timeout != 2*time.Minute || cfg.MaxInclusion != 30*time.Second || cfg.Retention != 7*24*time.Hour {
+		t.Errorf("GetProbeConfig() = %+v, %v", cfg, err)
+	}
+
+	for _, value := range []string{"soon", "0"} {
+		mock.ExpectQuery("SELECT key, value FROM info").
+			WillReturnRows(sqlmock.NewRows([]string{"key", "value"}).AddRow("timeout-seconds", value))
+		if _, err := GetProbeConfig(sqlxDB); err == nil {
+			t.Errorf("GetProbeConfig() accepted timeout-seconds %q", value)
+		}
+	}
+}
+
+func TestPruneProbeResults(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	cutoff := time.Now().Add(-30 * 24 * time.Hour)
+	mock.ExpectExec("DELETE FROM probe_result WHERE network = \\$1 AND sent_at < \\$2").
+		WithArgs("swan", cutoff).WillReturnResult(sqlmock.NewResult(0, 4))
+	if err := PruneProbeResults(sqlx.NewDb(db, "sqlmock"), "swan", cutoff); err != nil {
+		t.Errorf("PruneProbeResults() returned error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+// hangingClient is a node that never answers.
+type hangingClient struct {
+	ProbeClient
+}
+
+func (hangingClient) ChainID(ctx context.Context) (*big.Int, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestRunProbeSendTimeout(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey() returned error: %v", err)
+	}
+
+	cfg := ProbeConfig{Timeout: 100 * time.Millisecond, MaxInclusion: time.Minute, PollInterval: 10 * time.Millisecond}
+	done := make(chan error, 1)
+	go func() {
+		_, err := RunProbe(context.Background(), "hanging", hangingClient{}, key, cfg)
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err == nil || !strings.Contains(err.Error(), "deadline exceeded") {
+			t.Errorf("RunProbe() = %v, want a timeout", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("RunProbe() did not time out")
+	}
+}
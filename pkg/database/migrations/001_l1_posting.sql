@@ -0,0 +1,19 @@
+SET search_path TO swan_tool;
+
+-- Last batch and output root seen on L1, one row per posting kind and contract.
+CREATE TABLE IF NOT EXISTS l1_posting (
+    kind         VARCHAR(16)  NOT NULL,
+    address      VARCHAR(42)  NOT NULL,
+    block_number BIGINT       NOT NULL,
+    tx_hash      VARCHAR(66)  NOT NULL,
+    posted_at    TIMESTAMPTZ  NOT NULL,
+    checked_at   TIMESTAMPTZ  NOT NULL,
+    PRIMARY KEY (kind, address)
+);
+
+-- Example configuration rows:
+-- INSERT INTO info (key, value, type, is_active) VALUES
+--     ('batch-inbox', '0x...', 'l1-posting', true),
+--     ('l2-output-oracle', '0x...', 'l1-posting', true),
+--     ('batch-max-age-minutes', '30', 'l1-posting', true),
+--     ('output-max-age-minutes', '90', 'l1-posting', true);
@@ -0,0 +1,20 @@
+SET search_path TO swan_tool;
+
+-- Fee market readings taken by the gas checker, in gwei.
+CREATE TABLE IF NOT EXISTS gas_sample (
+    id                SERIAL PRIMARY KEY,
+    network           VARCHAR(32)      NOT NULL,
+    gas_price_gwei    DOUBLE PRECISION NOT NULL,
+    priority_fee_gwei DOUBLE PRECISION NOT NULL,
+    base_fee_gwei     DOUBLE PRECISION NOT NULL,
+    sampled_at        TIMESTAMPTZ      NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS gas_sample_network_sampled_at_idx ON gas_sample (network, sampled_at);
+
+-- Example configuration rows:
+-- INSERT INTO info (key, value, type, is_active) VALUES
+--     ('sepolia-ceiling-gwei', '50', 'gas', true),
+--     ('swan-ceiling-gwei', '5', 'gas', true),
+--     ('sustain-minutes', '15', 'gas', true),
+--     ('retention-days', '30', 'gas', true);
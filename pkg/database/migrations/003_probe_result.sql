@@ -0,0 +1,20 @@
+SET search_path TO swan_tool;
+
+-- Outcome of each synthetic self-transfer sent by the probe.
+CREATE TABLE IF NOT EXISTS probe_result (
+    id           SERIAL PRIMARY KEY,
+    network      VARCHAR(32)  NOT NULL,
+    tx_hash      VARCHAR(66)  NOT NULL,
+    status       SMALLINT     NOT NULL,
+    block_number BIGINT       NOT NULL,
+    inclusion_ms BIGINT       NOT NULL,
+    sent_at      TIMESTAMPTZ  NOT NULL,
+    error        TEXT         NOT NULL DEFAULT ''
+);
+
+-- Example configuration rows. The probe keys themselves are read from the
+-- SEPOLIA_PROBE_KEY and SWAN_PROBE_KEY environment variables.
+-- INSERT INTO info (key, value, type, is_active) VALUES
+--     ('timeout-seconds', '300', 'probe', true),
+--     ('max-inclusion-seconds', '60', 'probe', true),
+--     ('retention-days', '30', 'probe', true);
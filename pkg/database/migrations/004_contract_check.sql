@@ -0,0 +1,25 @@
+SET search_path TO swan_tool;
+
+-- eth_call health checks run every cycle. See chainstatus.ContractCheck for
+-- the meaning of comparison, expected and word_index.
+CREATE TABLE IF NOT EXISTS contract_check (
+    id              SERIAL PRIMARY KEY,
+    name            VARCHAR(64)  NOT NULL,
+    network         VARCHAR(32)  NOT NULL,
+    address         VARCHAR(42)  NOT NULL,
+    call_data       TEXT         NOT NULL,
+    comparison      VARCHAR(16)  NOT NULL,
+    expected        TEXT         NOT NULL DEFAULT '',
+    word_index      INTEGER      NOT NULL DEFAULT 0,
+    max_age_seconds INTEGER      NOT NULL DEFAULT 0,
+    is_active       BOOLEAN      NOT NULL DEFAULT true,
+    last_result     TEXT,
+    last_error      TEXT,
+    last_checked_at TIMESTAMPTZ
+);
+
+-- Examples:
+-- INSERT INTO contract_check (name, network, address, call_data, comparison, expected) VALUES
+--     ('bridge-not-paused', 'sepolia', '0x...', '0x5c975abb', 'eq', '0');  -- paused()
+-- INSERT INTO contract_check (name, network, address, call_data, comparison, word_index, max_age_seconds) VALUES
+--     ('oracle-fresh', 'swan', '0x...', '0xfeaf968c', 'fresh', 3, 3600);   -- latestRoundData().updatedAt
@@ -0,0 +1,18 @@
+SET search_path TO swan_tool;
+
+-- Latest OCSP status per domain backend, from the stapled response and from
+-- each responder listed in the leaf certificate.
+CREATE TABLE IF NOT EXISTS ocsp_status (
+    domain      TEXT         NOT NULL,
+    address     TEXT         NOT NULL,
+    source      VARCHAR(16)  NOT NULL,
+    responder   TEXT         NOT NULL DEFAULT '',
+    status      VARCHAR(16)  NOT NULL DEFAULT '',
+    revoked_at  TIMESTAMPTZ,
+    produced_at TIMESTAMPTZ,
+    this_update TIMESTAMPTZ,
+    next_update TIMESTAMPTZ,
+    error       TEXT         NOT NULL DEFAULT '',
+    checked_at  TIMESTAMPTZ  NOT NULL,
+    PRIMARY KEY (domain, address, source, responder)
+);
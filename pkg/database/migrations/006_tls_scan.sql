@@ -0,0 +1,25 @@
+SET search_path TO swan_tool;
+
+-- History of TLS configuration scans per domain backend. The latest row is
+-- compared against each new scan to detect regressions.
+CREATE TABLE IF NOT EXISTS tls_scan (
+    id                  SERIAL       PRIMARY KEY,
+    domain              TEXT         NOT NULL,
+    address             TEXT         NOT NULL,
+    grade               VARCHAR(2)   NOT NULL,
+    versions            TEXT         NOT NULL DEFAULT '',
+    cipher_suites       TEXT         NOT NULL DEFAULT '',
+    groups              TEXT         NOT NULL DEFAULT '',
+    key_type            VARCHAR(16)  NOT NULL DEFAULT '',
+    key_bits            INTEGER      NOT NULL DEFAULT 0,
+    signature_algorithm VARCHAR(32)  NOT NULL DEFAULT '',
+    findings            JSONB        NOT NULL DEFAULT '[]',
+    scanned_at          TIMESTAMPTZ  NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS tls_scan_domain_address_scanned_at
+    ON tls_scan (domain, address, scanned_at DESC);
+
+-- Scans are kept for 90 days unless configured otherwise:
+-- INSERT INTO info (key, value, type, is_active) VALUES
+--     ('retention-days', '90', 'ssl', true);
@@ -0,0 +1,28 @@
+SET search_path TO swan_tool;
+
+-- Leaf certificate fingerprints per domain backend, one row per run. The
+-- latest row is compared against each new run to detect rotations and
+-- unexpected changes.
+CREATE TABLE IF NOT EXISTS cert_fingerprint (
+    id          SERIAL       PRIMARY KEY,
+    domain      TEXT         NOT NULL,
+    address     TEXT         NOT NULL,
+    fingerprint CHAR(64)     NOT NULL,
+    spki_hash   VARCHAR(44)  NOT NULL,
+    subject     TEXT         NOT NULL DEFAULT '',
+    issuer      TEXT         NOT NULL DEFAULT '',
+    not_after   TIMESTAMPTZ  NOT NULL,
+    checked_at  TIMESTAMPTZ  NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS cert_fingerprint_domain_address_checked_at
+    ON cert_fingerprint (domain, address, checked_at DESC);
+
+-- Pins are optional. The key is the domain as configured in the 'domain'
+-- rows; the value lists hex SHA-256 fingerprints or base64 SPKI hashes of
+-- any certificate in the chain:
+-- INSERT INTO info (key, value, type, is_active) VALUES
+--     ('https://swanchain.io', 'kIdp6NNEd8wsugYyyIYFsi1ylMCED3hZbSR8ZFsa/A4=', 'ssl-pin', true);
+
+-- Fingerprints are kept as long as TLS scans, see retention-days in
+-- 006_tls_scan.sql.
@@ -0,0 +1,15 @@
+SET search_path TO swan_tool;
+
+-- Latest registration data per registrable domain, from RDAP or WHOIS.
+CREATE TABLE IF NOT EXISTS domain_registration (
+    domain     TEXT         PRIMARY KEY,
+    registrar  TEXT         NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ,
+    expires_at TIMESTAMPTZ  NOT NULL,
+    source     VARCHAR(8)   NOT NULL,
+    checked_at TIMESTAMPTZ  NOT NULL
+);
+
+-- Example configuration (defaults to 60,30,14,7):
+-- INSERT INTO info (key, value, type, is_active) VALUES
+--     ('warning-days', '90,60,30,14,7', 'domain-expiry', true);
@@ -0,0 +1,19 @@
+SET search_path TO swan_tool;
+
+-- Expected DNS answer set per name and record type. Answers are sorted and
+-- newline separated; an empty string means the name has no such records.
+CREATE TABLE IF NOT EXISTS dns_record (
+    name        TEXT         NOT NULL,
+    record_type VARCHAR(8)   NOT NULL,
+    answers     TEXT         NOT NULL,
+    changed_at  TIMESTAMPTZ  NOT NULL,
+    checked_at  TIMESTAMPTZ  NOT NULL,
+    PRIMARY KEY (name, record_type)
+);
+
+-- Example configuration. Every host in the domain list is checked for the
+-- default record types; 'dns-record' rows add names or override their types.
+-- INSERT INTO info (key, value, type, is_active) VALUES
+--     ('resolvers', '1.1.1.1, 8.8.8.8, 9.9.9.9', 'dns', true),
+--     ('record-types', 'A,AAAA,CNAME,MX,TXT,NS,CAA', 'dns', true),
+--     ('swanchain.io', 'MX,TXT,CAA', 'dns-record', true);
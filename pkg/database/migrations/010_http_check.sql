@@ -0,0 +1,29 @@
+SET search_path TO swan_tool;
+
+-- HTTP(S) checks run every minute. Every http(s) URL in the domain list is
+-- checked with the column defaults and gets a row on its first run; edit the
+-- row to tighten the check. See domaincheck.HTTPCheck.
+CREATE TABLE IF NOT EXISTS http_check (
+    id                   SERIAL PRIMARY KEY,
+    url                  TEXT         NOT NULL UNIQUE,
+    expected_status      INTEGER      NOT NULL DEFAULT 0,
+    max_response_ms      INTEGER      NOT NULL DEFAULT 10000,
+    body_contains        TEXT         NOT NULL DEFAULT '',
+    body_regex           TEXT         NOT NULL DEFAULT '',
+    json_path            TEXT         NOT NULL DEFAULT '',
+    json_expected        TEXT         NOT NULL DEFAULT '',
+    max_redirects        INTEGER      NOT NULL DEFAULT 5,
+    failure_threshold    INTEGER      NOT NULL DEFAULT 3,
+    is_active            BOOLEAN      NOT NULL DEFAULT true,
+    consecutive_failures INTEGER      NOT NULL DEFAULT 0,
+    last_status          INTEGER,
+    last_response_ms     INTEGER,
+    last_error           TEXT,
+    last_checked_at      TIMESTAMPTZ
+);
+
+-- Examples:
+-- INSERT INTO http_check (url, json_path, json_expected) VALUES
+--     ('https://api.swanchain.io/health', 'status', 'ok');
+-- INSERT INTO http_check (url, body_contains, max_response_ms) VALUES
+--     ('https://swanchain.io/', 'Swan Chain', 3000);
@@ -0,0 +1,8 @@
+SET search_path TO swan_tool;
+
+-- Latest security header findings per site, diffed against each new audit.
+CREATE TABLE IF NOT EXISTS header_audit (
+    url        TEXT         PRIMARY KEY,
+    findings   JSONB        NOT NULL DEFAULT '[]',
+    checked_at TIMESTAMPTZ  NOT NULL
+);
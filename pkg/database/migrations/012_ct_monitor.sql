@@ -0,0 +1,30 @@
+SET search_path TO swan_tool;
+
+-- Next entry index per CT log, or the last crt.sh ID per domain (source
+-- 'crtsh:<domain>'). A source seen for the first time starts at the current
+-- tree head so history is not replayed.
+CREATE TABLE IF NOT EXISTS ct_log_state (
+    source     TEXT         PRIMARY KEY,
+    position   BIGINT       NOT NULL,
+    checked_at TIMESTAMPTZ  NOT NULL
+);
+
+-- Certificates and precertificates logged for our domains.
+CREATE TABLE IF NOT EXISTS ct_certificate (
+    issuer        TEXT         NOT NULL,
+    serial_number TEXT         NOT NULL,
+    names         TEXT         NOT NULL,
+    source        TEXT         NOT NULL,
+    log_index     BIGINT       NOT NULL,
+    not_before    TIMESTAMPTZ,
+    not_after     TIMESTAMPTZ,
+    seen_at       TIMESTAMPTZ  NOT NULL,
+    PRIMARY KEY (issuer, serial_number)
+);
+
+-- Example configuration:
+-- INSERT INTO info (key, value, type, is_active) VALUES
+--     ('logs', 'https://ct.googleapis.com/logs/us1/argon2026h2', 'ct', true),
+--     ('crtsh', 'https://crt.sh', 'ct', true),
+--     ('issuers', 'Let''s Encrypt, Google Trust Services', 'ct', true),
+--     ('max-lag', '100000', 'ct', true);
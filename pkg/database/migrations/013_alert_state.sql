@@ -0,0 +1,24 @@
+SET search_path TO swan_tool;
+
+-- Persisted alert state per check and key. See alert.Manager for the
+-- ok/firing/resolved transitions.
+CREATE TABLE IF NOT EXISTS alert_state (
+    fingerprint   CHAR(32)     PRIMARY KEY,
+    check_name    VARCHAR(64)  NOT NULL,
+    alert_key     TEXT         NOT NULL,
+    title         TEXT         NOT NULL,
+    state         VARCHAR(16)  NOT NULL,
+    message       TEXT         NOT NULL,
+    first_seen    TIMESTAMPTZ  NOT NULL,
+    last_seen     TIMESTAMPTZ  NOT NULL,
+    last_notified TIMESTAMPTZ  NOT NULL,
+    resolved_at   TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS alert_state_check_name ON alert_state (check_name);
+
+-- Example configuration (repeats default to 60 minutes, 0 never repeats;
+-- states that stay ok, such as events, are deleted after 30 days):
+-- INSERT INTO info (key, value, type, is_active) VALUES
+--     ('repeat-minutes', '30', 'alert', true),
+--     ('retention-days', '30', 'alert', true);
@@ -0,0 +1,15 @@
+SET search_path TO swan_tool;
+
+-- Consecutive failures and recent state changes per alert. See alert.Rule
+-- for when a pending alert starts firing and alert.Config for flapping.
+ALTER TABLE alert_state ADD COLUMN IF NOT EXISTS failures    TEXT    NOT NULL DEFAULT '[]';
+ALTER TABLE alert_state ADD COLUMN IF NOT EXISTS transitions TEXT    NOT NULL DEFAULT '[]';
+ALTER TABLE alert_state ADD COLUMN IF NOT EXISTS flapping    BOOLEAN NOT NULL DEFAULT false;
+
+-- Example configuration. Rules are "N" or "N/M" for N consecutive failures
+-- within M minutes; chain-status defaults to 3/5 and other checks to 1.
+-- INSERT INTO info (key, value, type, is_active) VALUES
+--     ('flap-window-minutes', '60', 'alert', true),
+--     ('flap-threshold', '4', 'alert', true),
+--     ('chain-status', '3/5', 'alert-rule', true),
+--     ('probe', '2', 'alert-rule', true);
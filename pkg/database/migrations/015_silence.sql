@@ -0,0 +1,23 @@
+SET search_path TO swan_tool;
+
+-- Silences suppress notifications for matching checks, networks, domains or
+-- wallets between starts_at and ends_at. Manage them with cmd/alertctl.
+CREATE TABLE IF NOT EXISTS silence (
+    id         SERIAL       PRIMARY KEY,
+    check_name VARCHAR(64)  NOT NULL DEFAULT '',
+    network    VARCHAR(64)  NOT NULL DEFAULT '',
+    domain     TEXT         NOT NULL DEFAULT '',
+    wallet     VARCHAR(64)  NOT NULL DEFAULT '',
+    starts_at  TIMESTAMPTZ  NOT NULL,
+    ends_at    TIMESTAMPTZ  NOT NULL,
+    author     TEXT         NOT NULL,
+    reason     TEXT         NOT NULL,
+    created_at TIMESTAMPTZ  NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS silence_ends_at ON silence (ends_at);
+
+-- What each alert is about, so silences apply to it.
+ALTER TABLE alert_state ADD COLUMN IF NOT EXISTS network VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE alert_state ADD COLUMN IF NOT EXISTS domain  TEXT        NOT NULL DEFAULT '';
+ALTER TABLE alert_state ADD COLUMN IF NOT EXISTS wallet  VARCHAR(64) NOT NULL DEFAULT '';
@@ -0,0 +1,49 @@
+SET search_path TO swan_tool;
+
+-- Routes send alerts matching all of their non-empty columns to an
+-- escalation policy, trying routes by priority. Every check reports through
+-- the routes; alerts no route matches go to the default Teams webhook and
+-- Slack channel.
+CREATE TABLE IF NOT EXISTS alert_route (
+    id         SERIAL       PRIMARY KEY,
+    priority   INTEGER      NOT NULL DEFAULT 100,
+    check_name VARCHAR(64)  NOT NULL DEFAULT '',
+    severity   VARCHAR(16)  NOT NULL DEFAULT '',
+    network    VARCHAR(64)  NOT NULL DEFAULT '',
+    domain     TEXT         NOT NULL DEFAULT '',
+    wallet     VARCHAR(64)  NOT NULL DEFAULT '',
+    policy     VARCHAR(64)  NOT NULL,
+    is_active  BOOLEAN      NOT NULL DEFAULT true
+);
+
+-- The steps of each escalation policy. A step's targets are notified once an
+-- alert has fired delay_minutes ago without being acknowledged; the first
+-- step is notified when it fires. channel is teams, discord or webhook
+-- (target is a webhook URL), email (an address), slack or telegram (a channel
+-- or chat id), pagerduty (a routing key) or opsgenie (an API key); an empty
+-- target is the channel's default from the info table, or every email
+-- recipient.
+CREATE TABLE IF NOT EXISTS escalation_step (
+    id            SERIAL       PRIMARY KEY,
+    policy        VARCHAR(64)  NOT NULL,
+    delay_minutes INTEGER      NOT NULL DEFAULT 0,
+    channel       VARCHAR(16)  NOT NULL,
+    target        TEXT         NOT NULL DEFAULT ''
+);
+
+ALTER TABLE alert_state ADD COLUMN IF NOT EXISTS severity   VARCHAR(16) NOT NULL DEFAULT 'warning';
+ALTER TABLE alert_state ADD COLUMN IF NOT EXISTS fired_at   TIMESTAMPTZ;
+UPDATE alert_state SET fired_at = first_seen WHERE fired_at IS NULL;
+ALTER TABLE alert_state ALTER COLUMN fired_at SET NOT NULL;
+ALTER TABLE alert_state ADD COLUMN IF NOT EXISTS escalation INTEGER     NOT NULL DEFAULT 1;
+ALTER TABLE alert_state ADD COLUMN IF NOT EXISTS acked_at   TIMESTAMPTZ;
+ALTER TABLE alert_state ADD COLUMN IF NOT EXISTS acked_by   TEXT        NOT NULL DEFAULT '';
+
+-- Example: critical Swan alerts go to Teams, then on-call email after 15
+-- minutes, then the engineering lead after an hour.
+-- INSERT INTO alert_route (priority, severity, network, policy) VALUES
+--     (10, 'critical', 'swan', 'swan-critical');
+-- INSERT INTO escalation_step (policy, delay_minutes, channel, target) VALUES
+--     ('swan-critical', 0, 'teams', ''),
+--     ('swan-critical', 15, 'email', 'oncall@swanchain.io'),
+--     ('swan-critical', 60, 'email', 'lead@swanchain.io');
@@ -0,0 +1,21 @@
+SET search_path TO swan_tool;
+
+-- The Slack message each alert last fired with per channel, so follow-ups
+-- and the recovery are threaded under it.
+CREATE TABLE IF NOT EXISTS slack_thread (
+    fingerprint CHAR(32)     NOT NULL,
+    channel     VARCHAR(128) NOT NULL,
+    ts          VARCHAR(32)  NOT NULL,
+    posted_at   TIMESTAMPTZ  NOT NULL,
+    PRIMARY KEY (fingerprint, channel)
+);
+
+-- Example configuration. Alerts routed to the slack channel without a target,
+-- including those no route matches, go to the channel when a token is set,
+-- otherwise to the webhook. The token may instead be set in SLACK_BOT_TOKEN.
+-- INSERT INTO info (key, value, type, is_active) VALUES
+--     ('webhook', 'https://hooks.slack.com/services/T000/B000/XXXX', 'slack', true),
+--     ('token', 'xoxb-...', 'slack', true),
+--     ('channel', 'C0123456789', 'slack', true);
+-- INSERT INTO escalation_step (policy, delay_minutes, channel, target) VALUES
+--     ('swan-critical', 0, 'slack', 'C0123456789');
@@ -0,0 +1,21 @@
+SET search_path TO swan_tool;
+
+-- Discord and Telegram need no tables: escalation steps with channel discord
+-- (target is a webhook URL) or telegram (target is a chat ID or
+-- @channelusername) post to them, and an empty target uses the rows below.
+
+-- Example configuration. The Telegram bot token may instead be set in
+-- TELEGRAM_BOT_TOKEN; the bot must be an administrator of the channel.
+-- INSERT INTO info (key, value, type, is_active) VALUES
+--     ('webhook', 'https://discord.com/api/webhooks/000/XXXX', 'discord', true),
+--     ('token', '123456:ABC-...', 'telegram', true),
+--     ('chat-id', '@swanchain_status', 'telegram', true);
+
+-- Example: announce chain status incidents on the public Discord and
+-- Telegram channels as well as to the team.
+-- INSERT INTO alert_route (priority, check_name, policy) VALUES
+--     (5, 'chain-status', 'chain-status-public');
+-- INSERT INTO escalation_step (policy, delay_minutes, channel, target) VALUES
+--     ('chain-status-public', 0, 'teams', ''),
+--     ('chain-status-public', 0, 'discord', ''),
+--     ('chain-status-public', 0, 'telegram', '');
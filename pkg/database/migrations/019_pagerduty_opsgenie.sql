@@ -0,0 +1,20 @@
+SET search_path TO swan_tool;
+
+-- PagerDuty and Opsgenie need no tables: escalation steps with channel
+-- pagerduty (target is an Events API v2 routing key) or opsgenie (target is
+-- an API integration key) trigger, acknowledge and resolve an incident per
+-- alert, and an empty target uses the rows below. Acknowledging an alert
+-- with alertctl acknowledges its incident on the next run of its check.
+
+-- Example configuration. The keys may instead be set in
+-- PAGERDUTY_ROUTING_KEY and OPSGENIE_API_KEY; api-url is only needed for
+-- Opsgenie accounts in the EU region.
+-- INSERT INTO info (key, value, type, is_active) VALUES
+--     ('routing-key', 'R0123456789ABCDEF0123456789ABCDE', 'pagerduty', true),
+--     ('api-key', '00000000-0000-0000-0000-000000000000', 'opsgenie', true),
+--     ('api-url', 'https://api.eu.opsgenie.com', 'opsgenie', true);
+
+-- Example: page the on-call engineer when a critical Swan alert has not been
+-- acknowledged within 15 minutes.
+-- INSERT INTO escalation_step (policy, delay_minutes, channel, target) VALUES
+--     ('swan-critical', 15, 'pagerduty', '');
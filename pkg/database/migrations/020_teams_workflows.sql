@@ -0,0 +1,13 @@
+SET search_path TO swan_tool;
+
+-- Teams messages are now Adaptive Cards, which Office 365 connector URLs no
+-- longer accept once connectors are retired. Point teams-webhook, and teams
+-- escalation step targets, at a Power Automate Workflows webhook ("Post to a
+-- channel when a webhook request is received").
+-- UPDATE info SET value = 'https://prod-00.westus.logic.azure.com/workflows/.../triggers/manual/paths/invoke?...'
+--     WHERE key = 'teams-webhook';
+
+-- Cards link to the block explorer of their network when it is configured.
+-- INSERT INTO info (key, value, type, is_active) VALUES
+--     ('saturn-block-explorer', 'https://swanscan.io', 'explorer', true),
+--     ('sepolia-block-explorer', 'https://sepolia.etherscan.io', 'explorer', true);
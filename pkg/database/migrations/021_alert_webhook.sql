@@ -0,0 +1,19 @@
+SET search_path TO swan_tool;
+
+-- Generic webhooks need no tables: escalation steps with channel webhook
+-- (target is a URL) POST the versioned JSON alert payload to it, and an
+-- empty target uses the url row below. Requests carry the headers
+-- X-Domain-Check-Timestamp (Unix seconds), X-Domain-Check-Event (the
+-- notification kind) and X-Domain-Check-Signature:
+-- "sha256=" and the hex HMAC-SHA256 of the timestamp, "." and the body.
+-- Server errors and failed connections are retried.
+
+-- Example configuration. The secret is required and may instead be set in
+-- WEBHOOK_SECRET.
+-- INSERT INTO info (key, value, type, is_active) VALUES
+--     ('url', 'https://automation.swanchain.io/alerts', 'webhook', true),
+--     ('secret', 'change-me', 'webhook', true),
+--     ('timeout-seconds', '10', 'webhook', true),
+--     ('retries', '3', 'webhook', true);
+-- INSERT INTO escalation_step (policy, delay_minutes, channel, target) VALUES
+--     ('swan-critical', 0, 'webhook', '');
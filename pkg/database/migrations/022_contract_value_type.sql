@@ -0,0 +1,7 @@
+SET search_path TO swan_tool;
+
+-- How a contract check reads its word: uint256 or int256 (two's complement).
+ALTER TABLE contract_check ADD COLUMN IF NOT EXISTS value_type VARCHAR(8) NOT NULL DEFAULT 'uint256';
+
+-- Example:
+-- UPDATE contract_check SET value_type = 'int256' WHERE name = 'oracle-positive';
@@ -0,0 +1,7 @@
+SET search_path TO swan_tool;
+
+-- The issuing organization and root of each recorded leaf. A new certificate
+-- is only an unexpected change when one of these differs from the previous
+-- run (or it misses its pins); a new intermediate of the same CA is not.
+ALTER TABLE cert_fingerprint ADD COLUMN IF NOT EXISTS issuer_org TEXT NOT NULL DEFAULT '';
+ALTER TABLE cert_fingerprint ADD COLUMN IF NOT EXISTS root       TEXT NOT NULL DEFAULT '';
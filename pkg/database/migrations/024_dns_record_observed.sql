@@ -0,0 +1,9 @@
+SET search_path TO swan_tool;
+
+-- answers is now the accepted answer set and is no longer overwritten by each
+-- run; observed holds the latest answers. A difference between the two is
+-- reported on every run until it is accepted with
+-- `alertctl dns accept NAME TYPE`, which copies observed into answers.
+ALTER TABLE dns_record ADD COLUMN IF NOT EXISTS observed TEXT;
+UPDATE dns_record SET observed = answers WHERE observed IS NULL;
+ALTER TABLE dns_record ALTER COLUMN observed SET NOT NULL;
@@ -0,0 +1,18 @@
+SET search_path TO swan_tool;
+
+-- Wallet balance changes and SSL warnings used to be emailed to every
+-- recipient besides Teams and Slack. Route them to a policy that keeps doing
+-- so; the low priority lets more specific routes take precedence.
+INSERT INTO alert_route (priority, check_name, policy)
+SELECT 1000, c.check_name, c.check_name || '-default'
+FROM (VALUES ('wallet'), ('ssl')) AS c (check_name)
+WHERE NOT EXISTS (SELECT 1 FROM alert_route r WHERE r.check_name = c.check_name AND r.policy = c.check_name || '-default');
+
+INSERT INTO escalation_step (policy, delay_minutes, channel, target)
+SELECT p.policy, 0, s.channel, ''
+FROM (VALUES ('wallet-default'), ('ssl-default')) AS p (policy)
+CROSS JOIN (VALUES ('teams'), ('slack'), ('email')) AS s (channel)
+WHERE NOT EXISTS (SELECT 1 FROM escalation_step e WHERE e.policy = p.policy AND e.channel = s.channel);
+
+-- Example: stop emailing wallet balance changes.
+-- DELETE FROM escalation_step WHERE policy = 'wallet-default' AND channel = 'email';
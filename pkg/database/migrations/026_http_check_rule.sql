@@ -0,0 +1,10 @@
+SET search_path TO swan_tool;
+
+-- HTTP failures are counted by the alert manager like every other check; set
+-- an 'alert-rule' row for http to change how many it takes to fire.
+ALTER TABLE http_check DROP COLUMN IF EXISTS failure_threshold;
+ALTER TABLE http_check DROP COLUMN IF EXISTS consecutive_failures;
+
+-- Example: fire after 5 failures within 10 minutes.
+-- INSERT INTO info (key, value, type, is_active) VALUES
+--     ('http', '5/10', 'alert-rule', true);
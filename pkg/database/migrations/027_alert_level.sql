@@ -0,0 +1,6 @@
+SET search_path TO swan_tool;
+
+-- The level an alert was last reported at, e.g. how many SSL or registration
+-- warning windows its expiry has entered. Firing alerts are notified again
+-- when it rises as well as every repeat interval.
+ALTER TABLE alert_state ADD COLUMN IF NOT EXISTS level INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,5 @@
+SET search_path TO swan_tool;
+
+-- The table shown with an alert, e.g. the wallet balances, as a JSON array of
+-- {"title", "value"} objects.
+ALTER TABLE alert_state ADD COLUMN IF NOT EXISTS facts TEXT NOT NULL DEFAULT '[]';
@@ -0,0 +1,11 @@
+package database
+
+import "time"
+
+// NullTime returns nil for the zero time, so it is stored as NULL.
+func NullTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
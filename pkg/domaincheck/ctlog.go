@@ -0,0 +1,355 @@
+package domaincheck
+
+import (
+	"crypto/x509"
+	"database/sql"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/swanchain/domain-check/pkg/database"
+	"github.com/swanchain/domain-check/pkg/model"
+)
+
+// CTConfig is read from info rows of type 'ct'.
+type CTConfig struct {
+	Logs       []string
+	CrtSh      string
+	Issuers    []string
+	BatchSize  int
+	MaxEntries int
+	MaxLag     int
+}
+
+func GetCTConfig(db *sqlx.DB) (CTConfig, error) {
+	cfg := CTConfig{BatchSize: 256, MaxEntries: 10000, MaxLag: 100000}
+
+	var configs []model.Info
+	err := db.Select(&configs, "SELECT key, value FROM info WHERE is_active = true AND type = 'ct'")
+	if err != nil {
+		return cfg, err
+	}
+
+	for _, config := range configs {
+		switch config.Key {
+		case "logs":
+			cfg.Logs = nil
+			for _, logURL := range splitList(config.Value) {
+				cfg.Logs = append(cfg.Logs, strings.TrimSuffix(logURL, "/"))
+			}
+		case "crtsh":
+			cfg.CrtSh = strings.TrimSuffix(config.Value, "/")
+		case "issuers":
+			cfg.Issuers = splitList(config.Value)
+		case "batch-size", "max-entries", "max-lag":
+			n, err := strconv.Atoi(config.Value)
+			if err != nil || n <= 0 {
+				return cfg, fmt.Errorf("invalid ct %s %q", config.Key, config.Value)
+			}
+			switch config.Key {
+			case "batch-size":
+				cfg.BatchSize = n
+			case "max-entries":
+				cfg.MaxEntries = n
+			default:
+				cfg.MaxLag = n
+			}
+		}
+	}
+
+	return cfg, nil
+}
+
+// CTCertificate is a logged certificate or precertificate for one of our domains.
+type CTCertificate struct {
+	Source       string
+	Index        int64
+	Names        []string
+	Issuer       string
+	SerialNumber string
+	NotBefore    time.Time
+	NotAfter     time.Time
+	Precert      bool
+}
+
+// ExpectedIssuer reports whether the issuer contains one of issuers.
+func (c CTCertificate) ExpectedIssuer(issuers []string) bool {
+	issuer := strings.ToLower(c.Issuer)
+	for _, expected := range issuers {
+		if strings.Contains(issuer, strings.ToLower(expected)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Message describes the certificate for an alert.
+func (c CTCertificate) Message(issuers []string) string {
+	kind := "certificate"
+	if c.Precert {
+		kind = "precertificate"
+	}
+	issuer := "issuer"
+	if len(issuers) > 0 {
+		issuer = "unexpected issuer"
+	}
+	return fmt.Sprintf("A %s for %s from %s %s (serial %s, valid %s to %s) was logged in %s.",
+		kind, strings.Join(c.Names, ", "), issuer, c.Issuer, c.SerialNumber, c.NotBefore.Format("2006-01-02"), c.NotAfter.Format("2006-01-02"), c.Source)
+}
+
+// CTMonitor finds certificates for Domains and their subdomains.
+type CTMonitor struct {
+	Domains    []string
+	BatchSize  int
+	MaxEntries int
+	MaxLag     int
+	Client     *http.Client
+}
+
+func NewCTMonitor(cfg CTConfig, domains []string) *CTMonitor {
+	return &CTMonitor{
+		Domains:    domains,
+		BatchSize:  cfg.BatchSize,
+		MaxEntries: cfg.MaxEntries,
+		MaxLag:     cfg.MaxLag,
+		Client:     &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (m *CTMonitor) matches(name string) bool {
+	name = strings.ToLower(strings.TrimPrefix(name, "*."))
+	for _, domain := range m.Domains {
+		if name == domain || strings.HasSuffix(name, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *CTMonitor) getJSON(u string, v interface{}) error {
+	resp, err := m.Client.Get(u)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %s", u, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// TreeSize returns the size of the log's latest signed tree head.
+func (m *CTMonitor) TreeSize(logURL string) (int64, error) {
+	var sth struct {
+		TreeSize int64 `json:"tree_size"`
+	}
+	if err := m.getJSON(logURL+"/ct/v1/get-sth", &sth); err != nil {
+		return 0, err
+	}
+	return sth.TreeSize, nil
+}
+
+// PollLog returns the matching certificates logged from index from on and the
+// index to continue from, skipping to the tree head when more than MaxLag behind.
+func (m *CTMonitor) PollLog(logURL string, from int64) ([]CTCertificate, int64, error) {
+	treeSize, err := m.TreeSize(logURL)
+	if err != nil {
+		return nil, from, err
+	}
+	if m.MaxLag > 0 && treeSize-from > int64(m.MaxLag) {
+		log.Printf("%s is %d entries behind, skipping entries %d to %d", logURL, treeSize-from, from, treeSize-1)
+		return nil, treeSize, nil
+	}
+	end := min(treeSize, from+int64(m.MaxEntries))
+
+	var certs []CTCertificate
+	for from < end {
+		var response struct {
+			Entries []struct {
+				LeafInput []byte `json:"leaf_input"`
+				ExtraData []byte `json:"extra_data"`
+			} `json:"entries"`
+		}
+		last := min(end, from+int64(m.BatchSize)) - 1
+		err := m.getJSON(fmt.Sprintf("%s/ct/v1/get-entries?start=%d&end=%d", logURL, from, last), &response)
+		if err != nil {
+			return certs, from, err
+		}
+		if len(response.Entries) == 0 {
+			return certs, from, errors.New("log returned no entries")
+		}
+
+		for i, entry := range response.Entries {
+			cert, precert, err := parseLogEntry(entry.LeafInput, entry.ExtraData)
+			if err != nil {
+				log.Printf("Skipping entry %d of %s: %s", from+int64(i), logURL, err)
+				continue
+			}
+			if names := m.matchingNames(cert); len(names) > 0 {
+				certs = append(certs, CTCertificate{
+					Source:       logURL,
+					Index:        from + int64(i),
+					Names:        names,
+					Issuer:       cert.Issuer.String(),
+					SerialNumber: cert.SerialNumber.Text(16),
+					NotBefore:    cert.NotBefore,
+					NotAfter:     cert.NotAfter,
+					Precert:      precert,
+				})
+			}
+		}
+		from += int64(len(response.Entries))
+	}
+
+	return certs, from, nil
+}
+
+func (m *CTMonitor) matchingNames(cert *x509.Certificate) []string {
+	names := cert.DNSNames
+	if len(names) == 0 && cert.Subject.CommonName != "" {
+		names = []string{cert.Subject.CommonName}
+	}
+	for _, name := range names {
+		if m.matches(name) {
+			return names
+		}
+	}
+	return nil
+}
+
+// parseLogEntry decodes the certificate of an RFC 6962 entry.
+func parseLogEntry(leafInput, extraData []byte) (*x509.Certificate, bool, error) {
+	if len(leafInput) < 12 || leafInput[0] != 0 || leafInput[1] != 0 {
+		return nil, false, errors.New("unsupported leaf version or type")
+	}
+
+	var der []byte
+	var err error
+	precert := false
+	switch entryType := binary.BigEndian.Uint16(leafInput[10:12]); entryType {
+	case 0:
+		der, err = readASN1Cert(leafInput[12:])
+	case 1:
+		precert = true
+		der, err = readASN1Cert(extraData)
+	default:
+		return nil, false, fmt.Errorf("unknown entry type %d", entryType)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, false, err
+	}
+	return cert, precert, nil
+}
+
+// readASN1Cert reads a certificate prefixed with its 24-bit length.
+func readASN1Cert(data []byte) ([]byte, error) {
+	if len(data) < 3 {
+		return nil, errors.New("truncated certificate")
+	}
+	length := int(data[0])<<16 | int(data[1])<<8 | int(data[2])
+	if len(data) < 3+length {
+		return nil, errors.New("truncated certificate")
+	}
+	return data[3 : 3+length], nil
+}
+
+type crtShEntry struct {
+	ID           int64  `json:"id"`
+	IssuerName   string `json:"issuer_name"`
+	CommonName   string `json:"common_name"`
+	NameValue    string `json:"name_value"`
+	SerialNumber string `json:"serial_number"`
+	NotBefore    string `json:"not_before"`
+	NotAfter     string `json:"not_after"`
+}
+
+// PollCrtSh returns the certificates of domain on crt.sh with an ID after after.
+func (m *CTMonitor) PollCrtSh(baseURL, domain string, after int64) ([]CTCertificate, int64, error) {
+	var entries []crtShEntry
+	query := url.Values{"q": {"%." + domain}, "output": {"json"}, "exclude": {"expired"}}
+	if err := m.getJSON(baseURL+"/?"+query.Encode(), &entries); err != nil {
+		return nil, after, err
+	}
+
+	latest := after
+	var certs []CTCertificate
+	for _, entry := range entries {
+		if entry.ID <= after {
+			continue
+		}
+		latest = max(latest, entry.ID)
+
+		cert := CTCertificate{
+			Source:       baseURL,
+			Index:        entry.ID,
+			Names:        strings.Split(entry.NameValue, "\n"),
+			Issuer:       entry.IssuerName,
+			SerialNumber: entry.SerialNumber,
+		}
+		cert.NotBefore, _ = time.Parse("2006-01-02T15:04:05", entry.NotBefore)
+		cert.NotAfter, _ = time.Parse("2006-01-02T15:04:05", entry.NotAfter)
+		certs = append(certs, cert)
+	}
+	return certs, latest, nil
+}
+
+// GetCTPosition returns where polling of source left off.
+func GetCTPosition(db *sqlx.DB, source string) (int64, bool, error) {
+	var position int64
+	err := db.Get(&position, "SELECT position FROM ct_log_state WHERE source = $1", source)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil {
+		log.Printf("Error retrieving CT position for %s: %s", source, err)
+		return 0, false, err
+	}
+	return position, true, nil
+}
+
+func SetCTPosition(db *sqlx.DB, source string, position int64) error {
+	_, err := db.Exec(`
+		INSERT INTO ct_log_state (source, position, checked_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (source) DO UPDATE
+		SET position = EXCLUDED.position, checked_at = EXCLUDED.checked_at
+	`, source, position, time.Now())
+	if err != nil {
+		log.Printf("Error recording CT position for %s: %s", source, err)
+		return err
+	}
+	return nil
+}
+
+// RecordCTCertificate stores the certificate and reports whether its issuer
+// and serial are new.
+func RecordCTCertificate(db *sqlx.DB, cert CTCertificate) (bool, error) {
+	result, err := db.Exec(`
+		INSERT INTO ct_certificate (issuer, serial_number, names, source, log_index, not_before, not_after, seen_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (issuer, serial_number) DO NOTHING
+	`, cert.Issuer, strings.ToLower(cert.SerialNumber), strings.Join(cert.Names, ","), cert.Source, cert.Index,
+		database.NullTime(cert.NotBefore), database.NullTime(cert.NotAfter), time.Now())
+	if err != nil {
+		log.Printf("Error recording CT certificate %s: %s", cert.SerialNumber, err)
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
@@ -0,0 +1,203 @@
+package domaincheck
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+)
+
+var ctPoison = pkix.Extension{Id: asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 11129, 2, 4, 3}, Critical: true, Value: []byte{0x05, 0x00}}
+
+// newCTCert returns the DER of a certificate for names issued by a CA named
+// issuer. Precertificates carry the CT poison extension.
+func newCTCert(t *testing.T, issuer string, serial int64, precert bool, names ...string) []byte {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	ca := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{Organization: []string{issuer}, CommonName: issuer + " CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: names[0]},
+		DNSNames:     names,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+	}
+	if precert {
+		template.ExtraExtensions = []pkix.Extension{ctPoison}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, key.Public(), key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	return der
+}
+
+func asn1Cert(der []byte) []byte {
+	return append([]byte{byte(len(der) >> 16), byte(len(der) >> 8), byte(len(der))}, der...)
+}
+
+// ctEntry encodes der as an RFC 6962 get-entries entry.
+func ctEntry(der []byte, precert bool) map[string][]byte {
+	leaf := []byte{0, 0}
+	leaf = binary.BigEndian.AppendUint64(leaf, uint64(time.Now().UnixMilli()))
+	if !precert {
+		leaf = append(binary.BigEndian.AppendUint16(leaf, 0), asn1Cert(der)...)
+		return map[string][]byte{"leaf_input": append(leaf, 0, 0), "extra_data": {0, 0, 0}}
+	}
+	// The leaf holds the issuer key hash and TBSCertificate, which the
+	// monitor does not read; the precertificate itself is in extra_data.
+	leaf = binary.BigEndian.AppendUint16(leaf, 1)
+	leaf = append(leaf, make([]byte, 32)...)
+	leaf = append(leaf, asn1Cert([]byte{0x30, 0x00})...)
+	return map[string][]byte{"leaf_input": append(leaf, 0, 0), "extra_data": append(asn1Cert(der), 0, 0, 0)}
+}
+
+// newFakeLog serves entries over the RFC 6962 API, returning at most
+// pageSize entries per get-entries request like real logs do.
+func newFakeLog(t *testing.T, entries []map[string][]byte, pageSize int) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ct/v1/get-sth":
+			fmt.Fprintf(w, `{"tree_size": %d, "timestamp": %d}`, len(entries), time.Now().UnixMilli())
+		case "/ct/v1/get-entries":
+			start, _ := strconv.Atoi(r.URL.Query().Get("start"))
+			end, _ := strconv.Atoi(r.URL.Query().Get("end"))
+			end = min(end, start+pageSize-1, len(entries)-1)
+			json.NewEncoder(w).Encode(map[string]interface{}{"entries": entries[start : end+1]})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestPollLog(t *testing.T) {
+	entries := []map[string][]byte{
+		ctEntry(newCTCert(t, "Let's Encrypt", 10, false, "swanchain.io", "www.swanchain.io"), false),
+		ctEntry(newCTCert(t, "Other CA", 11, false, "unrelated.test"), false),
+		ctEntry(newCTCert(t, "Rogue CA", 12, true, "*.api.swanchain.io"), true),
+		{"leaf_input": {1, 0}, "extra_data": {}},
+		ctEntry(newCTCert(t, "Rogue CA", 13, false, "notswanchain.io"), false),
+	}
+	server := newFakeLog(t, entries, 2)
+
+	monitor := NewCTMonitor(CTConfig{BatchSize: 3, MaxEntries: 10}, []string{"swanchain.io"})
+
+	certs, next, err := monitor.PollLog(server.URL, 0)
+	if err != nil {
+		t.Fatalf("PollLog() returned error: %v", err)
+	}
+	if next != 5 || len(certs) != 2 {
+		t.Fatalf("PollLog() = %+v, %d", certs, next)
+	}
+
+	if certs[0].Index != 0 || certs[0].Precert || strings.Join(certs[0].Names, ",") != "swanchain.io,www.swanchain.io" {
+		t.Errorf("Unexpected certificate: %+v", certs[0])
+	}
+	if !certs[0].ExpectedIssuer([]string{"let's encrypt", "DigiCert"}) {
+		t.Errorf("Expected %s to be an expected issuer", certs[0].Issuer)
+	}
+
+	rogue := certs[1]
+	if rogue.Index != 2 || !rogue.Precert || rogue.SerialNumber != "c" || rogue.ExpectedIssuer([]string{"Let's Encrypt"}) {
+		t.Errorf("Unexpected precertificate: %+v", rogue)
+	}
+	if message := rogue.Message([]string{"Let's Encrypt"}); !strings.Contains(message, "precertificate for *.api.swanchain.io from unexpected issuer CN=Rogue CA CA,O=Rogue CA") {
+		t.Errorf("Unexpected message: %s", message)
+	}
+	if message := rogue.Message(nil); !strings.Contains(message, "precertificate for *.api.swanchain.io from issuer CN=Rogue CA CA,O=Rogue CA") {
+		t.Errorf("Unexpected message without expected issuers: %s", message)
+	}
+
+	certs, next, err = monitor.PollLog(server.URL, 5)
+	if err != nil || next != 5 || len(certs) != 0 {
+		t.Errorf("PollLog() at the tree head = %+v, %d, %v", certs, next, err)
+	}
+
+	monitor.MaxEntries = 2
+	if _, next, _ = monitor.PollLog(server.URL, 0); next != 2 {
+		t.Errorf("PollLog() with MaxEntries 2 stopped at %d", next)
+	}
+
+	monitor.MaxLag = 4
+	if certs, next, err = monitor.PollLog(server.URL, 0); err != nil || next != 5 || len(certs) != 0 {
+		t.Errorf("PollLog() more than MaxLag behind = %+v, %d, %v", certs, next, err)
+	}
+}
+
+func TestPollCrtSh(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("q") != "%.swanchain.io" || r.URL.Query().Get("output") != "json" {
+			http.Error(w, "bad query", http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`[
+			{"id": 900, "issuer_name": "C=US, O=Let's Encrypt, CN=R3", "name_value": "swanchain.io", "serial_number": "0a", "not_before": "2026-01-01T00:00:00", "not_after": "2026-04-01T00:00:00"},
+			{"id": 1200, "issuer_name": "C=XX, O=Rogue CA, CN=Rogue", "name_value": "swanchain.io\nwww.swanchain.io", "serial_number": "0b", "not_before": "2026-10-01T00:00:00", "not_after": "2027-01-01T00:00:00"}
+		]`))
+	}))
+	defer server.Close()
+
+	monitor := NewCTMonitor(CTConfig{}, []string{"swanchain.io"})
+	certs, latest, err := monitor.PollCrtSh(server.URL, "swanchain.io", 1000)
+	if err != nil {
+		t.Fatalf("PollCrtSh() returned error: %v", err)
+	}
+	if latest != 1200 || len(certs) != 1 || certs[0].Index != 1200 || len(certs[0].Names) != 2 || certs[0].NotBefore.Month() != time.October {
+		t.Errorf("PollCrtSh() = %+v, %d", certs, latest)
+	}
+}
+
+func TestRecordCTCertificate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+	cert := CTCertificate{Source: "https://ct.test", Index: 7, Names: []string{"swanchain.io"}, Issuer: "CN=R3", SerialNumber: "0A"}
+
+	mock.ExpectExec("INSERT INTO ct_certificate").
+		WithArgs("CN=R3", "0a", "swanchain.io", "https://ct.test", int64(7), nil, nil, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO ct_certificate").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if isNew, err := RecordCTCertificate(sqlxDB, cert); err != nil || !isNew {
+		t.Errorf("RecordCTCertificate() = %v, %v for a new certificate", isNew, err)
+	}
+	if isNew, err := RecordCTCertificate(sqlxDB, cert); err != nil || isNew {
+		t.Errorf("RecordCTCertificate() = %v, %v for a known certificate", isNew, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unmet expectations: %v", err)
+	}
+}
@@ -0,0 +1,425 @@
+package domaincheck
+
+import (
+	"database/sql"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/swanchain/domain-check/pkg/model"
+	"golang.org/x/net/dns/dnsmessage"
+	"golang.org/x/net/publicsuffix"
+)
+
+// typeCAA is missing from dnsmessage.
+const typeCAA = dnsmessage.Type(257)
+
+var recordTypes = map[string]dnsmessage.Type{
+	"A":     dnsmessage.TypeA,
+	"AAAA":  dnsmessage.TypeAAAA,
+	"CNAME": dnsmessage.TypeCNAME,
+	"MX":    dnsmessage.TypeMX,
+	"TXT":   dnsmessage.TypeTXT,
+	"NS":    dnsmessage.TypeNS,
+	"CAA":   typeCAA,
+}
+
+// DNSConfig is read from info rows of type 'dns' and 'dns-record'.
+type DNSConfig struct {
+	Resolvers   []string
+	RecordTypes []string
+	Records     map[string][]string
+}
+
+func GetDNSConfig(db *sqlx.DB) (DNSConfig, error) {
+	cfg := DNSConfig{
+		Resolvers:   []string{"1.1.1.1:53", "8.8.8.8:53"},
+		RecordTypes: []string{"A", "AAAA", "CNAME", "MX", "TXT", "NS", "CAA"},
+		Records:     map[string][]string{},
+	}
+
+	var configs []model.Info
+	err := db.Select(&configs, "SELECT key, value FROM info WHERE is_active = true AND type = 'dns'")
+	if err != nil {
+		return cfg, err
+	}
+	for _, config := range configs {
+		switch config.Key {
+		case "resolvers":
+			cfg.Resolvers = nil
+			for _, resolver := range splitList(config.Value) {
+				if _, _, err := net.SplitHostPort(resolver); err != nil {
+					resolver = net.JoinHostPort(resolver, "53")
+				}
+				cfg.Resolvers = append(cfg.Resolvers, resolver)
+			}
+		case "record-types":
+			types, err := parseRecordTypes(config.Value)
+			if err != nil {
+				return cfg, err
+			}
+			cfg.RecordTypes = types
+		}
+	}
+
+	var records []model.Info
+	err = db.Select(&records, "SELECT key, value FROM info WHERE is_active = true AND type = 'dns-record'")
+	if err != nil {
+		return cfg, err
+	}
+	for _, record := range records {
+		types, err := parseRecordTypes(record.Value)
+		if err != nil {
+			return cfg, err
+		}
+		cfg.Records[strings.ToLower(record.Key)] = types
+	}
+
+	return cfg, nil
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func parseRecordTypes(value string) ([]string, error) {
+	var types []string
+	for _, t := range splitList(value) {
+		t = strings.ToUpper(t)
+		if _, ok := recordTypes[t]; !ok {
+			return nil, fmt.Errorf("unsupported DNS record type %q", t)
+		}
+		types = append(types, t)
+	}
+	return types, nil
+}
+
+// DNSResult is the answer set for one name and record type.
+type DNSResult struct {
+	Name          string
+	Type          string
+	Answers       []string
+	Err           error
+	Authoritative map[string][]string
+}
+
+// Problems describes failed lookups and nameservers that disagree.
+func (r DNSResult) Problems() []string {
+	var problems []string
+	if r.Err != nil {
+		problems = append(problems, fmt.Sprintf("Resolving the %s records for %s failed: %s.", r.Type, r.Name, r.Err))
+	}
+
+	var servers []string
+	for server := range r.Authoritative {
+		servers = append(servers, server)
+	}
+	sort.Strings(servers)
+	for i := 1; i < len(servers); i++ {
+		if !sameAnswers(r.Authoritative[servers[i]], r.Authoritative[servers[0]]) {
+			var answers []string
+			for _, s := range servers {
+				answers = append(answers, fmt.Sprintf("%s: %v", s, r.Authoritative[s]))
+			}
+			problems = append(problems, fmt.Sprintf("The authoritative nameservers for %s disagree on its %s records (%s).",
+				r.Name, r.Type, strings.Join(answers, "; ")))
+			break
+		}
+	}
+	return problems
+}
+
+// sameAnswers compares two answer sets regardless of order and duplicates.
+func sameAnswers(a, b []string) bool {
+	return slices.Equal(answerSet(a), answerSet(b))
+}
+
+// answerSet returns the answers sorted and without duplicates.
+func answerSet(answers []string) []string {
+	set := slices.Clone(answers)
+	sort.Strings(set)
+	return slices.Compact(set)
+}
+
+// DNSChecker resolves records through the resolvers and each nameserver.
+type DNSChecker struct {
+	Resolvers []string
+	Timeout   time.Duration
+
+	nsPort      string
+	nameservers map[string][]string
+}
+
+func NewDNSChecker(resolvers []string) *DNSChecker {
+	return &DNSChecker{
+		Resolvers:   resolvers,
+		Timeout:     5 * time.Second,
+		nsPort:      "53",
+		nameservers: map[string][]string{},
+	}
+}
+
+// Check resolves each record type for name from the first resolver that responds.
+func (c *DNSChecker) Check(name string, types []string) []DNSResult {
+	name = strings.ToLower(name)
+	nameservers, err := c.authoritative(name)
+	if err != nil {
+		log.Printf("Skipping authoritative check for %s: %s", name, err)
+	}
+
+	var results []DNSResult
+	for _, t := range types {
+		result := DNSResult{Name: name, Type: t, Authoritative: map[string][]string{}}
+		var errs []error
+		for _, resolver := range c.Resolvers {
+			answers, err := c.query(resolver, name, recordTypes[t], true)
+			if err == nil {
+				result.Answers, errs = answers, nil
+				break
+			}
+			errs = append(errs, fmt.Errorf("%s: %v", resolver, err))
+		}
+		if len(errs) > 0 {
+			result.Err = errors.Join(errs...)
+		}
+
+		for _, ns := range nameservers {
+			answers, err := c.query(ns, name, recordTypes[t], false)
+			if err != nil {
+				log.Printf("Querying %s for %s %s failed: %s", ns, name, t, err)
+				continue
+			}
+			result.Authoritative[ns] = answers
+		}
+
+		results = append(results, result)
+	}
+	return results
+}
+
+// authoritative returns the nameserver addresses of the zone containing name.
+func (c *DNSChecker) authoritative(name string) ([]string, error) {
+	registrable, err := publicsuffix.EffectiveTLDPlusOne(name)
+	if err != nil {
+		return nil, err
+	}
+
+	for zone := name; ; {
+		if nameservers, ok := c.nameservers[zone]; ok {
+			return nameservers, nil
+		}
+
+		hosts, err := c.resolve(zone, dnsmessage.TypeNS)
+		if err != nil {
+			return nil, err
+		}
+		if len(hosts) > 0 {
+			var nameservers []string
+			for _, host := range hosts {
+				addrs, err := c.resolve(host, dnsmessage.TypeA)
+				if err != nil {
+					return nil, err
+				}
+				for _, addr := range addrs {
+					nameservers = append(nameservers, net.JoinHostPort(addr, c.nsPort))
+				}
+			}
+			c.nameservers[zone] = nameservers
+			return nameservers, nil
+		}
+
+		if zone == registrable {
+			return nil, fmt.Errorf("no nameservers found for %s", name)
+		}
+		zone = zone[strings.Index(zone, ".")+1:]
+	}
+}
+
+func (c *DNSChecker) resolve(name string, qtype dnsmessage.Type) ([]string, error) {
+	var errs []error
+	for _, resolver := range c.Resolvers {
+		answers, err := c.query(resolver, name, qtype, true)
+		if err == nil {
+			return answers, nil
+		}
+		errs = append(errs, err)
+	}
+	return nil, errors.Join(errs...)
+}
+
+// query asks server one question, retrying over TCP if the answer is truncated.
+func (c *DNSChecker) query(server, name string, qtype dnsmessage.Type, recursive bool) ([]string, error) {
+	fqdn, err := dnsmessage.NewName(strings.TrimSuffix(name, ".") + ".")
+	if err != nil {
+		return nil, err
+	}
+	request := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: uint16(rand.Intn(1 << 16)), RecursionDesired: recursive},
+		Questions: []dnsmessage.Question{{Name: fqdn, Type: qtype, Class: dnsmessage.ClassINET}},
+	}
+	packet, err := request.Pack()
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := c.exchange("udp", server, packet)
+	if err == nil && response.Truncated {
+		response, err = c.exchange("tcp", server, packet)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if response.ID != request.ID {
+		return nil, errors.New("response ID does not match the query")
+	}
+
+	switch response.RCode {
+	case dnsmessage.RCodeSuccess:
+	case dnsmessage.RCodeNameError:
+		return []string{}, nil
+	default:
+		return nil, fmt.Errorf("server returned %s", response.RCode)
+	}
+
+	answers := []string{}
+	for _, answer := range response.Answers {
+		if answer.Header.Type != qtype {
+			continue
+		}
+		answers = append(answers, formatRecord(answer.Body))
+	}
+	sort.Strings(answers)
+	return answers, nil
+}
+
+func (c *DNSChecker) exchange(network, server string, packet []byte) (*dnsmessage.Message, error) {
+	conn, err := net.DialTimeout(network, server, c.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(c.Timeout))
+
+	buf := make([]byte, 65535)
+	var n int
+	if network == "tcp" {
+		if _, err := conn.Write(append(binary.BigEndian.AppendUint16(nil, uint16(len(packet))), packet...)); err != nil {
+			return nil, err
+		}
+		if _, err := io.ReadFull(conn, buf[:2]); err != nil {
+			return nil, err
+		}
+		n = int(binary.BigEndian.Uint16(buf[:2]))
+		if _, err := io.ReadFull(conn, buf[:n]); err != nil {
+			return nil, err
+		}
+	} else {
+		if _, err := conn.Write(packet); err != nil {
+			return nil, err
+		}
+		if n, err = conn.Read(buf); err != nil {
+			return nil, err
+		}
+	}
+
+	var response dnsmessage.Message
+	if err := response.Unpack(buf[:n]); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+func formatRecord(body dnsmessage.ResourceBody) string {
+	switch r := body.(type) {
+	case *dnsmessage.AResource:
+		return net.IP(r.A[:]).String()
+	case *dnsmessage.AAAAResource:
+		return net.IP(r.AAAA[:]).String()
+	case *dnsmessage.CNAMEResource:
+		return strings.ToLower(r.CNAME.String())
+	case *dnsmessage.MXResource:
+		return fmt.Sprintf("%d %s", r.Pref, strings.ToLower(r.MX.String()))
+	case *dnsmessage.TXTResource:
+		return strconv.Quote(strings.Join(r.TXT, ""))
+	case *dnsmessage.NSResource:
+		return strings.ToLower(r.NS.String())
+	case *dnsmessage.UnknownResource:
+		// CAA: flags, tag length, tag, value.
+		if r.Type == typeCAA && len(r.Data) >= 2 && len(r.Data) >= 2+int(r.Data[1]) {
+			tag := string(r.Data[2 : 2+r.Data[1]])
+			return fmt.Sprintf("%d %s %s", r.Data[0], tag, strconv.Quote(string(r.Data[2+r.Data[1]:])))
+		}
+		return fmt.Sprintf("%x", r.Data)
+	default:
+		return body.GoString()
+	}
+}
+
+// RecordDNS stores the observed answers and returns a message while they
+// differ from the expected ones, which change only through AcceptDNS.
+func RecordDNS(db *sqlx.DB, result DNSResult) (string, error) {
+	if result.Err != nil {
+		return "", nil
+	}
+
+	var expected string
+	err := db.Get(&expected, "SELECT answers FROM dns_record WHERE name = $1 AND record_type = $2", result.Name, result.Type)
+	hasExpected := err == nil
+	if err != nil && err != sql.ErrNoRows {
+		log.Printf("Error retrieving expected DNS records for %s: %s", result.Name, err)
+		return "", err
+	}
+
+	observed := answerSet(result.Answers)
+	_, err = db.Exec(`
+		INSERT INTO dns_record (name, record_type, answers, observed, changed_at, checked_at)
+		VALUES ($1, $2, $3, $3, $4, $4)
+		ON CONFLICT (name, record_type) DO UPDATE
+		SET observed = EXCLUDED.observed, checked_at = EXCLUDED.checked_at,
+			changed_at = CASE WHEN dns_record.observed = EXCLUDED.observed THEN dns_record.changed_at ELSE EXCLUDED.changed_at END
+	`, result.Name, result.Type, strings.Join(observed, "\n"), time.Now())
+	if err != nil {
+		log.Printf("Error recording DNS records for %s: %s", result.Name, err)
+		return "", err
+	}
+
+	want := []string{}
+	if expected != "" {
+		want = strings.Split(expected, "\n")
+	}
+	if !hasExpected || sameAnswers(want, observed) {
+		return "", nil
+	}
+	return fmt.Sprintf("The %s records for %s are %v, expected %v. Run `alertctl dns accept %s %s` if the change is intended.",
+		result.Type, result.Name, observed, answerSet(want), result.Name, result.Type), nil
+}
+
+// AcceptDNS makes the observed answers the expected ones.
+func AcceptDNS(db *sqlx.DB, name, recordType string) (string, error) {
+	var answers string
+	err := db.Get(&answers, `
+		UPDATE dns_record SET answers = observed
+		WHERE name = $1 AND record_type = $2
+		RETURNING answers
+	`, strings.ToLower(name), strings.ToUpper(recordType))
+	if err == sql.ErrNoRows {
+		return "", fmt.Errorf("no %s records recorded for %s", strings.ToUpper(recordType), name)
+	}
+	return answers, err
+}
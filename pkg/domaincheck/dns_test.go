@@ -0,0 +1,209 @@
+package domaincheck
+
+import (
+	"database/sql"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// dnsZone maps "name type" to the answers a fake server returns. Names in
+// failing get SERVFAIL; anything else missing gets NXDOMAIN.
+type dnsZone struct {
+	records map[string][]dnsmessage.ResourceBody
+	failing map[string]bool
+}
+
+func serveDNS(t *testing.T, addr string, zone dnsZone) string {
+	t.Helper()
+
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, from, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			var request dnsmessage.Message
+			if request.Unpack(buf[:n]) != nil {
+				continue
+			}
+			question := request.Questions[0]
+			name := strings.TrimSuffix(question.Name.String(), ".")
+
+			response := dnsmessage.Message{
+				Header:    dnsmessage.Header{ID: request.ID, Response: true, Authoritative: true},
+				Questions: request.Questions,
+			}
+			var recordType string
+			for t, qtype := range recordTypes {
+				if qtype == question.Type {
+					recordType = t
+				}
+			}
+			bodies, ok := zone.records[name+" "+recordType]
+			switch {
+			case zone.failing[name]:
+				response.RCode = dnsmessage.RCodeServerFailure
+			case !ok:
+				response.RCode = dnsmessage.RCodeNameError
+			}
+			for _, body := range bodies {
+				response.Answers = append(response.Answers, dnsmessage.Resource{
+					Header: dnsmessage.ResourceHeader{Name: question.Name, Type: question.Type, Class: dnsmessage.ClassINET, TTL: 300},
+					Body:   body,
+				})
+			}
+
+			packet, _ := response.Pack()
+			conn.WriteTo(packet, from)
+		}
+	}()
+
+	return conn.LocalAddr().String()
+}
+
+func mustName(name string) dnsmessage.Name {
+	return dnsmessage.MustNewName(name)
+}
+
+func caa(flags byte, tag, value string) *dnsmessage.UnknownResource {
+	return &dnsmessage.UnknownResource{Type: typeCAA, Data: append([]byte{flags, byte(len(tag))}, tag+value...)}
+}
+
+func TestDNSCheck(t *testing.T) {
+	ns1 := serveDNS(t, "127.0.0.1:0", dnsZone{records: map[string][]dnsmessage.ResourceBody{
+		"www.swan.test A":  {&dnsmessage.AResource{A: [4]byte{192, 0, 2, 1}}},
+		"www.swan.test MX": {&dnsmessage.MXResource{Pref: 10, MX: mustName("mail.swan.test.")}},
+	}})
+	_, port, _ := net.SplitHostPort(ns1)
+	serveDNS(t, "127.0.0.2:"+port, dnsZone{records: map[string][]dnsmessage.ResourceBody{
+		"www.swan.test A":  {&dnsmessage.AResource{A: [4]byte{203, 0, 113, 7}}},
+		"www.swan.test MX": {&dnsmessage.MXResource{Pref: 10, MX: mustName("mail.swan.test.")}},
+	}})
+
+	resolver := serveDNS(t, "127.0.0.1:0", dnsZone{
+		records: map[string][]dnsmessage.ResourceBody{
+			"swan.test NS":      {&dnsmessage.NSResource{NS: mustName("ns2.swan.test.")}, &dnsmessage.NSResource{NS: mustName("ns1.swan.test.")}},
+			"ns1.swan.test A":   {&dnsmessage.AResource{A: [4]byte{127, 0, 0, 1}}},
+			"ns2.swan.test A":   {&dnsmessage.AResource{A: [4]byte{127, 0, 0, 2}}},
+			"www.swan.test A":   {&dnsmessage.AResource{A: [4]byte{192, 0, 2, 2}}, &dnsmessage.AResource{A: [4]byte{192, 0, 2, 1}}},
+			"www.swan.test MX":  {&dnsmessage.MXResource{Pref: 10, MX: mustName("Mail.Swan.Test.")}},
+			"www.swan.test TXT": {&dnsmessage.TXTResource{TXT: []string{"v=spf1 ", "-all"}}},
+			"www.swan.test CAA": {caa(0, "issue", "letsencrypt.org")},
+		},
+		failing: map[string]bool{"broken.swan.test": true},
+	})
+
+	checker := NewDNSChecker([]string{resolver})
+	checker.nsPort = port
+
+	results := checker.Check("WWW.swan.test", []string{"A", "MX", "TXT", "CAA", "AAAA"})
+	want := map[string]string{
+		"A":    "192.0.2.1,192.0.2.2",
+		"MX":   "10 mail.swan.test.",
+		"TXT":  `"v=spf1 -all"`,
+		"CAA":  `0 issue "letsencrypt.org"`,
+		"AAAA": "",
+	}
+	for _, result := range results {
+		if result.Err != nil || strings.Join(result.Answers, ",") != want[result.Type] {
+			t.Errorf("%s: got %v, %v, want %s", result.Type, result.Answers, result.Err, want[result.Type])
+		}
+	}
+
+	if problems := results[0].Problems(); len(problems) != 1 || !strings.Contains(problems[0], "disagree on its A records") {
+		t.Errorf("Unexpected problems for A: %v", problems)
+	}
+	if problems := results[1].Problems(); len(problems) != 0 || len(results[1].Authoritative) != 2 {
+		t.Errorf("Unexpected problems for MX: %v (%v)", problems, results[1].Authoritative)
+	}
+
+	results = checker.Check("broken.swan.test", []string{"A"})
+	if problems := results[0].Problems(); len(problems) != 1 || !strings.Contains(problems[0], "failed") {
+		t.Errorf("Unexpected problems for a failing name: %v", problems)
+	}
+}
+
+func TestRecordDNS(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+	result := DNSResult{Name: "swan.test", Type: "A", Answers: []string{"192.0.2.2", "192.0.2.1"}}
+
+	mock.ExpectQuery("SELECT answers FROM dns_record").WithArgs("swan.test", "A").WillReturnError(sql.ErrNoRows)
+	mock.ExpectExec("INSERT INTO dns_record").WithArgs("swan.test", "A", "192.0.2.1\n192.0.2.2", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	if message, err := RecordDNS(sqlxDB, result); err != nil || message != "" {
+		t.Errorf("RecordDNS() = %q, %v for a new name", message, err)
+	}
+
+	mock.ExpectQuery("SELECT answers FROM dns_record").WithArgs("swan.test", "A").
+		WillReturnRows(sqlmock.NewRows([]string{"answers"}).AddRow("192.0.2.2\n192.0.2.1"))
+	mock.ExpectExec("INSERT INTO dns_record").WillReturnResult(sqlmock.NewResult(1, 1))
+	if message, err := RecordDNS(sqlxDB, result); err != nil || message != "" {
+		t.Errorf("RecordDNS() = %q, %v for the same answers in another order", message, err)
+	}
+
+	// The expected set is not replaced, so the drift is reported on every run.
+	for i := 0; i < 2; i++ {
+		mock.ExpectQuery("SELECT answers FROM dns_record").WithArgs("swan.test", "A").
+			WillReturnRows(sqlmock.NewRows([]string{"answers"}).AddRow("198.51.100.9"))
+		mock.ExpectExec("INSERT INTO dns_record").WillReturnResult(sqlmock.NewResult(1, 1))
+		message, err := RecordDNS(sqlxDB, result)
+		if err != nil || !strings.HasPrefix(message, "The A records for swan.test are [192.0.2.1 192.0.2.2], expected [198.51.100.9].") {
+			t.Errorf("RecordDNS() = %q, %v for changed answers", message, err)
+		}
+	}
+
+	result.Answers = []string{}
+	mock.ExpectQuery("SELECT answers FROM dns_record").WithArgs("swan.test", "A").
+		WillReturnRows(sqlmock.NewRows([]string{"answers"}).AddRow("192.0.2.1"))
+	mock.ExpectExec("INSERT INTO dns_record").WillReturnResult(sqlmock.NewResult(1, 1))
+	if message, err := RecordDNS(sqlxDB, result); err != nil || !strings.Contains(message, "are [], expected") {
+		t.Errorf("RecordDNS() = %q, %v for deleted records", message, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unmet expectations: %v", err)
+	}
+}
+
+func TestAcceptDNS(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	mock.ExpectQuery("UPDATE dns_record SET answers = observed").WithArgs("swan.test", "A").
+		WillReturnRows(sqlmock.NewRows([]string{"answers"}).AddRow("192.0.2.1"))
+	if answers, err := AcceptDNS(sqlxDB, "Swan.test", "a"); err != nil || answers != "192.0.2.1" {
+		t.Errorf("AcceptDNS() = %q, %v", answers, err)
+	}
+
+	mock.ExpectQuery("UPDATE dns_record SET answers = observed").WithArgs("other.test", "MX").WillReturnError(sql.ErrNoRows)
+	if _, err := AcceptDNS(sqlxDB, "other.test", "MX"); err == nil {
+		t.Errorf("AcceptDNS() succeeded for a name that was never recorded")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unmet expectations: %v", err)
+	}
+}
@@ -0,0 +1,37 @@
+package domaincheck
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// Hostname returns the host part of an entry of the info domain list, which
+// may be a bare host or a URL such as https://swanchain.io or
+// smtp://mail.swanchain.io:587.
+func Hostname(domain string) (string, error) {
+	if !strings.Contains(domain, "://") {
+		domain = "https://" + domain
+	}
+	u, err := url.Parse(domain)
+	if err != nil {
+		return "", fmt.Errorf("invalid domain %q: %v", domain, err)
+	}
+	if u.Hostname() == "" {
+		return "", fmt.Errorf("invalid domain %q: no host", domain)
+	}
+	return strings.ToLower(u.Hostname()), nil
+}
+
+// RegistrableDomain returns the domain that is registered with a registrar
+// for an entry of the info domain list, e.g. swanchain.io for
+// https://api.swanchain.io.
+func RegistrableDomain(domain string) (string, error) {
+	host, err := Hostname(domain)
+	if err != nil {
+		return "", err
+	}
+	return publicsuffix.EffectiveTLDPlusOne(host)
+}
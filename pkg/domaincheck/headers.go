@@ -0,0 +1,231 @@
+package domaincheck
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	SeverityWarning = "warning"
+	SeverityInfo    = "info"
+)
+
+// Six months and one year, the shortest HSTS max-age not reported as weak
+// and the minimum for the preload list.
+const (
+	hstsMinMaxAge     = 15768000
+	hstsPreloadMaxAge = 31536000
+)
+
+// HeaderFinding is one problem found by the header audit.
+type HeaderFinding struct {
+	ID       string `json:"id"`
+	Severity string `json:"severity"`
+	Detail   string `json:"detail"`
+}
+
+// HeaderAudit holds the findings for one site.
+type HeaderAudit struct {
+	URL      string
+	Findings []HeaderFinding
+}
+
+// AuditHeaders checks the security headers, cookies and HTTPS redirect of domain.
+func AuditHeaders(domain string) (*HeaderAudit, error) {
+	httpsURL, err := url.Parse(domain)
+	if err != nil {
+		return nil, err
+	}
+	httpsURL.Scheme = "https"
+	httpURL := *httpsURL
+	httpURL.Scheme = "http"
+	httpURL.Host = httpsURL.Hostname()
+
+	return auditHeaders(&http.Client{Timeout: 30 * time.Second}, httpsURL.String(), httpURL.String())
+}
+
+func auditHeaders(client *http.Client, httpsURL, httpURL string) (*HeaderAudit, error) {
+	audit := &HeaderAudit{URL: httpsURL}
+	add := func(id, severity, detail string) {
+		audit.Findings = append(audit.Findings, HeaderFinding{ID: id, Severity: severity, Detail: detail})
+	}
+
+	resp, err := client.Get(httpsURL)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+
+	auditHSTS(resp.Header.Get("Strict-Transport-Security"), add)
+
+	csp := resp.Header.Get("Content-Security-Policy")
+	if csp == "" {
+		add("csp-missing", SeverityWarning, "Content-Security-Policy header is missing")
+	} else {
+		for _, source := range []string{"'unsafe-inline'", "'unsafe-eval'"} {
+			if strings.Contains(csp, source) {
+				add("csp-"+strings.Trim(source, "'"), SeverityWarning, "Content-Security-Policy allows "+source)
+			}
+		}
+	}
+
+	frameOptions := strings.ToUpper(strings.TrimSpace(resp.Header.Get("X-Frame-Options")))
+	switch {
+	case frameOptions == "" && !strings.Contains(csp, "frame-ancestors"):
+		add("x-frame-options-missing", SeverityWarning, "X-Frame-Options header is missing and the CSP has no frame-ancestors")
+	case frameOptions != "" && frameOptions != "DENY" && frameOptions != "SAMEORIGIN":
+		add("x-frame-options-invalid", SeverityWarning, fmt.Sprintf("X-Frame-Options is %q, expected DENY or SAMEORIGIN", frameOptions))
+	}
+
+	for _, cookie := range resp.Cookies() {
+		if !cookie.Secure {
+			add("cookie-not-secure:"+cookie.Name, SeverityWarning, fmt.Sprintf("cookie %s is missing the Secure flag", cookie.Name))
+		}
+		if !cookie.HttpOnly {
+			add("cookie-not-httponly:"+cookie.Name, SeverityWarning, fmt.Sprintf("cookie %s is missing the HttpOnly flag", cookie.Name))
+		}
+	}
+
+	auditRedirect(client, httpsURL, httpURL, add)
+
+	sort.Slice(audit.Findings, func(i, j int) bool { return audit.Findings[i].ID < audit.Findings[j].ID })
+	return audit, nil
+}
+
+func auditHSTS(header string, add func(id, severity, detail string)) {
+	if header == "" {
+		add("hsts-missing", SeverityWarning, "Strict-Transport-Security header is missing")
+		return
+	}
+
+	maxAge := -1
+	var includeSubDomains, preload bool
+	for _, directive := range strings.Split(header, ";") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		switch strings.ToLower(name) {
+		case "max-age":
+			if n, err := strconv.Atoi(strings.Trim(value, `"`)); err == nil {
+				maxAge = n
+			}
+		case "includesubdomains":
+			includeSubDomains = true
+		case "preload":
+			preload = true
+		}
+	}
+
+	if maxAge < hstsMinMaxAge {
+		add("hsts-weak", SeverityWarning, fmt.Sprintf("Strict-Transport-Security max-age is %d, should be at least %d", maxAge, hstsMinMaxAge))
+	}
+	var missing []string
+	if maxAge < hstsPreloadMaxAge {
+		missing = append(missing, fmt.Sprintf("max-age of at least %d", hstsPreloadMaxAge))
+	}
+	if !includeSubDomains {
+		missing = append(missing, "includeSubDomains")
+	}
+	if !preload {
+		missing = append(missing, "preload")
+	}
+	if len(missing) > 0 {
+		add("hsts-not-preload-eligible", SeverityInfo, "Strict-Transport-Security is not preload eligible, missing "+strings.Join(missing, ", "))
+	}
+}
+
+// auditRedirect checks that plain HTTP redirects to HTTPS on the same host.
+func auditRedirect(client *http.Client, httpsURL, httpURL string, add func(id, severity, detail string)) {
+	noRedirects := *client
+	noRedirects.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	resp, err := noRedirects.Get(httpURL)
+	if err != nil {
+		log.Printf("Skipping HTTP redirect check for %s: %s", httpURL, err)
+		return
+	}
+	resp.Body.Close()
+
+	location, err := resp.Location()
+	switch {
+	case resp.StatusCode < 300 || resp.StatusCode > 399 || err != nil:
+		add("http-no-redirect", SeverityWarning, fmt.Sprintf("%s returns status %d instead of redirecting to HTTPS", httpURL, resp.StatusCode))
+	case location.Scheme != "https":
+		add("http-redirect-insecure", SeverityWarning, fmt.Sprintf("%s redirects to %s, not to HTTPS", httpURL, location))
+	default:
+		target, _ := url.Parse(httpsURL)
+		if location.Hostname() != target.Hostname() {
+			add("http-redirect-other-host", SeverityInfo, fmt.Sprintf("%s redirects to %s on another host, so HSTS is not set for %s first", httpURL, location, target.Hostname()))
+		}
+	}
+}
+
+// RecordHeaderAudit stores the findings and describes those that changed
+// since the previous audit, with the severity of the worst new one.
+func RecordHeaderAudit(db *sqlx.DB, audit *HeaderAudit) ([]string, string, error) {
+	previous := "[]"
+	err := db.Get(&previous, "SELECT findings FROM header_audit WHERE url = $1", audit.URL)
+	if err != nil && err != sql.ErrNoRows {
+		log.Printf("Error retrieving previous header audit for %s: %s", audit.URL, err)
+		return nil, "", err
+	}
+
+	findings, err := json.Marshal(audit.Findings)
+	if err != nil {
+		return nil, "", err
+	}
+	_, err = db.Exec(`
+		INSERT INTO header_audit (url, findings, checked_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (url) DO UPDATE
+		SET findings = EXCLUDED.findings, checked_at = EXCLUDED.checked_at
+	`, audit.URL, string(findings), time.Now())
+	if err != nil {
+		log.Printf("Error recording header audit for %s: %s", audit.URL, err)
+		return nil, "", err
+	}
+
+	messages, severity := diffHeaderFindings(audit, previous)
+	return messages, severity, nil
+}
+
+func diffHeaderFindings(audit *HeaderAudit, previousFindings string) ([]string, string) {
+	var before []HeaderFinding
+	json.Unmarshal([]byte(previousFindings), &before)
+
+	previous := map[string]bool{}
+	for _, finding := range before {
+		previous[finding.ID] = true
+	}
+	current := map[string]bool{}
+	for _, finding := range audit.Findings {
+		current[finding.ID] = true
+	}
+
+	var messages []string
+	severity := SeverityInfo
+	for _, finding := range audit.Findings {
+		if !previous[finding.ID] {
+			messages = append(messages, fmt.Sprintf("New %s for %s: %s.", finding.Severity, audit.URL, finding.Detail))
+			if finding.Severity == SeverityWarning {
+				severity = SeverityWarning
+			}
+		}
+	}
+	for _, finding := range before {
+		if !current[finding.ID] {
+			messages = append(messages, fmt.Sprintf("Resolved for %s: %s.", audit.URL, finding.Detail))
+		}
+	}
+	return messages, severity
+}
@@ -0,0 +1,131 @@
+package domaincheck
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+)
+
+func findingIDs(audit *HeaderAudit) string {
+	var ids []string
+	for _, finding := range audit.Findings {
+		ids = append(ids, finding.ID)
+	}
+	return strings.Join(ids, ",")
+}
+
+func TestAuditHeadersHardened(t *testing.T) {
+	site := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Strict-Transport-Security", "max-age=63072000; includeSubDomains; preload")
+		w.Header().Set("Content-Security-Policy", "default-src 'self'; frame-ancestors 'none'")
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "x", Secure: true, HttpOnly: true})
+	}))
+	defer site.Close()
+	plain := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, site.URL+r.URL.Path, http.StatusMovedPermanently)
+	}))
+	defer plain.Close()
+
+	audit, err := auditHeaders(site.Client(), site.URL+"/", plain.URL+"/")
+	if err != nil {
+		t.Fatalf("auditHeaders() returned error: %v", err)
+	}
+	if len(audit.Findings) != 0 {
+		t.Errorf("Unexpected findings: %+v", audit.Findings)
+	}
+}
+
+func TestAuditHeadersWeak(t *testing.T) {
+	site := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Strict-Transport-Security", "max-age=86400")
+		w.Header().Set("Content-Security-Policy", "script-src 'self' 'unsafe-inline'")
+		w.Header().Set("X-Frame-Options", "ALLOW-FROM https://swan.test")
+		http.SetCookie(w, &http.Cookie{Name: "tracking", Value: "x"})
+	}))
+	defer site.Close()
+	plain := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("plain"))
+	}))
+	defer plain.Close()
+
+	audit, err := auditHeaders(site.Client(), site.URL+"/", plain.URL+"/")
+	if err != nil {
+		t.Fatalf("auditHeaders() returned error: %v", err)
+	}
+
+	want := "cookie-not-httponly:tracking,cookie-not-secure:tracking,csp-unsafe-inline,hsts-not-preload-eligible,hsts-weak,http-no-redirect,x-frame-options-invalid"
+	if got := findingIDs(audit); got != want {
+		t.Errorf("Findings = %s, want %s", got, want)
+	}
+}
+
+func TestAuditHeadersMissing(t *testing.T) {
+	site := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer site.Close()
+	plain := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://www.swan.test/", http.StatusFound)
+	}))
+	defer plain.Close()
+
+	audit, err := auditHeaders(site.Client(), site.URL+"/", plain.URL+"/")
+	if err != nil {
+		t.Fatalf("auditHeaders() returned error: %v", err)
+	}
+
+	want := "csp-missing,hsts-missing,http-redirect-insecure,x-frame-options-missing"
+	if got := findingIDs(audit); got != want {
+		t.Errorf("Findings = %s, want %s", got, want)
+	}
+}
+
+func TestRecordHeaderAudit(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	mock.ExpectQuery("SELECT findings FROM header_audit").WithArgs("https://swan.test/").
+		WillReturnRows(sqlmock.NewRows([]string{"findings"}).
+			AddRow(`[{"id": "csp-missing", "severity": "warning", "detail": "Content-Security-Policy header is missing"}]`))
+	mock.ExpectExec("INSERT INTO header_audit").WillReturnResult(sqlmock.NewResult(1, 1))
+
+	audit := &HeaderAudit{
+		URL:      "https://swan.test/",
+		Findings: []HeaderFinding{{ID: "hsts-missing", Severity: SeverityWarning, Detail: "Strict-Transport-Security header is missing"}},
+	}
+	messages, severity, err := RecordHeaderAudit(sqlxDB, audit)
+	if err != nil {
+		t.Fatalf("RecordHeaderAudit() returned error: %v", err)
+	}
+
+	want := []string{
+		"New warning for https://swan.test/: Strict-Transport-Security header is missing.",
+		"Resolved for https://swan.test/: Content-Security-Policy header is missing.",
+	}
+	if strings.Join(messages, "\n") != strings.Join(want, "\n") || severity != SeverityWarning {
+		t.Errorf("Unexpected messages: %v, %s", messages, severity)
+	}
+
+	// The first audit of a URL reports every finding.
+	mock.ExpectQuery("SELECT findings FROM header_audit").WithArgs("https://new.swan.test/").WillReturnError(sql.ErrNoRows)
+	mock.ExpectExec("INSERT INTO header_audit").WillReturnResult(sqlmock.NewResult(1, 1))
+	audit = &HeaderAudit{
+		URL:      "https://new.swan.test/",
+		Findings: []HeaderFinding{{ID: "hsts-not-preload-eligible", Severity: SeverityInfo, Detail: "Strict-Transport-Security is not preload eligible"}},
+	}
+	messages, severity, err = RecordHeaderAudit(sqlxDB, audit)
+	if err != nil || len(messages) != 1 || severity != SeverityInfo {
+		t.Errorf("RecordHeaderAudit() = %v, %s, %v for a first audit", messages, severity, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unmet expectations: %v", err)
+	}
+}
@@ -0,0 +1,211 @@
+package domaincheck
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// maxBodySize limits how much of a response is read for content checks.
+const maxBodySize = 10 << 20
+
+// HTTPCheck is a request declared in the http_check table together with the
+// conditions its response must satisfy. Every http(s) URL in the domain list
+// is checked with the defaults from NewHTTPCheck until it has a row of its
+// own; the first result recorded for it creates that row.
+//
+// ExpectedStatus 0 accepts any 2xx status. JSONPath is a dot separated path
+// such as data.items.0.status into a JSON body; the value must exist and, if
+// JSONExpected is set, equal it.
+type HTTPCheck struct {
+	ID             int    `db:"id"`
+	URL            string `db:"url"`
+	ExpectedStatus int    `db:"expected_status"`
+	MaxResponseMs  int    `db:"max_response_ms"`
+	BodyContains   string `db:"body_contains"`
+	BodyRegex      string `db:"body_regex"`
+	JSONPath       string `db:"json_path"`
+	JSONExpected   string `db:"json_expected"`
+	MaxRedirects   int    `db:"max_redirects"`
+}
+
+func NewHTTPCheck(url string) HTTPCheck {
+	return HTTPCheck{
+		URL:           url,
+		MaxResponseMs: 10000,
+		MaxRedirects:  5,
+	}
+}
+
+func GetHTTPChecks(db *sqlx.DB) ([]HTTPCheck, error) {
+	var checks []HTTPCheck
+	err := db.Select(&checks, `
+		SELECT id, url, expected_status, max_response_ms, body_contains, body_regex, json_path, json_expected,
+			max_redirects
+		FROM http_check WHERE is_active = true ORDER BY id
+	`)
+	if err != nil {
+		log.Printf("Error retrieving HTTP checks: %s", err)
+		return nil, err
+	}
+	return checks, nil
+}
+
+// HTTPResult is the outcome of one request. Err is nil if every condition of
+// the check held.
+type HTTPResult struct {
+	Status       int
+	ResponseTime time.Duration
+	Err          error
+}
+
+// RunHTTPCheck requests the URL, following at most MaxRedirects redirects,
+// and evaluates the final response. The response time includes reading the
+// body.
+func RunHTTPCheck(check HTTPCheck) HTTPResult {
+	// Slow responses are still read to completion so they can be reported
+	// with their response time.
+	timeout := max(30*time.Second, 2*time.Duration(check.MaxResponseMs)*time.Millisecond)
+	client := &http.Client{
+		Timeout: timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > check.MaxRedirects {
+				return fmt.Errorf("stopped after %d redirects", check.MaxRedirects)
+			}
+			return nil
+		},
+	}
+
+	start := time.Now()
+	resp, err := client.Get(check.URL)
+	if err != nil {
+		return HTTPResult{ResponseTime: time.Since(start), Err: err}
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+	result := HTTPResult{Status: resp.StatusCode, ResponseTime: time.Since(start)}
+	if err != nil {
+		result.Err = fmt.Errorf("reading body: %v", err)
+		return result
+	}
+
+	result.Err = check.Evaluate(resp.StatusCode, result.ResponseTime, body)
+	return result
+}
+
+// Evaluate returns nil if the response satisfies the check.
+func (c HTTPCheck) Evaluate(status int, responseTime time.Duration, body []byte) error {
+	switch {
+	case c.ExpectedStatus != 0 && status != c.ExpectedStatus:
+		return fmt.Errorf("status %d, expected %d", status, c.ExpectedStatus)
+	case c.ExpectedStatus == 0 && (status < 200 || status > 299):
+		return fmt.Errorf("status %d", status)
+	}
+
+	if limit := time.Duration(c.MaxResponseMs) * time.Millisecond; limit > 0 && responseTime > limit {
+		return fmt.Errorf("response took %s, max is %s", responseTime.Round(time.Millisecond), limit)
+	}
+
+	if c.BodyContains != "" && !strings.Contains(string(body), c.BodyContains) {
+		return fmt.Errorf("body does not contain %q", c.BodyContains)
+	}
+
+	if c.BodyRegex != "" {
+		re, err := regexp.Compile(c.BodyRegex)
+		if err != nil {
+			return fmt.Errorf("invalid body_regex %q: %v", c.BodyRegex, err)
+		}
+		if !re.Match(body) {
+			return fmt.Errorf("body does not match %q", c.BodyRegex)
+		}
+	}
+
+	if c.JSONPath != "" {
+		value, err := lookupJSONPath(body, c.JSONPath)
+		if err != nil {
+			return err
+		}
+		if c.JSONExpected != "" && value != c.JSONExpected {
+			return fmt.Errorf("%s is %q, expected %q", c.JSONPath, value, c.JSONExpected)
+		}
+	}
+
+	return nil
+}
+
+// lookupJSONPath returns the value at path formatted as a string. Numeric
+// path elements index into arrays.
+func lookupJSONPath(body []byte, path string) (string, error) {
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return "", fmt.Errorf("body is not JSON: %v", err)
+	}
+
+	for _, key := range strings.Split(path, ".") {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			next, ok := v[key]
+			if !ok {
+				return "", fmt.Errorf("%s not found", path)
+			}
+			value = next
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				return "", fmt.Errorf("%s not found", path)
+			}
+			value = v[i]
+		default:
+			return "", fmt.Errorf("%s not found", path)
+		}
+	}
+
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case nil:
+		return "null", nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	default:
+		encoded, _ := json.Marshal(v)
+		return string(encoded), nil
+	}
+}
+
+// RecordHTTPCheckResult stores the result of the latest run.
+func RecordHTTPCheckResult(db *sqlx.DB, check HTTPCheck, result HTTPResult) error {
+	var lastError string
+	if result.Err != nil {
+		lastError = result.Err.Error()
+	}
+
+	_, err := db.Exec(`
+		INSERT INTO http_check (url, last_status, last_response_ms, last_error, last_checked_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (url) DO UPDATE
+		SET last_status = EXCLUDED.last_status, last_response_ms = EXCLUDED.last_response_ms,
+			last_error = EXCLUDED.last_error, last_checked_at = EXCLUDED.last_checked_at
+	`, check.URL, result.Status, result.ResponseTime.Milliseconds(), lastError, time.Now())
+	if err != nil {
+		log.Printf("Error recording result for HTTP check %s: %s", check.URL, err)
+		return err
+	}
+	return nil
+}
+
+// IsHTTPURL reports whether an entry of the domain list is an http(s) URL.
+func IsHTTPURL(domain string) bool {
+	return strings.HasPrefix(domain, "http://") || strings.HasPrefix(domain, "https://")
+}
@@ -0,0 +1,99 @@
+package domaincheck
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+)
+
+func newHTTPServer(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"version": "1.4.2", "chain": {"height": 1024, "healthy": true}, "peers": [{"id": "a"}, {"id": "b"}]}`)
+	})
+	mux.HandleFunc("/redirect/", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/redirect"+r.URL.Path, http.StatusFound)
+	})
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/status", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+	})
+	mux.HandleFunc("/missing", http.NotFound)
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestRunHTTPCheck(t *testing.T) {
+	server := newHTTPServer(t)
+
+	tests := []struct {
+		name   string
+		modify func(c *HTTPCheck)
+		path   string
+		err    string
+	}{
+		{"ok", func(c *HTTPCheck) {}, "/status", ""},
+		{"redirect followed", func(c *HTTPCheck) {}, "/moved", ""},
+		{"too many redirects", func(c *HTTPCheck) {}, "/redirect/", "stopped after 5 redirects"},
+		{"not found", func(c *HTTPCheck) {}, "/missing", "status 404"},
+		{"expected 404", func(c *HTTPCheck) { c.ExpectedStatus = 404 }, "/missing", ""},
+		{"slow", func(c *HTTPCheck) { c.MaxResponseMs = 20 }, "/slow", "response took"},
+		{"contains", func(c *HTTPCheck) { c.BodyContains = `"healthy": true` }, "/status", ""},
+		{"does not contain", func(c *HTTPCheck) { c.BodyContains = "maintenance" }, "/status", "does not contain"},
+		{"regex", func(c *HTTPCheck) { c.BodyRegex = `"version": "1\.\d+` }, "/status", ""},
+		{"regex mismatch", func(c *HTTPCheck) { c.BodyRegex = `"version": "2\.` }, "/status", "does not match"},
+		{"json value", func(c *HTTPCheck) { c.JSONPath, c.JSONExpected = "chain.height", "1024" }, "/status", ""},
+		{"json array", func(c *HTTPCheck) { c.JSONPath, c.JSONExpected = "peers.1.id", "b" }, "/status", ""},
+		{"json exists", func(c *HTTPCheck) { c.JSONPath = "chain.healthy" }, "/status", ""},
+		{"json mismatch", func(c *HTTPCheck) { c.JSONPath, c.JSONExpected = "chain.healthy", "false" }, "/status", `chain.healthy is "true", expected "false"`},
+		{"json missing", func(c *HTTPCheck) { c.JSONPath = "peers.5.id" }, "/status", "peers.5.id not found"},
+	}
+
+	for _, tt := range tests {
+		check := NewHTTPCheck(server.URL + tt.path)
+		tt.modify(&check)
+
+		result := RunHTTPCheck(check)
+		switch {
+		case tt.err == "" && result.Err != nil:
+			t.Errorf("%s: unexpected error: %v", tt.name, result.Err)
+		case tt.err != "" && (result.Err == nil || !strings.Contains(result.Err.Error(), tt.err)):
+			t.Errorf("%s: got error %v, want %q", tt.name, result.Err, tt.err)
+		}
+	}
+}
+
+func TestRecordHTTPCheckResult(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	mock.ExpectExec("INSERT INTO http_check").
+		WithArgs("https://swan.test/", 502, int64(120), "status 502", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	err = RecordHTTPCheckResult(sqlxDB, NewHTTPCheck("https://swan.test/"),
+		HTTPResult{Status: 502, ResponseTime: 120 * time.Millisecond, Err: errors.New("status 502")})
+	if err != nil {
+		t.Errorf("RecordHTTPCheckResult() = %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unmet expectations: %v", err)
+	}
+}
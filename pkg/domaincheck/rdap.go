@@ -0,0 +1,336 @@
+package domaincheck
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/swanchain/domain-check/pkg/database"
+	"github.com/swanchain/domain-check/pkg/model"
+	"github.com/swanchain/domain-check/pkg/sslcert"
+)
+
+const (
+	SourceRDAP  = "rdap"
+	SourceWHOIS = "whois"
+)
+
+// Registration is what the registry reports for a registrable domain.
+type Registration struct {
+	Domain    string
+	Registrar string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	Source    string
+}
+
+// RegistrationConfig holds the warning windows for domain registration
+// expiry, from info rows of type 'domain-expiry'.
+type RegistrationConfig struct {
+	WarningWindows []time.Duration
+}
+
+func GetRegistrationConfig(db *sqlx.DB) (RegistrationConfig, error) {
+	cfg := RegistrationConfig{
+		WarningWindows: []time.Duration{60 * 24 * time.Hour, 30 * 24 * time.Hour, 14 * 24 * time.Hour, 7 * 24 * time.Hour},
+	}
+
+	var configs []model.Info
+	err := db.Select(&configs, "SELECT key, value FROM info WHERE is_active = true AND type = 'domain-expiry'")
+	if err != nil {
+		return cfg, err
+	}
+
+	for _, config := range configs {
+		switch config.Key {
+		case "warning-days":
+			windows, err := sslcert.ParseWarningDays(config.Value)
+			if err != nil {
+				return cfg, err
+			}
+			cfg.WarningWindows = windows
+		}
+	}
+
+	return cfg, nil
+}
+
+// ExpiryMessage returns a warning if the registration is inside one of the
+// warning windows.
+func (r *Registration) ExpiryMessage(now time.Time, windows []time.Duration) (string, bool) {
+	window, ok := sslcert.WarningWindow(r.ExpiresAt, now, windows)
+	if !ok {
+		return "", false
+	}
+	if !r.ExpiresAt.After(now) {
+		return fmt.Sprintf("The registration for %s (registrar: %s) expired on %s.", r.Domain, r.Registrar, r.ExpiresAt.String()), true
+	}
+	return fmt.Sprintf("The registration for %s (registrar: %s) will expire on %s, in %s (%d day warning).",
+		r.Domain, r.Registrar, r.ExpiresAt.String(), sslcert.FormatDuration(r.ExpiresAt.Sub(now)), int(window.Hours()/24)), true
+}
+
+// RegistrationChecker looks up registrations over RDAP, using the IANA
+// bootstrap file to find each TLD's server, and falls back to WHOIS for TLDs
+// without RDAP or when the RDAP lookup fails.
+type RegistrationChecker struct {
+	BootstrapURL string
+	WhoisServer  string
+	Client       *http.Client
+
+	once     sync.Once
+	services map[string]string
+	err      error
+}
+
+func NewRegistrationChecker() *RegistrationChecker {
+	return &RegistrationChecker{
+		BootstrapURL: "https://data.iana.org/rdap/dns.json",
+		WhoisServer:  "whois.iana.org:43",
+		Client:       &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+func (c *RegistrationChecker) Lookup(domain string) (*Registration, error) {
+	registration, rdapErr := c.lookupRDAP(domain)
+	if rdapErr == nil {
+		return registration, nil
+	}
+
+	registration, whoisErr := c.lookupWHOIS(domain)
+	if whoisErr != nil {
+		return nil, fmt.Errorf("registration lookup for %s failed: %v", domain, errors.Join(rdapErr, whoisErr))
+	}
+	return registration, nil
+}
+
+type rdapBootstrap struct {
+	Services [][][]string `json:"services"`
+}
+
+func (c *RegistrationChecker) loadBootstrap() {
+	resp, err := c.Client.Get(c.BootstrapURL)
+	if err != nil {
+		c.err = err
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		c.err = fmt.Errorf("RDAP bootstrap returned %s", resp.Status)
+		return
+	}
+
+	var bootstrap rdapBootstrap
+	if err := json.NewDecoder(resp.Body).Decode(&bootstrap); err != nil {
+		c.err = fmt.Errorf("invalid RDAP bootstrap: %v", err)
+		return
+	}
+
+	c.services = map[string]string{}
+	for _, service := range bootstrap.Services {
+		if len(service) != 2 || len(service[1]) == 0 {
+			continue
+		}
+		base := service[1][0]
+		for _, url := range service[1] {
+			if strings.HasPrefix(url, "https://") {
+				base = url
+				break
+			}
+		}
+		for _, tld := range service[0] {
+			c.services[strings.ToLower(tld)] = strings.TrimSuffix(base, "/") + "/"
+		}
+	}
+}
+
+type rdapDomain struct {
+	LDHName string `json:"ldhName"`
+	Events  []struct {
+		Action string    `json:"eventAction"`
+		Date   time.Time `json:"eventDate"`
+	} `json:"events"`
+	Entities []struct {
+		Roles      []string        `json:"roles"`
+		VCardArray json.RawMessage `json:"vcardArray"`
+	} `json:"entities"`
+}
+
+func (c *RegistrationChecker) lookupRDAP(domain string) (*Registration, error) {
+	c.once.Do(c.loadBootstrap)
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	tld := domain[strings.LastIndex(domain, ".")+1:]
+	base, ok := c.services[tld]
+	if !ok {
+		return nil, fmt.Errorf("no RDAP server for .%s", tld)
+	}
+
+	resp, err := c.Client.Get(base + "domain/" + domain)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("RDAP server returned %s", resp.Status)
+	}
+
+	var response rdapDomain
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("invalid RDAP response: %v", err)
+	}
+
+	registration := &Registration{Domain: domain, Source: SourceRDAP}
+	for _, event := range response.Events {
+		switch event.Action {
+		case "registration":
+			registration.CreatedAt = event.Date
+		case "expiration":
+			registration.ExpiresAt = event.Date
+		}
+	}
+	for _, entity := range response.Entities {
+		for _, role := range entity.Roles {
+			if role == "registrar" {
+				registration.Registrar = vcardName(entity.VCardArray)
+			}
+		}
+	}
+
+	if registration.ExpiresAt.IsZero() {
+		return nil, errors.New("RDAP response has no expiration event")
+	}
+	return registration, nil
+}
+
+// vcardName returns the fn property of a jCard such as
+// ["vcard", [["version", {}, "text", "4.0"], ["fn", {}, "text", "Name"]]].
+func vcardName(raw json.RawMessage) string {
+	var vcard []json.RawMessage
+	if json.Unmarshal(raw, &vcard) != nil || len(vcard) != 2 {
+		return ""
+	}
+	var properties [][]interface{}
+	if json.Unmarshal(vcard[1], &properties) != nil {
+		return ""
+	}
+	for _, property := range properties {
+		if len(property) == 4 && property[0] == "fn" {
+			name, _ := property[3].(string)
+			return name
+		}
+	}
+	return ""
+}
+
+// whoisDateLayouts are the date formats seen in WHOIS expiry lines.
+var whoisDateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05Z",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	"02-Jan-2006",
+	"2006.01.02",
+}
+
+var whoisExpiryKeys = []string{
+	"registry expiry date",
+	"registrar registration expiration date",
+	"expiration date",
+	"expiry date",
+	"expires",
+	"paid-till",
+}
+
+// lookupWHOIS asks the WHOIS server which server is authoritative for the
+// domain's TLD and then queries that server.
+func (c *RegistrationChecker) lookupWHOIS(domain string) (*Registration, error) {
+	fields, err := queryWHOIS(c.WhoisServer, domain)
+	if err != nil {
+		return nil, err
+	}
+	if refer := fields["refer"]; refer != "" {
+		if !strings.Contains(refer, ":") {
+			refer += ":43"
+		}
+		if fields, err = queryWHOIS(refer, domain); err != nil {
+			return nil, err
+		}
+	}
+
+	registration := &Registration{Domain: domain, Registrar: fields["registrar"], Source: SourceWHOIS}
+	registration.CreatedAt, _ = parseWHOISDate(fields["creation date"])
+	for _, key := range whoisExpiryKeys {
+		if value := fields[key]; value != "" {
+			registration.ExpiresAt, err = parseWHOISDate(value)
+			if err != nil {
+				return nil, err
+			}
+			return registration, nil
+		}
+	}
+	return nil, errors.New("WHOIS response has no expiry date")
+}
+
+// queryWHOIS returns the "key: value" lines of the response with lower case
+// keys. The first occurrence of a key wins.
+func queryWHOIS(server, domain string) (map[string]string, error) {
+	conn, err := net.DialTimeout("tcp", server, 15*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(15 * time.Second))
+
+	if _, err := fmt.Fprintf(conn, "%s\r\n", domain); err != nil {
+		return nil, err
+	}
+
+	fields := map[string]string{}
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if !ok || strings.HasPrefix(key, "%") || strings.HasPrefix(key, "#") {
+			continue
+		}
+		key, value = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(value)
+		if _, seen := fields[key]; !seen && value != "" {
+			fields[key] = value
+		}
+	}
+	return fields, scanner.Err()
+}
+
+func parseWHOISDate(value string) (time.Time, error) {
+	for _, layout := range whoisDateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognised WHOIS date %q", value)
+}
+
+func RecordRegistration(db *sqlx.DB, registration *Registration) error {
+	_, err := db.Exec(`
+		INSERT INTO domain_registration (domain, registrar, created_at, expires_at, source, checked_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (domain) DO UPDATE
+		SET registrar = EXCLUDED.registrar, created_at = EXCLUDED.created_at, expires_at = EXCLUDED.expires_at,
+			source = EXCLUDED.source, checked_at = EXCLUDED.checked_at
+	`, registration.Domain, registration.Registrar, database.NullTime(registration.CreatedAt), registration.ExpiresAt, registration.Source, time.Now())
+	if err != nil {
+		log.Printf("Error recording registration for %s: %s", registration.Domain, err)
+		return err
+	}
+	return nil
+}
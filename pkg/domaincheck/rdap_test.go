@@ -0,0 +1,182 @@
+package domaincheck
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+)
+
+// newRDAPServer stands in for both the IANA bootstrap file and a registry's
+// RDAP server, which serves .test domains.
+func newRDAPServer(t *testing.T) *httptest.Server {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/dns.json":
+			fmt.Fprintf(w, `{"version": "1.0", "services": [[["test", "example"], ["%s/rdap/"]]]}`, server.URL)
+		case "/rdap/domain/swanchain.test":
+			io.WriteString(w, `{
+				"objectClassName": "domain",
+				"ldhName": "SWANCHAIN.TEST",
+				"events": [
+					{"eventAction": "registration", "eventDate": "2021-03-01T10:00:00Z"},
+					{"eventAction": "expiration", "eventDate": "2027-03-01T10:00:00Z"}
+				],
+				"entities": [
+					{"roles": ["registrant"], "vcardArray": ["vcard", [["fn", {}, "text", "REDACTED"]]]},
+					{"roles": ["registrar"], "vcardArray": ["vcard", [["version", {}, "text", "4.0"], ["fn", {}, "text", "Test Registrar, Inc."]]]}
+				]
+			}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// serveWHOIS answers every query with the response for the requested domain.
+func serveWHOIS(t *testing.T, responses map[string]string) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				query, _ := bufio.NewReader(conn).ReadString('\n')
+				io.WriteString(conn, responses[strings.TrimSpace(query)])
+			}()
+		}
+	}()
+
+	return listener.Addr().String()
+}
+
+func TestLookupRDAP(t *testing.T) {
+	server := newRDAPServer(t)
+	checker := NewRegistrationChecker()
+	checker.BootstrapURL = server.URL + "/dns.json"
+
+	registration, err := checker.Lookup("swanchain.test")
+	if err != nil {
+		t.Fatalf("Lookup() returned error: %v", err)
+	}
+
+	if registration.Source != SourceRDAP || registration.Registrar != "Test Registrar, Inc." {
+		t.Errorf("Unexpected registration: %+v", registration)
+	}
+	if !registration.ExpiresAt.Equal(time.Date(2027, 3, 1, 10, 0, 0, 0, time.UTC)) || registration.CreatedAt.Year() != 2021 {
+		t.Errorf("Unexpected dates: %+v", registration)
+	}
+}
+
+func TestLookupWHOISFallback(t *testing.T) {
+	server := newRDAPServer(t)
+	registry := serveWHOIS(t, map[string]string{
+		"swanchain.org": "% registry whois\r\n" +
+			"Domain Name: SWANCHAIN.ORG\r\n" +
+			"Registrar: Whois Registrar\r\n" +
+			"Creation Date: 2020-05-04T00:00:00Z\r\n" +
+			"Registry Expiry Date: 2026-11-20T08:00:00Z\r\n" +
+			"Registrar Registration Expiration Date: 2030-01-01\r\n",
+	})
+	iana := serveWHOIS(t, map[string]string{
+		"swanchain.org":  "% IANA WHOIS server\r\nrefer:        " + registry + "\r\n\r\ndomain:       ORG\r\n",
+		"swanchain.test": "% IANA WHOIS server\r\n",
+	})
+
+	checker := NewRegistrationChecker()
+	checker.BootstrapURL = server.URL + "/dns.json"
+	checker.WhoisServer = iana
+
+	registration, err := checker.Lookup("swanchain.org")
+	if err != nil {
+		t.Fatalf("Lookup() returned error: %v", err)
+	}
+	if registration.Source != SourceWHOIS || registration.Registrar != "Whois Registrar" {
+		t.Errorf("Unexpected registration: %+v", registration)
+	}
+	if !registration.ExpiresAt.Equal(time.Date(2026, 11, 20, 8, 0, 0, 0, time.UTC)) || registration.CreatedAt.Year() != 2020 {
+		t.Errorf("Unexpected dates: %+v", registration)
+	}
+
+	// The RDAP server does not know this domain and WHOIS has no expiry.
+	if _, err := checker.Lookup("missing.example"); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("Unexpected error for an unknown domain: %v", err)
+	}
+}
+
+func TestRegistrationExpiryMessage(t *testing.T) {
+	now := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	windows := []time.Duration{60 * 24 * time.Hour, 30 * 24 * time.Hour}
+	registration := &Registration{Domain: "swanchain.test", Registrar: "Test Registrar"}
+
+	registration.ExpiresAt = now.Add(90 * 24 * time.Hour)
+	if message, ok := registration.ExpiryMessage(now, windows); ok {
+		t.Errorf("Unexpected warning outside the windows: %s", message)
+	}
+
+	registration.ExpiresAt = now.Add(20 * 24 * time.Hour)
+	if message, ok := registration.ExpiryMessage(now, windows); !ok || !strings.Contains(message, "30 day warning") {
+		t.Errorf("Unexpected warning: %s", message)
+	}
+
+	registration.ExpiresAt = now.Add(-time.Hour)
+	if message, ok := registration.ExpiryMessage(now, windows); !ok || !strings.Contains(message, "expired on") {
+		t.Errorf("Unexpected warning for a lapsed domain: %s", message)
+	}
+}
+
+func TestRegistrableDomain(t *testing.T) {
+	tests := map[string]string{
+		"https://api.swanchain.io/":    "swanchain.io",
+		"smtp://mail.swanchain.io:587": "swanchain.io",
+		"www.swanchain.co.uk":          "swanchain.co.uk",
+		"SwanChain.io":                 "swanchain.io",
+	}
+	for domain, want := range tests {
+		if got, err := RegistrableDomain(domain); err != nil || got != want {
+			t.Errorf("RegistrableDomain(%q) = %q, %v, want %q", domain, got, err, want)
+		}
+	}
+}
+
+func TestRecordRegistration(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+	expiresAt := time.Date(2027, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	mock.ExpectExec("INSERT INTO domain_registration").
+		WithArgs("swanchain.test", "Test Registrar", nil, expiresAt, SourceRDAP, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	err = RecordRegistration(sqlxDB, &Registration{Domain: "swanchain.test", Registrar: "Test Registrar", ExpiresAt: expiresAt, Source: SourceRDAP})
+	if err != nil {
+		t.Errorf("RecordRegistration() returned error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unmet expectations: %v", err)
+	}
+}
@@ -0,0 +1,99 @@
+package notify
+
+import (
+	"log"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/swanchain/domain-check/pkg/alert"
+	"github.com/swanchain/domain-check/pkg/model"
+)
+
+// Discord limits the length of embed titles and descriptions, and the
+// number and length of embed fields.
+const (
+	discordTitleLimit       = 256
+	discordDescriptionLimit = 4096
+	discordFieldsLimit      = 25
+	discordFieldNameLimit   = 256
+	discordFieldValueLimit  = 1024
+)
+
+// GetDiscordWebhook returns the default webhook URL from the info row of
+// type 'discord' with key webhook, or "" if there is none.
+func GetDiscordWebhook(db *sqlx.DB) (string, error) {
+	var configs []model.Info
+	err := db.Select(&configs, "SELECT key, value FROM info WHERE is_active = true AND type = 'discord' AND key = 'webhook'")
+	if err != nil || len(configs) == 0 {
+		return "", err
+	}
+	return configs[0].Value, nil
+}
+
+type DiscordField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline,omitempty"`
+}
+
+type DiscordEmbed struct {
+	Title       string         `json:"title"`
+	Description string         `json:"description,omitempty"`
+	Color       int            `json:"color"`
+	Timestamp   string         `json:"timestamp,omitempty"`
+	Fields      []DiscordField `json:"fields,omitempty"`
+}
+
+type DiscordMessage struct {
+	Username string         `json:"username,omitempty"`
+	Embeds   []DiscordEmbed `json:"embeds"`
+}
+
+// NewDiscordMessage lays out a title and message as an embed. Discord embeds
+// render the same Markdown as the Teams messages.
+func NewDiscordMessage(title, message string, color int, at time.Time) DiscordMessage {
+	return DiscordMessage{Embeds: []DiscordEmbed{{
+		Title:       truncate(title, discordTitleLimit),
+		Description: truncate(message, discordDescriptionLimit),
+		Color:       color,
+		Timestamp:   at.UTC().Format(time.RFC3339),
+	}}}
+}
+
+// AddFacts adds the facts, up to Discord's limit, as inline fields of the
+// embed.
+func (m *DiscordMessage) AddFacts(facts []alert.Fact) {
+	for _, fact := range facts[:min(len(facts), discordFieldsLimit)] {
+		m.Embeds[0].Fields = append(m.Embeds[0].Fields, DiscordField{
+			Name:   truncate(fact.Title, discordFieldNameLimit),
+			Value:  truncate(fact.Value, discordFieldValueLimit),
+			Inline: true,
+		})
+	}
+}
+
+// Discord posts to Discord webhooks.
+type Discord struct {
+	sender
+}
+
+func NewDiscord() *Discord {
+	return &Discord{sender: newSender()}
+}
+
+func (d *Discord) Send(webhookURL string, msg DiscordMessage) error {
+	_, err := d.postJSON("Discord", webhookURL, nil, msg)
+	return err
+}
+
+// SendDiscordAlert posts an alert notification as an embed colored by its
+// severity, or green once it has resolved.
+func SendDiscordAlert(discord *Discord, webhookURL string, notification alert.Notification) error {
+	msg := NewDiscordMessage(notification.Title(), notification.Text(), color(notification), time.Now())
+	msg.AddFacts(notification.State.Facts)
+	if err := discord.Send(webhookURL, msg); err != nil {
+		log.Printf("Error posting %s to Discord: %s", notification.Title(), err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package notify
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/swanchain/domain-check/pkg/alert"
+)
+
+func TestColor(t *testing.T) {
+	critical := alert.State{Severity: alert.SeverityCritical, State: alert.StateFiring}
+	warning := alert.State{Severity: alert.SeverityWarning, State: alert.StateFiring}
+	resolved := alert.State{Severity: alert.SeverityCritical, State: alert.StateResolved}
+
+	tests := []struct {
+		notification alert.Notification
+		want         int
+	}{
+		{alert.Notification{Kind: alert.KindFiring, State: critical}, colorCritical},
+		{alert.Notification{Kind: alert.KindEscalated, State: warning}, colorWarning},
+		{alert.Notification{Kind: alert.KindResolved, State: resolved}, colorResolved},
+		{alert.Notification{Kind: alert.KindFlapping, State: critical}, colorFlapping},
+		{alert.Notification{Kind: alert.KindFlapStopped, State: critical}, colorCritical},
+		{alert.Notification{Kind: alert.KindFlapStopped, State: alert.State{State: alert.StateOK}}, colorResolved},
+	}
+	for _, tt := range tests {
+		if got := color(tt.notification); got != tt.want {
+			t.Errorf("color(%s, %s) = %#x, want %#x", tt.notification.Kind, tt.notification.State.Severity, got, tt.want)
+		}
+	}
+}
+
+func TestDiscordAddFacts(t *testing.T) {
+	facts := make([]alert.Fact, 30)
+	for i := range facts {
+		facts[i] = alert.Fact{Title: "0x1234 (swan)", Value: strings.Repeat("1", 2000)}
+	}
+	msg := NewDiscordMessage("Wallet Balance Change Update", "Balances of 30 wallets.", colorWarning, time.Now())
+	msg.AddFacts(facts)
+	fields := msg.Embeds[0].Fields
+	if len(fields) != discordFieldsLimit || !fields[0].Inline || fields[0].Name != "0x1234 (swan)" || len(fields[0].Value) > discordFieldValueLimit {
+		t.Errorf("AddFacts() = %d fields, first %+v", len(fields), fields[0])
+	}
+}
+
+func TestSendDiscordAlert(t *testing.T) {
+	var requests []DiscordMessage
+	limited := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if limited {
+			limited = false
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"message":"You are being rate limited.","retry_after":0.25,"global":false}`))
+			return
+		}
+		var msg DiscordMessage
+		json.NewDecoder(r.Body).Decode(&msg)
+		requests = append(requests, msg)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	discord := NewDiscord()
+	var slept []time.Duration
+	discord.sleep = func(d time.Duration) { slept = append(slept, d) }
+
+	state := alert.State{Title: "Chain Status Warning", Message: strings.Repeat("less than 5 transactions ", 300),
+		Severity: alert.SeverityCritical, State: alert.StateFiring}
+	if err := SendDiscordAlert(discord, server.URL, alert.Notification{Kind: alert.KindFiring, State: state}); err != nil {
+		t.Fatalf("SendDiscordAlert() = %v", err)
+	}
+	if len(slept) != 1 || slept[0] != 250*time.Millisecond {
+		t.Errorf("Slept %v after being rate limited", slept)
+	}
+	if len(requests) != 1 || len(requests[0].Embeds) != 1 {
+		t.Fatalf("Unexpected requests %+v", requests)
+	}
+	embed := requests[0].Embeds[0]
+	if embed.Title != "[FIRING] Chain Status Warning" || embed.Color != colorCritical || len(embed.Description) > discordDescriptionLimit {
+		t.Errorf("Unexpected embed %q color %#x with %d bytes", embed.Title, embed.Color, len(embed.Description))
+	}
+	if _, err := time.Parse(time.RFC3339, embed.Timestamp); err != nil {
+		t.Errorf("Invalid timestamp %q", embed.Timestamp)
+	}
+
+	server.Close()
+	if err := SendDiscordAlert(discord, server.URL, alert.Notification{Kind: alert.KindFiring, State: state}); err == nil {
+		t.Errorf("SendDiscordAlert() succeeded without a server")
+	}
+}
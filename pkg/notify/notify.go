@@ -0,0 +1,184 @@
+// Package notify sends alerts to services other than Teams: the Slack,
+// Discord and Telegram chats, the PagerDuty and Opsgenie incident services
+// and generic webhooks.
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/swanchain/domain-check/pkg/alert"
+)
+
+// sender posts JSON requests, retrying rate limited ones after the delay
+// the service asks for, up to MaxRetries times. With RetryFailures it also
+// retries server errors and failed connections.
+type sender struct {
+	Client        *http.Client
+	MaxRetries    int
+	RetryFailures bool
+	sleep         func(time.Duration)
+}
+
+func newSender() sender {
+	return sender{Client: &http.Client{Timeout: 30 * time.Second}, MaxRetries: 3, sleep: time.Sleep}
+}
+
+// postJSON posts body and returns the response body of a 2xx response.
+func (s sender) postJSON(service, url string, header http.Header, body interface{}) ([]byte, error) {
+	msgBytes, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	return s.post(service, url, header, msgBytes)
+}
+
+// post posts msgBytes, which is JSON, as postJSON does.
+func (s sender) post(service, url string, header http.Header, msgBytes []byte) ([]byte, error) {
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(msgBytes))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json; charset=utf-8")
+		for key, values := range header {
+			req.Header[key] = values
+		}
+
+		resp, err := s.Client.Do(req)
+		if err != nil {
+			if s.RetryFailures && attempt < s.MaxRetries {
+				s.backoff(service, attempt, fmt.Sprintf("request failed: %s", err))
+				continue
+			}
+			return nil, err
+		}
+		respBody, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case resp.StatusCode == http.StatusTooManyRequests && attempt < s.MaxRetries:
+			delay := retryAfter(resp.Header, respBody)
+			log.Printf("%s rate limited, retrying in %s", service, delay)
+			s.sleep(delay)
+		case resp.StatusCode >= 500 && s.RetryFailures && attempt < s.MaxRetries:
+			s.backoff(service, attempt, "returned "+resp.Status)
+		case resp.StatusCode < 200 || resp.StatusCode > 299:
+			return nil, fmt.Errorf("%s returned %s: %s", service, resp.Status, respBody)
+		default:
+			return respBody, nil
+		}
+	}
+}
+
+// backoff waits before retrying a failed request: a second after the first
+// attempt and twice as long after each further one.
+func (s sender) backoff(service string, attempt int, reason string) {
+	delay := time.Second << attempt
+	log.Printf("%s %s, retrying in %s", service, reason, delay)
+	s.sleep(delay)
+}
+
+// retryAfter reads the delay from the Retry-After header, or from the
+// retry_after field Discord and Telegram also return, defaulting to a
+// second.
+func retryAfter(header http.Header, body []byte) time.Duration {
+	if seconds, err := strconv.ParseFloat(header.Get("Retry-After"), 64); err == nil && seconds > 0 {
+		return time.Duration(seconds * float64(time.Second))
+	}
+
+	var response struct {
+		RetryAfter float64 `json:"retry_after"`
+		Parameters struct {
+			RetryAfter float64 `json:"retry_after"`
+		} `json:"parameters"`
+	}
+	json.Unmarshal(body, &response)
+	if seconds := max(response.RetryAfter, response.Parameters.RetryAfter); seconds > 0 {
+		return time.Duration(seconds * float64(time.Second))
+	}
+	return time.Second
+}
+
+// Embed colors by what a notification says.
+const (
+	colorCritical = 0xD32F2F
+	colorWarning  = 0xF9A825
+	colorResolved = 0x2E7D32
+	colorFlapping = 0x757575
+)
+
+// color returns the color of a notification: green once resolved, grey while
+// flapping and otherwise by severity.
+func color(notification alert.Notification) int {
+	switch {
+	case notification.Resolved():
+		return colorResolved
+	case notification.Kind == alert.KindFlapping:
+		return colorFlapping
+	case notification.State.Severity == alert.SeverityCritical:
+		return colorCritical
+	default:
+		return colorWarning
+	}
+}
+
+// dedupKey identifies an alert's incident in PagerDuty and Opsgenie. It stays
+// the same while the alert fires again, so the incident follows the alert.
+func dedupKey(state alert.State) string {
+	return state.Check + "-" + state.Fingerprint
+}
+
+// source names what an alert is about for the incident services.
+func source(state alert.State) string {
+	for _, source := range []string{state.Domain, state.Wallet, state.Network} {
+		if source != "" {
+			return source
+		}
+	}
+	return "domain-check"
+}
+
+func truncate(text string, limit int) string {
+	if len(text) <= limit {
+		return text
+	}
+	cut := limit - len("…")
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	return text[:cut] + "…"
+}
+
+// splitText splits text into chunks of at most limit bytes, preferring to
+// break between lines.
+func splitText(text string, limit int) []string {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil
+	}
+	var chunks []string
+	for len(text) > limit {
+		cut := strings.LastIndex(text[:limit], "\n")
+		if cut <= 0 {
+			cut = limit
+			for cut > 0 && !utf8.RuneStart(text[cut]) {
+				cut--
+			}
+		}
+		chunks = append(chunks, strings.TrimSpace(text[:cut]))
+		text = strings.TrimSpace(text[cut:])
+	}
+	return append(chunks, text)
+}
@@ -0,0 +1,150 @@
+package notify
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/swanchain/domain-check/pkg/alert"
+	"github.com/swanchain/domain-check/pkg/model"
+)
+
+// Opsgenie limits the length of alert messages and descriptions.
+const (
+	opsgenieMessageLimit     = 130
+	opsgenieDescriptionLimit = 15000
+)
+
+// OpsgenieConfig is read from info rows of type 'opsgenie': the API key of
+// the default integration, which may instead be set in OPSGENIE_API_KEY, and
+// api-url for accounts in the EU region.
+type OpsgenieConfig struct {
+	APIKey string
+	APIURL string
+}
+
+func GetOpsgenieConfig(db *sqlx.DB) (OpsgenieConfig, error) {
+	cfg := OpsgenieConfig{APIKey: os.Getenv("OPSGENIE_API_KEY"), APIURL: "https://api.opsgenie.com"}
+
+	var configs []model.Info
+	err := db.Select(&configs, "SELECT key, value FROM info WHERE is_active = true AND type = 'opsgenie'")
+	if err != nil {
+		return cfg, err
+	}
+
+	for _, config := range configs {
+		switch config.Key {
+		case "api-key":
+			cfg.APIKey = config.Value
+		case "api-url":
+			cfg.APIURL = config.Value
+		}
+	}
+	return cfg, nil
+}
+
+// OpsgenieAlert is the body of a create alert request.
+type OpsgenieAlert struct {
+	Message     string            `json:"message"`
+	Alias       string            `json:"alias"`
+	Description string            `json:"description,omitempty"`
+	Priority    string            `json:"priority"`
+	Source      string            `json:"source"`
+	Entity      string            `json:"entity,omitempty"`
+	Tags        []string          `json:"tags,omitempty"`
+	Details     map[string]string `json:"details,omitempty"`
+}
+
+// opsgenieAction is the body of acknowledge and close requests.
+type opsgenieAction struct {
+	Source string `json:"source"`
+	User   string `json:"user,omitempty"`
+	Note   string `json:"note,omitempty"`
+}
+
+// NewOpsgenieAlert lays out an alert for Opsgenie: critical alerts are P1
+// and the others P3.
+func NewOpsgenieAlert(notification alert.Notification) OpsgenieAlert {
+	state := notification.State
+	priority := "P3"
+	if state.Severity == alert.SeverityCritical {
+		priority = "P1"
+	}
+	tags := []string{state.Check, state.Severity}
+	if state.Network != "" {
+		tags = append(tags, state.Network)
+	}
+	return OpsgenieAlert{
+		Message:     truncate(state.Title, opsgenieMessageLimit),
+		Alias:       dedupKey(state),
+		Description: truncate(notification.Text(), opsgenieDescriptionLimit),
+		Priority:    priority,
+		Source:      "domain-check",
+		Entity:      source(state),
+		Tags:        tags,
+		Details:     map[string]string{"fingerprint": state.Fingerprint, "kind": notification.Kind},
+	}
+}
+
+// Opsgenie creates, acknowledges and closes alerts with the Opsgenie Alert
+// API.
+type Opsgenie struct {
+	sender
+	APIURL string
+	APIKey string
+}
+
+func NewOpsgenie(cfg OpsgenieConfig) *Opsgenie {
+	return &Opsgenie{sender: newSender(), APIURL: cfg.APIURL, APIKey: cfg.APIKey}
+}
+
+func (o *Opsgenie) post(path string, body interface{}) error {
+	if o.APIKey == "" {
+		return errors.New("sending to Opsgenie needs an API key")
+	}
+	_, err := o.postJSON("Opsgenie", o.APIURL+path, http.Header{"Authorization": {"GenieKey " + o.APIKey}}, body)
+	return err
+}
+
+// Create opens an alert. Opsgenie adds to the count of an open alert with
+// the same alias instead of opening another.
+func (o *Opsgenie) Create(a OpsgenieAlert) error {
+	return o.post("/v2/alerts", a)
+}
+
+func (o *Opsgenie) Acknowledge(alias, user, note string) error {
+	return o.post("/v2/alerts/"+url.PathEscape(alias)+"/acknowledge?identifierType=alias",
+		opsgenieAction{Source: "domain-check", User: user, Note: note})
+}
+
+func (o *Opsgenie) Close(alias, note string) error {
+	return o.post("/v2/alerts/"+url.PathEscape(alias)+"/close?identifierType=alias",
+		opsgenieAction{Source: "domain-check", Note: note})
+}
+
+// SendOpsgenieAlert creates, acknowledges or closes the Opsgenie alert of an
+// alert, in line with the notification. Events are not sent, since they
+// never close the alerts they would open.
+func SendOpsgenieAlert(opsgenie *Opsgenie, notification alert.Notification) error {
+	if notification.Kind == alert.KindEvent {
+		return nil
+	}
+	alias := dedupKey(notification.State)
+	var err error
+	switch {
+	case notification.Kind == alert.KindAcknowledged:
+		err = opsgenie.Acknowledge(alias, notification.State.AckedBy, notification.Text())
+	case notification.Resolved():
+		err = opsgenie.Close(alias, notification.Text())
+	default:
+		err = opsgenie.Create(NewOpsgenieAlert(notification))
+	}
+	if err != nil {
+		log.Printf("Error sending %s to Opsgenie: %s", notification.Title(), err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package notify
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/swanchain/domain-check/pkg/alert"
+)
+
+func TestSendOpsgenieAlert(t *testing.T) {
+	type request struct {
+		URI  string
+		Body map[string]interface{}
+	}
+	var requests []request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "GenieKey api-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"message":"Key format is not valid!","took":0.001,"requestId":"1"}`))
+			return
+		}
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		requests = append(requests, request{r.URL.RequestURI(), body})
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte(`{"result":"Request will be processed","took":0.1,"requestId":"2"}`))
+	}))
+	defer server.Close()
+
+	opsgenie := NewOpsgenie(OpsgenieConfig{APIKey: "api-key", APIURL: server.URL})
+
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	state := alert.State{Fingerprint: "0f1e", Check: "ssl", Key: "swanchain.io", Title: "SSL Certificate Expiring",
+		Message: "expires in 3 days", Severity: alert.SeverityWarning, Domain: "swanchain.io", State: alert.StateFiring, FirstSeen: now}
+	acked := state
+	acked.AckedAt, acked.AckedBy = &now, "alice"
+	resolved := state
+	resolved.State, resolved.ResolvedAt = alert.StateResolved, &now
+
+	for _, notification := range []alert.Notification{
+		{Kind: alert.KindFiring, State: state},
+		{Kind: alert.KindAcknowledged, State: acked},
+		{Kind: alert.KindResolved, State: resolved},
+		{Kind: alert.KindEvent, State: state},
+	} {
+		if err := SendOpsgenieAlert(opsgenie, notification); err != nil {
+			t.Fatalf("SendOpsgenieAlert(%s) = %v", notification.Kind, err)
+		}
+	}
+
+	uris := []string{"/v2/alerts", "/v2/alerts/ssl-0f1e/acknowledge?identifierType=alias", "/v2/alerts/ssl-0f1e/close?identifierType=alias"}
+	if len(requests) != len(uris) {
+		t.Fatalf("Sent %d requests, want %d", len(requests), len(uris))
+	}
+	for i, r := range requests {
+		if r.URI != uris[i] {
+			t.Errorf("Request %d to %s, want %s", i, r.URI, uris[i])
+		}
+	}
+	created := requests[0].Body
+	if created["alias"] != "ssl-0f1e" || created["priority"] != "P3" || created["message"] != "SSL Certificate Expiring" ||
+		created["entity"] != "swanchain.io" {
+		t.Errorf("Unexpected alert %+v", created)
+	}
+	if requests[1].Body["user"] != "alice" {
+		t.Errorf("Unexpected acknowledgement %+v", requests[1].Body)
+	}
+
+	state.Severity = alert.SeverityCritical
+	if a := NewOpsgenieAlert(alert.Notification{Kind: alert.KindFiring, State: state}); a.Priority != "P1" {
+		t.Errorf("NewOpsgenieAlert() priority = %s for a critical alert", a.Priority)
+	}
+
+	opsgenie.APIKey = "other-key"
+	if err := SendOpsgenieAlert(opsgenie, alert.Notification{Kind: alert.KindFiring, State: state}); err == nil {
+		t.Errorf("SendOpsgenieAlert() succeeded with an invalid API key")
+	}
+	opsgenie.APIKey = ""
+	if err := SendOpsgenieAlert(opsgenie, alert.Notification{Kind: alert.KindFiring, State: state}); err == nil {
+		t.Errorf("SendOpsgenieAlert() succeeded without an API key")
+	}
+}
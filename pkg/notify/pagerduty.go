@@ -0,0 +1,110 @@
+package notify
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/swanchain/domain-check/pkg/alert"
+	"github.com/swanchain/domain-check/pkg/model"
+)
+
+// pagerDutySummaryLimit is the longest summary the Events API accepts.
+const pagerDutySummaryLimit = 1024
+
+// GetPagerDutyRoutingKey returns the default integration key from the info
+// row of type 'pagerduty' with key routing-key, or PAGERDUTY_ROUTING_KEY.
+func GetPagerDutyRoutingKey(db *sqlx.DB) (string, error) {
+	var configs []model.Info
+	err := db.Select(&configs, "SELECT key, value FROM info WHERE is_active = true AND type = 'pagerduty' AND key = 'routing-key'")
+	if err != nil || len(configs) == 0 {
+		return os.Getenv("PAGERDUTY_ROUTING_KEY"), err
+	}
+	return configs[0].Value, nil
+}
+
+type PagerDutyPayload struct {
+	Summary       string            `json:"summary"`
+	Source        string            `json:"source"`
+	Severity      string            `json:"severity"`
+	Group         string            `json:"group,omitempty"`
+	Class         string            `json:"class,omitempty"`
+	CustomDetails map[string]string `json:"custom_details,omitempty"`
+}
+
+// PagerDutyEvent is an Events API v2 event. Only trigger events have a
+// payload.
+type PagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"`
+	DedupKey    string            `json:"dedup_key"`
+	Payload     *PagerDutyPayload `json:"payload,omitempty"`
+}
+
+// NewPagerDutyEvent returns the event that brings the incident of an alert
+// in line with a notification: acknowledged, resolved or otherwise
+// triggered. Triggering an open incident again only adds to its log.
+func NewPagerDutyEvent(routingKey string, notification alert.Notification) PagerDutyEvent {
+	state := notification.State
+	event := PagerDutyEvent{RoutingKey: routingKey, DedupKey: dedupKey(state)}
+	switch {
+	case notification.Kind == alert.KindAcknowledged:
+		event.EventAction = "acknowledge"
+	case notification.Resolved():
+		event.EventAction = "resolve"
+	default:
+		event.EventAction = "trigger"
+		severity := "warning"
+		if state.Severity == alert.SeverityCritical {
+			severity = "critical"
+		}
+		event.Payload = &PagerDutyPayload{
+			Summary:  truncate(notification.Title(), pagerDutySummaryLimit),
+			Source:   source(state),
+			Severity: severity,
+			Group:    state.Network,
+			Class:    state.Check,
+			CustomDetails: map[string]string{
+				"message":     state.Message,
+				"fingerprint": state.Fingerprint,
+				"first_seen":  state.FirstSeen.UTC().Format(time.RFC3339),
+			},
+		}
+	}
+	return event
+}
+
+// PagerDuty sends events to the PagerDuty Events API v2.
+type PagerDuty struct {
+	sender
+	URL string
+}
+
+func NewPagerDuty() *PagerDuty {
+	return &PagerDuty{sender: newSender(), URL: "https://events.pagerduty.com/v2/enqueue"}
+}
+
+func (p *PagerDuty) Send(event PagerDutyEvent) error {
+	if event.RoutingKey == "" {
+		return fmt.Errorf("sending %s to PagerDuty needs a routing key", event.DedupKey)
+	}
+	_, err := p.postJSON("PagerDuty", p.URL, nil, event)
+	return err
+}
+
+// SendPagerDutyAlert triggers, acknowledges or resolves the incident of an
+// alert on the service of routingKey. Events are not sent, since they never
+// resolve the incidents they would open.
+func SendPagerDutyAlert(pagerDuty *PagerDuty, routingKey string, notification alert.Notification) error {
+	if notification.Kind == alert.KindEvent {
+		return nil
+	}
+	event := NewPagerDutyEvent(routingKey, notification)
+	if err := pagerDuty.Send(event); err != nil {
+		log.Printf("Error sending %s event for %s to PagerDuty: %s", event.EventAction, notification.State.Title, err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package notify
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/swanchain/domain-check/pkg/alert"
+)
+
+func TestSendPagerDutyAlert(t *testing.T) {
+	var events []PagerDutyEvent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event PagerDutyEvent
+		json.NewDecoder(r.Body).Decode(&event)
+		if event.RoutingKey != "routing-key" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"status":"invalid event","message":"Event object is invalid","errors":["Invalid routing key"]}`))
+			return
+		}
+		events = append(events, event)
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte(`{"status":"success","message":"Event processed","dedup_key":"` + event.DedupKey + `"}`))
+	}))
+	defer server.Close()
+
+	pagerDuty := NewPagerDuty()
+	pagerDuty.URL = server.URL
+
+	chain := alert.Alert{Check: "chain-status", Key: "swan", Title: "Chain Status Warning", Message: "rpc timeout",
+		Severity: alert.SeverityCritical, Network: "swan"}
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	state := alert.State{Fingerprint: chain.Fingerprint(), Check: chain.Check, Key: chain.Key, Title: chain.Title,
+		Message: chain.Message, Severity: chain.Severity, Network: chain.Network, State: alert.StateFiring, FirstSeen: now}
+	acked := state
+	acked.AckedAt, acked.AckedBy = &now, "alice"
+	resolved := state
+	resolved.State, resolved.ResolvedAt = alert.StateResolved, &now
+
+	for _, notification := range []alert.Notification{
+		{Kind: alert.KindFiring, State: state},
+		{Kind: alert.KindEscalated, State: state},
+		{Kind: alert.KindAcknowledged, State: acked},
+		{Kind: alert.KindResolved, State: resolved},
+		{Kind: alert.KindFlapStopped, State: resolved},
+		{Kind: alert.KindEvent, State: state},
+	} {
+		if err := SendPagerDutyAlert(pagerDuty, "routing-key", notification); err != nil {
+			t.Fatalf("SendPagerDutyAlert(%s) = %v", notification.Kind, err)
+		}
+	}
+
+	actions := []string{"trigger", "trigger", "acknowledge", "resolve", "resolve"}
+	if len(events) != len(actions) {
+		t.Fatalf("Sent %d events, want %d", len(events), len(actions))
+	}
+	for i, event := range events {
+		if event.EventAction != actions[i] || event.DedupKey != "chain-status-"+chain.Fingerprint() {
+			t.Errorf("Event %d is %s with dedup key %s", i, event.EventAction, event.DedupKey)
+		}
+		if (event.Payload != nil) != (event.EventAction == "trigger") {
+			t.Errorf("Event %d is %s with payload %+v", i, event.EventAction, event.Payload)
+		}
+	}
+	payload := events[0].Payload
+	if payload.Summary != "[FIRING] Chain Status Warning" || payload.Severity != "critical" || payload.Source != "swan" ||
+		payload.Class != "chain-status" || payload.CustomDetails["message"] != "rpc timeout" {
+		t.Errorf("Unexpected payload %+v", payload)
+	}
+
+	notification := alert.Notification{Kind: alert.KindFiring, State: state}
+	if err := SendPagerDutyAlert(pagerDuty, "other-key", notification); err == nil {
+		t.Errorf("SendPagerDutyAlert() succeeded with an invalid routing key")
+	}
+	if err := SendPagerDutyAlert(pagerDuty, "", notification); err == nil {
+		t.Errorf("SendPagerDutyAlert() succeeded without a routing key")
+	}
+}
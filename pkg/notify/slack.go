@@ -0,0 +1,226 @@
+package notify
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/swanchain/domain-check/pkg/alert"
+	"github.com/swanchain/domain-check/pkg/model"
+)
+
+// Slack limits the length of header and section texts, and the number and
+// length of section fields.
+const (
+	slackHeaderLimit  = 150
+	slackSectionLimit = 3000
+	slackFieldsLimit  = 10
+	slackFieldLimit   = 2000
+)
+
+// SlackConfig is read from info rows of type 'slack'. webhook is an incoming
+// webhook URL; token and channel post with chat.postMessage instead, which
+// is needed to thread follow-ups under an alert. The token may also be set in
+// SLACK_BOT_TOKEN.
+type SlackConfig struct {
+	WebhookURL string
+	Token      string
+	Channel    string
+}
+
+func GetSlackConfig(db *sqlx.DB) (SlackConfig, error) {
+	cfg := SlackConfig{Token: os.Getenv("SLACK_BOT_TOKEN")}
+
+	var configs []model.Info
+	err := db.Select(&configs, "SELECT key, value FROM info WHERE is_active = true AND type = 'slack'")
+	if err != nil {
+		return cfg, err
+	}
+
+	for _, config := range configs {
+		switch config.Key {
+		case "webhook":
+			cfg.WebhookURL = config.Value
+		case "token":
+			cfg.Token = config.Value
+		case "channel":
+			cfg.Channel = config.Value
+		}
+	}
+	return cfg, nil
+}
+
+// Destination returns the default channel, or the webhook URL without one.
+// It is empty if Slack is not configured.
+func (c SlackConfig) Destination() string {
+	if c.Token != "" && c.Channel != "" {
+		return c.Channel
+	}
+	return c.WebhookURL
+}
+
+type SlackText struct {
+	Type  string `json:"type"`
+	Text  string `json:"text"`
+	Emoji bool   `json:"emoji,omitempty"`
+}
+
+type SlackBlock struct {
+	Type   string      `json:"type"`
+	Text   *SlackText  `json:"text,omitempty"`
+	Fields []SlackText `json:"fields,omitempty"`
+}
+
+type SlackMessage struct {
+	Channel        string       `json:"channel,omitempty"`
+	Text           string       `json:"text"`
+	Blocks         []SlackBlock `json:"blocks,omitempty"`
+	ThreadTS       string       `json:"thread_ts,omitempty"`
+	ReplyBroadcast bool         `json:"reply_broadcast,omitempty"`
+}
+
+// NewSlackMessage lays out a title and message as the Teams cards do: the
+// title as a header and the message below it, converted from the Markdown
+// the Teams messages use to Slack mrkdwn. Text is the notification fallback.
+func NewSlackMessage(title, message string, isMarkdown bool) SlackMessage {
+	msg := SlackMessage{Text: title}
+	msg.Blocks = append(msg.Blocks, SlackBlock{
+		Type: "header",
+		Text: &SlackText{Type: "plain_text", Text: truncate(title, slackHeaderLimit), Emoji: true},
+	})
+
+	textType := "plain_text"
+	if isMarkdown {
+		textType = "mrkdwn"
+		message = slackMarkdown(message)
+	}
+	for _, chunk := range splitText(message, slackSectionLimit) {
+		msg.Blocks = append(msg.Blocks, SlackBlock{Type: "section", Text: &SlackText{Type: textType, Text: chunk}})
+	}
+	return msg
+}
+
+// AddFacts adds the facts as section fields, each with its title in bold.
+func (m *SlackMessage) AddFacts(facts []alert.Fact) {
+	for start := 0; start < len(facts); start += slackFieldsLimit {
+		block := SlackBlock{Type: "section"}
+		for _, fact := range facts[start:min(start+slackFieldsLimit, len(facts))] {
+			block.Fields = append(block.Fields, SlackText{Type: "mrkdwn", Text: truncate("*"+fact.Title+"*\n"+fact.Value, slackFieldLimit)})
+		}
+		m.Blocks = append(m.Blocks, block)
+	}
+}
+
+var markdownLink = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+
+// slackMarkdown converts bold text and links from Markdown to mrkdwn.
+func slackMarkdown(text string) string {
+	text = markdownLink.ReplaceAllString(text, "<$2|$1>")
+	return strings.ReplaceAll(text, "**", "*")
+}
+
+// Slack posts messages to incoming webhooks or with chat.postMessage.
+type Slack struct {
+	sender
+	APIURL string
+	Token  string
+}
+
+func NewSlack(token string) *Slack {
+	return &Slack{sender: newSender(), APIURL: "https://slack.com/api", Token: token}
+}
+
+// Post sends msg to destination, an incoming webhook URL or a channel. For a
+// channel it returns the timestamp of the message, which threads replies.
+func (s *Slack) Post(destination string, msg SlackMessage) (string, error) {
+	if strings.HasPrefix(destination, "https://") || strings.HasPrefix(destination, "http://") {
+		msg.Channel, msg.ThreadTS, msg.ReplyBroadcast = "", "", false
+		_, err := s.postJSON("Slack", destination, nil, msg)
+		return "", err
+	}
+
+	if s.Token == "" {
+		return "", fmt.Errorf("posting to Slack channel %s needs a token", destination)
+	}
+	msg.Channel = destination
+	body, err := s.postJSON("Slack", s.APIURL+"/chat.postMessage", http.Header{"Authorization": {"Bearer " + s.Token}}, msg)
+	if err != nil {
+		return "", err
+	}
+
+	var response struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+		TS    string `json:"ts"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return "", fmt.Errorf("slack chat.postMessage returned %q", body)
+	}
+	if !response.OK {
+		return "", fmt.Errorf("slack chat.postMessage error: %s", response.Error)
+	}
+	return response.TS, nil
+}
+
+// SendSlackAlert posts an alert notification. The message an alert fires
+// with starts a thread on a channel; repeats, escalations and flapping are
+// posted in it, and the resolution is also shown in the channel. Webhooks
+// cannot thread, so every notification is a new message there.
+func SendSlackAlert(db *sqlx.DB, slack *Slack, destination string, notification alert.Notification) error {
+	msg := NewSlackMessage(notification.Title(), notification.Text(), true)
+	msg.AddFacts(notification.State.Facts)
+	fingerprint := notification.State.Fingerprint
+
+	if notification.Kind != alert.KindFiring {
+		ts, err := getSlackThread(db, fingerprint, destination)
+		if err != nil {
+			return err
+		}
+		msg.ThreadTS = ts
+		msg.ReplyBroadcast = ts != "" && notification.Kind == alert.KindResolved
+	}
+
+	ts, err := slack.Post(destination, msg)
+	if err != nil {
+		log.Printf("Error posting %s to Slack %s: %s", notification.Title(), destination, err)
+		return err
+	}
+	if notification.Kind == alert.KindFiring && ts != "" {
+		return setSlackThread(db, fingerprint, destination, ts)
+	}
+	return nil
+}
+
+func getSlackThread(db *sqlx.DB, fingerprint, channel string) (string, error) {
+	var ts string
+	err := db.Get(&ts, "SELECT ts FROM slack_thread WHERE fingerprint = $1 AND channel = $2", fingerprint, channel)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		log.Printf("Error retrieving Slack thread for %s: %s", fingerprint, err)
+		return "", err
+	}
+	return ts, nil
+}
+
+func setSlackThread(db *sqlx.DB, fingerprint, channel, ts string) error {
+	_, err := db.Exec(`
+		INSERT INTO slack_thread (fingerprint, channel, ts, posted_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (fingerprint, channel) DO UPDATE
+		SET ts = EXCLUDED.ts, posted_at = EXCLUDED.posted_at
+	`, fingerprint, channel, ts, time.Now())
+	if err != nil {
+		log.Printf("Error recording Slack thread for %s: %s", fingerprint, err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/swanchain/domain-check/pkg/alert"
+)
+
+func TestNewSlackMessage(t *testing.T) {
+	msg := NewSlackMessage("Wallet Balance Change Update", "**0xabc** is low, see [explorer](https://explorer.swanchain.io/address/0xabc)", true)
+	if msg.Text != "Wallet Balance Change Update" || len(msg.Blocks) != 2 {
+		t.Fatalf("NewSlackMessage() = %+v", msg)
+	}
+	if msg.Blocks[0].Type != "header" || msg.Blocks[0].Text.Type != "plain_text" {
+		t.Errorf("Unexpected header block %+v", msg.Blocks[0])
+	}
+	want := "*0xabc* is low, see <https://explorer.swanchain.io/address/0xabc|explorer>"
+	if msg.Blocks[1].Text.Type != "mrkdwn" || msg.Blocks[1].Text.Text != want {
+		t.Errorf("Section = %+v, want %q", msg.Blocks[1].Text, want)
+	}
+
+	long := strings.Repeat("SSL certificate for swanchain.io expires soon.\n", 100)
+	msg = NewSlackMessage(strings.Repeat("Title ", 40), long, false)
+	if len(msg.Blocks[0].Text.Text) > slackHeaderLimit || !strings.HasSuffix(msg.Blocks[0].Text.Text, "…") {
+		t.Errorf("Header not truncated: %q", msg.Blocks[0].Text.Text)
+	}
+	if len(msg.Blocks) != 3 {
+		t.Fatalf("Got %d blocks for a long message", len(msg.Blocks))
+	}
+	for _, block := range msg.Blocks[1:] {
+		if len(block.Text.Text) > slackSectionLimit || strings.HasPrefix(block.Text.Text, "\n") || block.Text.Type != "plain_text" {
+			t.Errorf("Unexpected section of %d bytes", len(block.Text.Text))
+		}
+	}
+}
+
+func TestSlackAddFacts(t *testing.T) {
+	var facts []alert.Fact
+	for i := 0; i < 12; i++ {
+		facts = append(facts, alert.Fact{Title: fmt.Sprintf("0x%04d (swan)", i), Value: "12.500000 (change -0.500000)"})
+	}
+	msg := NewSlackMessage("Wallet Balance Change Update", "Balances of 12 wallets.", true)
+	msg.AddFacts(facts)
+	if len(msg.Blocks) != 4 || len(msg.Blocks[2].Fields) != slackFieldsLimit || len(msg.Blocks[3].Fields) != 2 {
+		t.Fatalf("AddFacts() = %+v", msg.Blocks)
+	}
+	if field := msg.Blocks[2].Fields[0]; field.Type != "mrkdwn" || field.Text != "*0x0000 (swan)*\n12.500000 (change -0.500000)" {
+		t.Errorf("Unexpected field %+v", field)
+	}
+}
+
+func TestSlackPost(t *testing.T) {
+	var requests []SlackMessage
+	var auth []string
+	limited := 1
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg SlackMessage
+		json.NewDecoder(r.Body).Decode(&msg)
+		if limited > 0 {
+			limited--
+			w.Header().Set("Retry-After", "7")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		requests = append(requests, msg)
+		auth = append(auth, r.Header.Get("Authorization"))
+		switch {
+		case r.URL.Path == "/hook":
+			w.Write([]byte("ok"))
+		case msg.Channel == "C-missing":
+			w.Write([]byte(`{"ok":false,"error":"channel_not_found"}`))
+		default:
+			w.Write([]byte(`{"ok":true,"ts":"1727784000.000100"}`))
+		}
+	}))
+	defer server.Close()
+
+	slack := NewSlack("xoxb-test")
+	slack.APIURL = server.URL
+	var slept []time.Duration
+	slack.sleep = func(d time.Duration) { slept = append(slept, d) }
+
+	// A webhook cannot thread, and the first attempt is rate limited.
+	msg := NewSlackMessage("Chain Status Warning", "rpc timeout", true)
+	msg.ThreadTS = "1"
+	if ts, err := slack.Post(server.URL+"/hook", msg); err != nil || ts != "" {
+		t.Errorf("Post() to a webhook = %q, %v", ts, err)
+	}
+	if len(slept) != 1 || slept[0] != 7*time.Second {
+		t.Errorf("Slept %v after being rate limited", slept)
+	}
+	if requests[0].ThreadTS != "" || auth[0] != "" {
+		t.Errorf("Webhook request %+v with authorization %q", requests[0], auth[0])
+	}
+
+	ts, err := slack.Post("C0123", msg)
+	if err != nil || ts != "1727784000.000100" {
+		t.Errorf("Post() to a channel = %q, %v", ts, err)
+	}
+	if requests[1].Channel != "C0123" || requests[1].ThreadTS != "1" || auth[1] != "Bearer xoxb-test" {
+		t.Errorf("API request %+v with authorization %q", requests[1], auth[1])
+	}
+
+	if _, err := slack.Post("C-missing", msg); err == nil || !strings.Contains(err.Error(), "channel_not_found") {
+		t.Errorf("Post() = %v for a missing channel", err)
+	}
+
+	limited = 10
+	if _, err := slack.Post("C0123", msg); err == nil {
+		t.Errorf("Post() succeeded while rate limited")
+	}
+	if len(slept) != 1+slack.MaxRetries {
+		t.Errorf("Retried %d times, want %d", len(slept)-1, slack.MaxRetries)
+	}
+
+	if _, err := NewSlack("").Post("C0123", msg); err == nil {
+		t.Errorf("Post() to a channel succeeded without a token")
+	}
+}
+
+func TestSendSlackAlert(t *testing.T) {
+	var requests []SlackMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg SlackMessage
+		json.NewDecoder(r.Body).Decode(&msg)
+		requests = append(requests, msg)
+		w.Write([]byte(`{"ok":true,"ts":"1727784000.000100"}`))
+	}))
+	defer server.Close()
+	slack := NewSlack("xoxb-test")
+	slack.APIURL = server.URL
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	resolvedAt := time.Date(2026, 10, 1, 12, 30, 0, 0, time.UTC)
+	state := alert.State{Fingerprint: "dc88", Title: "Chain Status Warning", Message: "rpc timeout",
+		FirstSeen: resolvedAt.Add(-30 * time.Minute), ResolvedAt: &resolvedAt}
+
+	mock.ExpectExec("INSERT INTO slack_thread").WithArgs("dc88", "C0123", "1727784000.000100", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	if err := SendSlackAlert(sqlxDB, slack, "C0123", alert.Notification{Kind: alert.KindFiring, State: state}); err != nil {
+		t.Errorf("SendSlackAlert() = %v when firing", err)
+	}
+
+	mock.ExpectQuery("SELECT ts FROM slack_thread").WithArgs("dc88", "C0123").
+		WillReturnRows(sqlmock.NewRows([]string{"ts"}).AddRow("1727784000.000100"))
+	if err := SendSlackAlert(sqlxDB, slack, "C0123", alert.Notification{Kind: alert.KindResolved, State: state}); err != nil {
+		t.Errorf("SendSlackAlert() = %v when resolved", err)
+	}
+
+	if len(requests) != 2 || requests[0].ThreadTS != "" || requests[1].ThreadTS != "1727784000.000100" || !requests[1].ReplyBroadcast {
+		t.Errorf("Unexpected requests %+v", requests)
+	}
+	if requests[1].Text != "[RESOLVED] Chain Status Warning" {
+		t.Errorf("Resolution posted as %q", requests[1].Text)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unmet expectations: %v", err)
+	}
+}
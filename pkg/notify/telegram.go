@@ -0,0 +1,117 @@
+package notify
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/swanchain/domain-check/pkg/alert"
+	"github.com/swanchain/domain-check/pkg/model"
+)
+
+// telegramTextLimit is the longest message sendMessage accepts.
+const telegramTextLimit = 4096
+
+// TelegramConfig is read from info rows of type 'telegram': the bot token,
+// which may instead be set in TELEGRAM_BOT_TOKEN, and the default chat-id.
+type TelegramConfig struct {
+	Token  string
+	ChatID string
+}
+
+func GetTelegramConfig(db *sqlx.DB) (TelegramConfig, error) {
+	cfg := TelegramConfig{Token: os.Getenv("TELEGRAM_BOT_TOKEN")}
+
+	var configs []model.Info
+	err := db.Select(&configs, "SELECT key, value FROM info WHERE is_active = true AND type = 'telegram'")
+	if err != nil {
+		return cfg, err
+	}
+
+	for _, config := range configs {
+		switch config.Key {
+		case "token":
+			cfg.Token = config.Value
+		case "chat-id":
+			cfg.ChatID = config.Value
+		}
+	}
+	return cfg, nil
+}
+
+var markdownV2Escaper = strings.NewReplacer(
+	`\`, `\\`, "_", `\_`, "*", `\*`, "[", `\[`, "]", `\]`, "(", `\(`, ")", `\)`, "~", `\~`, "`", "\\`",
+	">", `\>`, "#", `\#`, "+", `\+`, "-", `\-`, "=", `\=`, "|", `\|`, "{", `\{`, "}", `\}`, ".", `\.`, "!", `\!`,
+)
+
+// EscapeMarkdownV2 escapes every character MarkdownV2 reserves, so text is
+// shown as written.
+func EscapeMarkdownV2(text string) string {
+	return markdownV2Escaper.Replace(text)
+}
+
+// TelegramText formats a title and message for MarkdownV2: the title in
+// bold and the message as plain text, without the bold markers the Teams
+// messages use. It is cut to the length Telegram accepts.
+func TelegramText(title, message string) string {
+	title = truncate(title, 256)
+	message = strings.ReplaceAll(message, "**", "")
+	// Leave room for the escapes, which at most double the length.
+	message = truncate(message, telegramTextLimit/2-len(title)-4)
+	return "*" + EscapeMarkdownV2(title) + "*\n\n" + EscapeMarkdownV2(message)
+}
+
+// Telegram sends messages with the Telegram Bot API.
+type Telegram struct {
+	sender
+	APIURL string
+	Token  string
+}
+
+func NewTelegram(token string) *Telegram {
+	return &Telegram{sender: newSender(), APIURL: "https://api.telegram.org", Token: token}
+}
+
+// SendMessage sends text, formatted as MarkdownV2, to a chat ID or
+// @channelusername.
+func (t *Telegram) SendMessage(chatID, text string) error {
+	if t.Token == "" {
+		return fmt.Errorf("sending to Telegram chat %s needs a bot token", chatID)
+	}
+
+	body, err := t.postJSON("Telegram", t.APIURL+"/bot"+t.Token+"/sendMessage", nil, map[string]interface{}{
+		"chat_id":                  chatID,
+		"text":                     text,
+		"parse_mode":               "MarkdownV2",
+		"disable_web_page_preview": true,
+	})
+	if err != nil {
+		// The URL contains the token; keep it out of logs.
+		return errors.New(strings.ReplaceAll(err.Error(), t.Token, "<token>"))
+	}
+
+	var response struct {
+		OK          bool   `json:"ok"`
+		Description string `json:"description"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil || !response.OK {
+		return fmt.Errorf("telegram sendMessage error: %s", response.Description)
+	}
+	return nil
+}
+
+func SendTelegramAlert(telegram *Telegram, chatID string, notification alert.Notification) error {
+	text := notification.Text()
+	if facts := notification.State.Facts; len(facts) > 0 {
+		text += "\n\n" + alert.FactsText(facts)
+	}
+	if err := telegram.SendMessage(chatID, TelegramText(notification.Title(), text)); err != nil {
+		log.Printf("Error sending %s to Telegram: %s", notification.Title(), err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package notify
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/swanchain/domain-check/pkg/alert"
+)
+
+func TestEscapeMarkdownV2(t *testing.T) {
+	got := EscapeMarkdownV2(`Block 1.5 (swan) isn't [ok]: a_b*c~d` + "`e`" + ` > #1 +2 -3 =4 |x| {y} done! \`)
+	want := `Block 1\.5 \(swan\) isn't \[ok\]: a\_b\*c\~d` + "\\`e\\`" + ` \> \#1 \+2 \-3 \=4 \|x\| \{y\} done\! \\`
+	if got != want {
+		t.Errorf("EscapeMarkdownV2() = %s, want %s", got, want)
+	}
+
+	text := TelegramText("Chain Status Warning", "**swan**: only 2 transactions.")
+	if text != "*Chain Status Warning*\n\nswan: only 2 transactions\\." {
+		t.Errorf("TelegramText() = %q", text)
+	}
+	if long := TelegramText("Title", strings.Repeat(".", 10000)); len(long) > telegramTextLimit {
+		t.Errorf("TelegramText() is %d bytes", len(long))
+	}
+}
+
+func TestSendTelegramAlert(t *testing.T) {
+	var paths []string
+	var requests []map[string]interface{}
+	limited := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if limited {
+			limited = false
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"ok":false,"error_code":429,"description":"Too Many Requests: retry after 3","parameters":{"retry_after":3}}`))
+			return
+		}
+		var request map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&request)
+		paths = append(paths, r.URL.Path)
+		requests = append(requests, request)
+		if request["chat_id"] == "@missing" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"ok":false,"error_code":400,"description":"Bad Request: chat not found"}`))
+			return
+		}
+		w.Write([]byte(`{"ok":true,"result":{"message_id":1}}`))
+	}))
+	defer server.Close()
+
+	telegram := NewTelegram("123:secret")
+	telegram.APIURL = server.URL
+	var slept []time.Duration
+	telegram.sleep = func(d time.Duration) { slept = append(slept, d) }
+
+	state := alert.State{Title: "Chain Status Warning", Message: "rpc timeout", Severity: alert.SeverityCritical}
+	if err := SendTelegramAlert(telegram, "@swanchain_status", alert.Notification{Kind: alert.KindFiring, State: state}); err != nil {
+		t.Fatalf("SendTelegramAlert() = %v", err)
+	}
+	if len(slept) != 1 || slept[0] != 3*time.Second {
+		t.Errorf("Slept %v after being rate limited", slept)
+	}
+	if paths[0] != "/bot123:secret/sendMessage" || requests[0]["parse_mode"] != "MarkdownV2" || requests[0]["chat_id"] != "@swanchain_status" {
+		t.Errorf("Unexpected request %s %+v", paths[0], requests[0])
+	}
+	if requests[0]["text"] != `*\[FIRING\] Chain Status Warning*`+"\n\nrpc timeout" {
+		t.Errorf("Unexpected text %q", requests[0]["text"])
+	}
+
+	err := SendTelegramAlert(telegram, "@missing", alert.Notification{Kind: alert.KindFiring, State: state})
+	if err == nil || !strings.Contains(err.Error(), "chat not found") {
+		t.Errorf("SendTelegramAlert() = %v for a missing chat", err)
+	}
+
+	server.Close()
+	err = SendTelegramAlert(telegram, "@swanchain_status", alert.Notification{Kind: alert.KindFiring, State: state})
+	if err == nil || strings.Contains(err.Error(), "secret") {
+		t.Errorf("SendTelegramAlert() = %v without a server", err)
+	}
+	if err := NewTelegram("").SendMessage("@swanchain_status", "text"); err == nil {
+		t.Errorf("SendMessage() succeeded without a token")
+	}
+}
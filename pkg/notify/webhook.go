@@ -0,0 +1,229 @@
+package notify
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/swanchain/domain-check/pkg/alert"
+	"github.com/swanchain/domain-check/pkg/model"
+)
+
+// WebhookSchemaVersion is the version of the WebhookPayload schema. Fields
+// may be added within a version; it changes when fields change meaning or
+// are removed.
+const WebhookSchemaVersion = "1"
+
+// Headers sent with every webhook request. The signature is
+// "sha256=" followed by the hex HMAC-SHA256, keyed by the secret, of the
+// timestamp, a period and the body.
+const (
+	WebhookTimestampHeader = "X-Domain-Check-Timestamp"
+	WebhookSignatureHeader = "X-Domain-Check-Signature"
+	WebhookEventHeader     = "X-Domain-Check-Event"
+)
+
+// WebhookConfig is read from info rows of type 'webhook': the default url,
+// the signing secret, which is required and may instead be set in
+// WEBHOOK_SECRET,
+// timeout-seconds for each attempt and the number of retries after a
+// failed one.
+type WebhookConfig struct {
+	URL     string
+	Secret  string
+	Timeout time.Duration
+	Retries int
+}
+
+func GetWebhookConfig(db *sqlx.DB) (WebhookConfig, error) {
+	cfg := WebhookConfig{Secret: os.Getenv("WEBHOOK_SECRET"), Timeout: 10 * time.Second, Retries: 3}
+
+	var configs []model.Info
+	err := db.Select(&configs, "SELECT key, value FROM info WHERE is_active = true AND type = 'webhook'")
+	if err != nil {
+		return cfg, err
+	}
+
+	for _, config := range configs {
+		switch config.Key {
+		case "url":
+			cfg.URL = config.Value
+		case "secret":
+			cfg.Secret = config.Value
+		case "timeout-seconds", "retries":
+			n, err := strconv.Atoi(config.Value)
+			if err != nil || n < 0 || config.Key == "timeout-seconds" && n == 0 {
+				return cfg, fmt.Errorf("invalid webhook %s %q", config.Key, config.Value)
+			}
+			if config.Key == "timeout-seconds" {
+				cfg.Timeout = time.Duration(n) * time.Second
+			} else {
+				cfg.Retries = n
+			}
+		}
+	}
+	if cfg.Secret == "" {
+		return cfg, errors.New("webhook secret is not set, not sending unsigned webhooks")
+	}
+	return cfg, nil
+}
+
+// WebhookTimestamps are when the alert was first reported, last fired, last
+// reported, resolved and acknowledged.
+type WebhookTimestamps struct {
+	FirstSeen      time.Time  `json:"first_seen"`
+	FiredAt        time.Time  `json:"fired_at"`
+	LastSeen       time.Time  `json:"last_seen"`
+	ResolvedAt     *time.Time `json:"resolved_at"`
+	AcknowledgedAt *time.Time `json:"acknowledged_at"`
+}
+
+// WebhookValues are the counters of the alert: its current run of
+// consecutive failures, its recent state changes and the escalation steps
+// notified.
+type WebhookValues struct {
+	Failures    int `json:"failures"`
+	Transitions int `json:"transitions"`
+	Escalation  int `json:"escalation"`
+}
+
+// WebhookPayload is the JSON body of a webhook request. Event is the
+// notification kind and Status whether the alert is firing or resolved
+// once it has been sent, or event for one-off events. Labels hold the network, domain and wallet the
+// alert is about, where set, and Facts the alert's table.
+type WebhookPayload struct {
+	Version        string            `json:"version"`
+	Event          string            `json:"event"`
+	Status         string            `json:"status"`
+	Fingerprint    string            `json:"fingerprint"`
+	DedupKey       string            `json:"dedup_key"`
+	Check          string            `json:"check"`
+	Key            string            `json:"key"`
+	Severity       string            `json:"severity"`
+	Title          string            `json:"title"`
+	Message        string            `json:"message"`
+	Facts          []alert.Fact      `json:"facts,omitempty"`
+	Labels         map[string]string `json:"labels"`
+	Values         WebhookValues     `json:"values"`
+	Timestamps     WebhookTimestamps `json:"timestamps"`
+	AcknowledgedBy string            `json:"acknowledged_by,omitempty"`
+	Flapping       bool              `json:"flapping"`
+	SentAt         time.Time         `json:"sent_at"`
+}
+
+func NewWebhookPayload(notification alert.Notification, at time.Time) WebhookPayload {
+	state := notification.State
+	status := alert.StateFiring
+	switch {
+	case notification.Kind == alert.KindEvent:
+		status = alert.KindEvent
+	case notification.Resolved():
+		status = alert.StateResolved
+	}
+	labels := map[string]string{}
+	for name, value := range map[string]string{"network": state.Network, "domain": state.Domain, "wallet": state.Wallet} {
+		if value != "" {
+			labels[name] = value
+		}
+	}
+	return WebhookPayload{
+		Version:     WebhookSchemaVersion,
+		Event:       notification.Kind,
+		Status:      status,
+		Fingerprint: state.Fingerprint,
+		DedupKey:    dedupKey(state),
+		Check:       state.Check,
+		Key:         state.Key,
+		Severity:    state.Severity,
+		Title:       state.Title,
+		Message:     state.Message,
+		Facts:       state.Facts,
+		Labels:      labels,
+		Values: WebhookValues{
+			Failures:    len(state.Failures),
+			Transitions: len(state.Transitions),
+			Escalation:  state.Escalation,
+		},
+		Timestamps: WebhookTimestamps{
+			FirstSeen:      state.FirstSeen.UTC(),
+			FiredAt:        state.FiredAt.UTC(),
+			LastSeen:       state.LastSeen.UTC(),
+			ResolvedAt:     state.ResolvedAt,
+			AcknowledgedAt: state.AckedAt,
+		},
+		AcknowledgedBy: state.AckedBy,
+		Flapping:       state.Flapping,
+		SentAt:         at.UTC(),
+	}
+}
+
+// SignWebhook returns the signature header value of body sent at timestamp,
+// in Unix seconds.
+func SignWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhook reports whether signature is the signature of body sent at
+// timestamp, for receivers written in Go.
+func VerifyWebhook(secret, timestamp, signature string, body []byte) bool {
+	return hmac.Equal([]byte(signature), []byte(SignWebhook(secret, timestamp, body)))
+}
+
+// Webhook posts alerts to arbitrary URLs, retrying server errors and failed
+// connections.
+type Webhook struct {
+	sender
+	Secret string
+	now    func() time.Time
+}
+
+func NewWebhook(cfg WebhookConfig) *Webhook {
+	webhook := &Webhook{sender: newSender(), Secret: cfg.Secret, now: time.Now}
+	webhook.Client.Timeout = cfg.Timeout
+	webhook.MaxRetries = cfg.Retries
+	webhook.RetryFailures = true
+	return webhook
+}
+
+// Send posts payload to url, signed with the secret; retries resend it with
+// the same timestamp and signature.
+func (w *Webhook) Send(url string, payload WebhookPayload) error {
+	if url == "" {
+		return fmt.Errorf("no URL to send %s of %s to", payload.Event, payload.DedupKey)
+	}
+	if w.Secret == "" {
+		return fmt.Errorf("no secret to sign %s of %s with", payload.Event, payload.DedupKey)
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	timestamp := strconv.FormatInt(payload.SentAt.Unix(), 10)
+	header := http.Header{
+		WebhookTimestampHeader: {timestamp},
+		WebhookSignatureHeader: {SignWebhook(w.Secret, timestamp, body)},
+		WebhookEventHeader:     {payload.Event},
+	}
+	_, err = w.post("Webhook", url, header, body)
+	return err
+}
+
+func SendWebhookAlert(webhook *Webhook, url string, notification alert.Notification) error {
+	if err := webhook.Send(url, NewWebhookPayload(notification, webhook.now())); err != nil {
+		log.Printf("Error posting %s to webhook %s: %s", notification.Title(), url, err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package notify
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/swanchain/domain-check/pkg/alert"
+)
+
+func TestGetWebhookConfig(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	mock.ExpectQuery("SELECT key, value FROM info WHERE is_active = true AND type = 'webhook'").
+		WillReturnRows(sqlmock.NewRows([]string{"key", "value"}).
+			AddRow("url", "https://automation.example/alerts").
+			AddRow("secret", "s3cret").
+			AddRow("timeout-seconds", "5").
+			AddRow("retries", "0"))
+	cfg, err := GetWebhookConfig(sqlxDB)
+	want := WebhookConfig{URL: "https://automation.example/alerts", Secret: "s3cret", Timeout: 5 * time.Second}
+	if err != nil || cfg != want {
+		t.Errorf("GetWebhookConfig() = %+v, %v, want %+v", cfg, err, want)
+	}
+
+	t.Setenv("WEBHOOK_SECRET", "")
+	for _, row := range [][2]string{{"timeout-seconds", "0"}, {"timeout-seconds", "x"}, {"retries", "-1"}, {"url", "https://automation.example/alerts"}} {
+		mock.ExpectQuery("SELECT key, value FROM info").WillReturnRows(sqlmock.NewRows([]string{"key", "value"}).AddRow(row[0], row[1]))
+		if _, err := GetWebhookConfig(sqlxDB); err == nil {
+			t.Errorf("GetWebhookConfig() succeeded with %s %q", row[0], row[1])
+		}
+	}
+}
+
+func TestSendWebhookAlert(t *testing.T) {
+	var attempts int
+	var payload WebhookPayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		if !VerifyWebhook("s3cret", r.Header.Get(WebhookTimestampHeader), r.Header.Get(WebhookSignatureHeader), body) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Header.Get(WebhookEventHeader) != alert.KindResolved {
+			t.Errorf("Unexpected event header %q", r.Header.Get(WebhookEventHeader))
+		}
+		json.Unmarshal(body, &payload)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	webhook := NewWebhook(WebhookConfig{Secret: "s3cret", Timeout: time.Second, Retries: 2})
+	webhook.now = func() time.Time { return now }
+	var slept []time.Duration
+	webhook.sleep = func(d time.Duration) { slept = append(slept, d) }
+
+	resolvedAt := now.Add(-time.Minute)
+	state := alert.State{Fingerprint: "0f1e", Check: "chain-status", Key: "swan", Title: "Chain Status Warning", Message: "rpc timeout",
+		Severity: alert.SeverityCritical, Network: "swan", State: alert.StateResolved, FirstSeen: now.Add(-time.Hour),
+		FiredAt: now.Add(-time.Hour), LastSeen: now.Add(-2 * time.Minute), ResolvedAt: &resolvedAt, Escalation: 2}
+	if err := SendWebhookAlert(webhook, server.URL, alert.Notification{Kind: alert.KindResolved, State: state}); err != nil {
+		t.Fatalf("SendWebhookAlert() = %v", err)
+	}
+	if attempts != 2 || len(slept) != 1 || slept[0] != time.Second {
+		t.Errorf("Made %d attempts, sleeping %v", attempts, slept)
+	}
+	if payload.Version != WebhookSchemaVersion || payload.Status != alert.StateResolved || payload.DedupKey != "chain-status-0f1e" ||
+		payload.Labels["network"] != "swan" || len(payload.Labels) != 1 || payload.Values.Escalation != 2 ||
+		!payload.Timestamps.ResolvedAt.Equal(resolvedAt) || !payload.SentAt.Equal(now) {
+		t.Errorf("Unexpected payload %+v", payload)
+	}
+
+	// A wrong secret is rejected, and client errors are not retried.
+	attempts, slept = 1, nil
+	webhook.Secret = "wrong"
+	if err := SendWebhookAlert(webhook, server.URL, alert.Notification{Kind: alert.KindResolved, State: state}); err == nil || len(slept) != 0 {
+		t.Errorf("SendWebhookAlert() = %v after sleeping %v with the wrong secret", err, slept)
+	}
+
+	// Failed connections are retried, backing off, until retries run out.
+	server.Close()
+	slept = nil
+	if err := SendWebhookAlert(webhook, server.URL, alert.Notification{Kind: alert.KindResolved, State: state}); err == nil {
+		t.Errorf("SendWebhookAlert() succeeded without a server")
+	}
+	if len(slept) != 2 || slept[1] != 2*time.Second {
+		t.Errorf("Slept %v without a server", slept)
+	}
+	if err := SendWebhookAlert(webhook, "", alert.Notification{Kind: alert.KindResolved, State: state}); err == nil {
+		t.Errorf("SendWebhookAlert() succeeded without a URL")
+	}
+	webhook.Secret = ""
+	if err := SendWebhookAlert(webhook, "https://automation.example/alerts", alert.Notification{Kind: alert.KindResolved, State: state}); err == nil {
+		t.Errorf("SendWebhookAlert() succeeded without a secret")
+	}
+}
+
+func TestNewWebhookPayloadStatus(t *testing.T) {
+	state := alert.State{Fingerprint: "0f1e", Check: "ct", Title: "Certificate Transparency Warning", State: alert.StateOK}
+	tests := map[string]string{
+		alert.KindFiring:   alert.StateFiring,
+		alert.KindRepeat:   alert.StateFiring,
+		alert.KindResolved: alert.StateResolved,
+		alert.KindEvent:    "event",
+	}
+	for kind, want := range tests {
+		if payload := NewWebhookPayload(alert.Notification{Kind: kind, State: state}, time.Now()); payload.Status != want {
+			t.Errorf("NewWebhookPayload(%s) status = %s, want %s", kind, payload.Status, want)
+		}
+	}
+}
@@ -0,0 +1,321 @@
+package sslcert
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+const dialTimeout = 15 * time.Second
+
+// CertificateInfo describes one certificate of the chain a server presented.
+type CertificateInfo struct {
+	Subject   string
+	Issuer    string
+	SANs      []string
+	KeyType   string
+	KeyBits   int
+	NotBefore time.Time
+	NotAfter  time.Time
+	IsCA      bool
+
+	// IssuerOrganization is the organization of the issuing CA. It stays the
+	// same when a CA moves a certificate to another of its intermediates.
+	IssuerOrganization string
+
+	// Fingerprint is the hex SHA-256 of the certificate and SPKIHash the
+	// base64 SHA-256 of its public key, as used for pinning.
+	Fingerprint string
+	SPKIHash    string
+}
+
+// CertificateReport is the result of inspecting a server's certificate chain.
+// Chain is in the order the server sent it, starting with the leaf.
+type CertificateReport struct {
+	Domain     string
+	Address    string
+	ServerName string
+	Chain      []CertificateInfo
+	OCSP       []OCSPResult
+
+	// Root is the subject of the root the chain leads to: the verified root
+	// if the chain validates, otherwise the best guess from the certificates
+	// the server sent.
+	Root string
+
+	// EarliestIntermediate is the index in Chain of the intermediate that
+	// expires first, or -1 if the server sent no intermediates.
+	EarliestIntermediate int
+
+	HostnameMismatch bool
+	UntrustedRoot    bool
+	NotYetValid      bool
+	Expired          bool
+	VerifyError      string
+
+	certs       []*x509.Certificate
+	stapledOCSP []byte
+	checkedAt   time.Time
+}
+
+func (r *CertificateReport) Leaf() CertificateInfo {
+	return r.Chain[0]
+}
+
+// EarliestExpiry is the first NotAfter of any certificate in the chain, which
+// is when the chain as a whole stops validating.
+func (r *CertificateReport) EarliestExpiry() time.Time {
+	earliest := r.Chain[0].NotAfter
+	for _, cert := range r.Chain[1:] {
+		if cert.NotAfter.Before(earliest) {
+			earliest = cert.NotAfter
+		}
+	}
+	return earliest
+}
+
+// Problems lists everything wrong with the chain in a form suitable for a
+// notification. It is empty for a valid, trusted chain.
+func (r *CertificateReport) Problems() []string {
+	var problems []string
+	leaf := r.Leaf()
+
+	if r.HostnameMismatch {
+		problems = append(problems, fmt.Sprintf("The certificate for %s is not valid for %s (SANs: %v).", r.Domain, r.ServerName, leaf.SANs))
+	}
+	if r.UntrustedRoot {
+		problems = append(problems, fmt.Sprintf("The certificate chain for %s does not lead to a trusted root (issuer: %s).", r.Domain, r.Chain[len(r.Chain)-1].Issuer))
+	}
+	if r.NotYetValid {
+		problems = append(problems, fmt.Sprintf("A certificate in the chain for %s is not valid yet.", r.Domain))
+	}
+	if r.Expired {
+		problems = append(problems, fmt.Sprintf("A certificate in the chain for %s has expired.", r.Domain))
+	}
+	if r.VerifyError != "" {
+		problems = append(problems, fmt.Sprintf("The certificate chain for %s failed verification: %s.", r.Domain, r.VerifyError))
+	}
+	if r.EarliestIntermediate > 0 {
+		intermediate := r.Chain[r.EarliestIntermediate]
+		if intermediate.NotAfter.Before(leaf.NotAfter) {
+			problems = append(problems, fmt.Sprintf("The intermediate certificate %s for %s expires on %s, before the leaf certificate.",
+				intermediate.Subject, r.Domain, intermediate.NotAfter.String()))
+		}
+	}
+	for _, result := range r.OCSP {
+		problems = append(problems, result.Problems(r.Domain, r.checkedAt)...)
+	}
+
+	return problems
+}
+
+// Revocations describes the OCSP responses that say the certificate was
+// revoked.
+func (r *CertificateReport) Revocations() []string {
+	var revocations []string
+	for _, result := range r.OCSP {
+		if revocation, ok := result.Revocation(r.Domain); ok {
+			revocations = append(revocations, revocation)
+		}
+	}
+	return revocations
+}
+
+// InspectCertificate connects to every backend of the domain's target and
+// reports on the full chain each one presents. Invalid chains are still
+// returned so their problems can be reported. The error collects the
+// backends that could not be reached; reports for the others are returned
+// alongside it.
+func InspectCertificate(domain string) ([]*CertificateReport, error) {
+	target, err := ParseTarget(domain)
+	if err != nil {
+		return nil, err
+	}
+	return inspectTarget(target, nil, time.Now())
+}
+
+func inspectTarget(target Target, roots *x509.CertPool, now time.Time) ([]*CertificateReport, error) {
+	addrs, err := target.Addrs(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %v", target.Host, err)
+	}
+
+	var reports []*CertificateReport
+	var errs []error
+	for _, addr := range addrs {
+		report, err := inspectAddr(addr, target.Protocol, target.SNI, roots, now)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s (%s): %v", target.Domain, addr, err))
+			continue
+		}
+		report.Domain = target.Domain
+		checkRevocation(report)
+		reports = append(reports, report)
+	}
+
+	return reports, errors.Join(errs...)
+}
+
+func inspectAddr(addr, protocol, serverName string, roots *x509.CertPool, now time.Time) (*CertificateReport, error) {
+	conn, err := dialTLS(addr, protocol, &tls.Config{
+		ServerName: serverName,
+		// The chain is verified by inspectState so that an invalid chain is
+		// reported instead of failing the handshake.
+		InsecureSkipVerify: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	report, err := inspectState(conn.ConnectionState(), serverName, roots, now)
+	if err != nil {
+		return nil, err
+	}
+	report.Address = addr
+	return report, nil
+}
+
+// dialTLS connects to addr, runs the protocol's STARTTLS exchange if it has
+// one and completes a TLS handshake with config.
+func dialTLS(addr, protocol string, config *tls.Config) (*tls.Conn, error) {
+	rawConn, err := net.DialTimeout("tcp", addr, dialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	rawConn.SetDeadline(time.Now().Add(dialTimeout))
+
+	if startTLS, ok := startTLSProtocols[protocol]; ok {
+		if err := startTLS(rawConn); err != nil {
+			rawConn.Close()
+			return nil, err
+		}
+	}
+
+	conn := tls.Client(rawConn, config)
+	if err := conn.Handshake(); err != nil {
+		rawConn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+func inspectState(state tls.ConnectionState, serverName string, roots *x509.CertPool, now time.Time) (*CertificateReport, error) {
+	certs := state.PeerCertificates
+	if len(certs) == 0 {
+		return nil, errors.New("server presented no certificates")
+	}
+
+	report := &CertificateReport{
+		Domain:               serverName,
+		ServerName:           serverName,
+		EarliestIntermediate: -1,
+		certs:                certs,
+		stapledOCSP:          state.OCSPResponse,
+		checkedAt:            now,
+	}
+
+	intermediates := x509.NewCertPool()
+	latestNotBefore := certs[0].NotBefore
+	for i, cert := range certs {
+		report.Chain = append(report.Chain, describeCertificate(cert))
+
+		if now.Before(cert.NotBefore) {
+			report.NotYetValid = true
+		}
+		if now.After(cert.NotAfter) {
+			report.Expired = true
+		}
+		if cert.NotBefore.After(latestNotBefore) {
+			latestNotBefore = cert.NotBefore
+		}
+
+		if i == 0 {
+			continue
+		}
+		intermediates.AddCert(cert)
+		// A self-signed root sent by the server is not an intermediate.
+		if cert.CheckSignatureFrom(cert) == nil {
+			continue
+		}
+		if report.EarliestIntermediate < 0 || cert.NotAfter.Before(certs[report.EarliestIntermediate].NotAfter) {
+			report.EarliestIntermediate = i
+		}
+	}
+
+	if serverName != "" && certs[0].VerifyHostname(serverName) != nil {
+		report.HostnameMismatch = true
+	}
+
+	// Validity periods are checked above, so verify trust at a moment when
+	// every certificate in the chain is valid to keep the two apart.
+	last := certs[len(certs)-1]
+	report.Root = last.Issuer.String()
+	if last.CheckSignatureFrom(last) == nil {
+		report.Root = last.Subject.String()
+	}
+	chains, err := certs[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   latestNotBefore.Add(time.Second),
+	})
+	var unknownAuthority x509.UnknownAuthorityError
+	var invalid x509.CertificateInvalidError
+	switch {
+	case err == nil:
+		verified := chains[0]
+		report.Root = verified[len(verified)-1].Subject.String()
+	case errors.As(err, &unknownAuthority):
+		report.UntrustedRoot = true
+	case errors.As(err, &invalid) && invalid.Reason == x509.Expired:
+		// The chain has no moment at which all certificates are valid;
+		// Expired or NotYetValid already describe this.
+	default:
+		report.VerifyError = err.Error()
+	}
+
+	return report, nil
+}
+
+func describeCertificate(cert *x509.Certificate) CertificateInfo {
+	info := CertificateInfo{
+		Subject:   cert.Subject.String(),
+		Issuer:    cert.Issuer.String(),
+		SANs:      cert.DNSNames,
+		NotBefore: cert.NotBefore,
+		NotAfter:  cert.NotAfter,
+		IsCA:      cert.IsCA,
+	}
+	info.IssuerOrganization = strings.Join(cert.Issuer.Organization, ", ")
+	fingerprint := sha256.Sum256(cert.Raw)
+	info.Fingerprint = hex.EncodeToString(fingerprint[:])
+	spki := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	info.SPKIHash = base64.StdEncoding.EncodeToString(spki[:])
+	for _, ip := range cert.IPAddresses {
+		info.SANs = append(info.SANs, ip.String())
+	}
+
+	switch key := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		info.KeyType, info.KeyBits = "RSA", key.N.BitLen()
+	case *ecdsa.PublicKey:
+		info.KeyType, info.KeyBits = "ECDSA", key.Curve.Params().BitSize
+	case ed25519.PublicKey:
+		info.KeyType, info.KeyBits = "Ed25519", 256
+	default:
+		info.KeyType = cert.PublicKeyAlgorithm.String()
+	}
+
+	return info
+}
@@ -0,0 +1,100 @@
+package sslcert
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInspectValidChain(t *testing.T) {
+	root, intermediate, leaf := newTestChain(t, "swan.test")
+	addr := serveTLS(t, nil, leaf, intermediate)
+
+	report, err := inspectAddr(addr, "https", "swan.test", certPool(root), time.Now())
+	if err != nil {
+		t.Fatalf("inspectAddr() returned error: %v", err)
+	}
+
+	if problems := report.Problems(); len(problems) != 0 {
+		t.Errorf("Unexpected problems: %v", problems)
+	}
+	if len(report.Chain) != 2 || report.EarliestIntermediate != 1 {
+		t.Fatalf("Unexpected chain: %+v", report)
+	}
+
+	got := report.Leaf()
+	if got.KeyType != "ECDSA" || got.KeyBits != 256 || got.Subject != "CN=swan.test" || got.Issuer != "CN=Test Intermediate" {
+		t.Errorf("Unexpected leaf: %+v", got)
+	}
+	if len(got.SANs) != 2 || got.SANs[0] != "swan.test" || got.SANs[1] != "127.0.0.1" {
+		t.Errorf("Unexpected SANs: %v", got.SANs)
+	}
+}
+
+func TestInspectHostnameMismatchAndUntrustedRoot(t *testing.T) {
+	_, intermediate, leaf := newTestChain(t, "swan.test")
+	addr := serveTLS(t, nil, leaf, intermediate)
+
+	report, err := inspectAddr(addr, "https", "other.test", x509.NewCertPool(), time.Now())
+	if err != nil {
+		t.Fatalf("inspectAddr() returned error: %v", err)
+	}
+
+	if !report.HostnameMismatch || !report.UntrustedRoot || report.Expired || report.NotYetValid {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+	if problems := report.Problems(); len(problems) != 2 {
+		t.Errorf("Unexpected problems: %v", problems)
+	}
+}
+
+func TestInspectIntermediateExpiringBeforeLeaf(t *testing.T) {
+	root := newTestCert(t, &x509.Certificate{Subject: pkix.Name{CommonName: "Test Root"}, IsCA: true}, nil)
+	intermediate := newTestCert(t, &x509.Certificate{
+		Subject:  pkix.Name{CommonName: "Short Intermediate"},
+		IsCA:     true,
+		NotAfter: time.Now().Add(5 * 24 * time.Hour),
+	}, root)
+	leaf := newTestCert(t, &x509.Certificate{Subject: pkix.Name{CommonName: "swan.test"}, DNSNames: []string{"swan.test"}}, intermediate)
+	addr := serveTLS(t, nil, leaf, intermediate, root)
+
+	report, err := inspectAddr(addr, "https", "swan.test", certPool(root), time.Now())
+	if err != nil {
+		t.Fatalf("inspectAddr() returned error: %v", err)
+	}
+
+	if report.EarliestIntermediate != 1 {
+		t.Errorf("EarliestIntermediate = %d, want 1", report.EarliestIntermediate)
+	}
+	if !report.EarliestExpiry().Equal(intermediate.Cert.NotAfter) {
+		t.Errorf("EarliestExpiry() = %v, want %v", report.EarliestExpiry(), intermediate.Cert.NotAfter)
+	}
+	problems := report.Problems()
+	if len(problems) != 1 || !strings.Contains(problems[0], "CN=Short Intermediate") {
+		t.Errorf("Unexpected problems: %v", problems)
+	}
+}
+
+func TestInspectStateValidity(t *testing.T) {
+	root, intermediate, leaf := newTestChain(t, "swan.test")
+	state := tls.ConnectionState{PeerCertificates: []*x509.Certificate{leaf.Cert, intermediate.Cert}}
+
+	report, err := inspectState(state, "swan.test", certPool(root), leaf.Cert.NotBefore.Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("inspectState() returned error: %v", err)
+	}
+	if !report.NotYetValid || report.Expired || report.UntrustedRoot {
+		t.Errorf("Unexpected report for a future certificate: %+v", report)
+	}
+
+	report, err = inspectState(state, "swan.test", certPool(root), leaf.Cert.NotAfter.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("inspectState() returned error: %v", err)
+	}
+	if !report.Expired || report.NotYetValid || report.UntrustedRoot {
+		t.Errorf("Unexpected report for an expired certificate: %+v", report)
+	}
+}
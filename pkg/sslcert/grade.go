@@ -0,0 +1,319 @@
+package sslcert
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Finding is one weakness found in a domain's TLS configuration. ID is stable
+// across runs so findings can be compared between scans.
+type Finding struct {
+	ID     string `json:"id"`
+	Grade  string `json:"grade"`
+	Detail string `json:"detail"`
+}
+
+// TLSScan is what a backend supports and how it grades. Grades run from A to
+// F and each finding caps the grade at its own.
+type TLSScan struct {
+	Domain             string
+	Address            string
+	Versions           []string
+	CipherSuites       []string
+	Groups             []string
+	KeyType            string
+	KeyBits            int
+	SignatureAlgorithm string
+	Findings           []Finding
+	Grade              string
+}
+
+var grades = []string{"A", "B", "C", "F"}
+
+func gradeRank(grade string) int {
+	for i, g := range grades {
+		if g == grade {
+			return i
+		}
+	}
+	return len(grades)
+}
+
+// GradeWorse reports whether grade a is lower than grade b.
+func GradeWorse(a, b string) bool {
+	return gradeRank(a) > gradeRank(b)
+}
+
+var scanVersions = []struct {
+	version uint16
+	name    string
+}{
+	{tls.VersionTLS10, "TLS 1.0"},
+	{tls.VersionTLS11, "TLS 1.1"},
+	{tls.VersionTLS12, "TLS 1.2"},
+	{tls.VersionTLS13, "TLS 1.3"},
+}
+
+var scanGroups = []tls.CurveID{tls.X25519, tls.CurveP256, tls.CurveP384, tls.CurveP521}
+
+// ScanTLS scans every backend of the domain's target.
+func ScanTLS(domain string) ([]*TLSScan, error) {
+	target, err := ParseTarget(domain)
+	if err != nil {
+		return nil, err
+	}
+
+	addrs, err := target.Addrs(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %v", target.Host, err)
+	}
+
+	var scans []*TLSScan
+	var errs []error
+	for _, addr := range addrs {
+		scan, err := scanAddr(addr, target.Protocol, target.SNI)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s (%s): %v", domain, addr, err))
+			continue
+		}
+		scan.Domain = domain
+		scans = append(scans, scan)
+	}
+
+	return scans, errors.Join(errs...)
+}
+
+func scanAddr(addr, protocol, serverName string) (*TLSScan, error) {
+	scan := &TLSScan{Address: addr}
+	allSuites := append(tls.CipherSuites(), tls.InsecureCipherSuites()...)
+	var allSuiteIDs []uint16
+	for _, suite := range allSuites {
+		allSuiteIDs = append(allSuiteIDs, suite.ID)
+	}
+
+	handshake := func(config *tls.Config) (tls.ConnectionState, bool) {
+		config.ServerName = serverName
+		config.InsecureSkipVerify = true
+		conn, err := dialTLS(addr, protocol, config)
+		if err != nil {
+			return tls.ConnectionState{}, false
+		}
+		defer conn.Close()
+		return conn.ConnectionState(), true
+	}
+
+	var maxVersion uint16
+	var leaf *x509.Certificate
+	for _, v := range scanVersions {
+		state, ok := handshake(&tls.Config{MinVersion: v.version, MaxVersion: v.version, CipherSuites: allSuiteIDs})
+		if !ok {
+			continue
+		}
+		scan.Versions = append(scan.Versions, v.name)
+		maxVersion = v.version
+		leaf = state.PeerCertificates[0]
+		if v.version == tls.VersionTLS13 {
+			scan.CipherSuites = append(scan.CipherSuites, tls.CipherSuiteName(state.CipherSuite))
+		}
+	}
+	if leaf == nil {
+		return nil, errors.New("no TLS version could be negotiated")
+	}
+
+	// Enumerate the pre-TLS 1.3 suites in the server's order of preference:
+	// offer everything not yet seen and drop whatever it picks. This takes
+	// one handshake per supported suite rather than one per known suite.
+	var legacySuites []uint16
+	if scan.Versions[0] != "TLS 1.3" {
+		var offered []uint16
+		for _, suite := range allSuites {
+			if supportsPreTLS13(suite) {
+				offered = append(offered, suite.ID)
+			}
+		}
+		for len(offered) > 0 {
+			state, ok := handshake(&tls.Config{MinVersion: tls.VersionTLS10, MaxVersion: tls.VersionTLS12, CipherSuites: offered})
+			if !ok {
+				break
+			}
+			scan.CipherSuites = append(scan.CipherSuites, tls.CipherSuiteName(state.CipherSuite))
+			legacySuites = append(legacySuites, state.CipherSuite)
+			offered = slices.DeleteFunc(offered, func(id uint16) bool { return id == state.CipherSuite })
+		}
+	}
+
+	// Groups only matter to handshakes that use them: TLS 1.3, or ECDHE
+	// suites before it. Probing with RSA key exchange suites on offer would
+	// succeed whatever the group.
+	groupConfig := &tls.Config{MinVersion: tls.VersionTLS13, MaxVersion: tls.VersionTLS13}
+	if maxVersion < tls.VersionTLS13 {
+		groupConfig = &tls.Config{MinVersion: tls.VersionTLS10, MaxVersion: maxVersion}
+		for _, id := range legacySuites {
+			if strings.Contains(tls.CipherSuiteName(id), "_ECDHE_") {
+				groupConfig.CipherSuites = append(groupConfig.CipherSuites, id)
+			}
+		}
+	}
+	if maxVersion == tls.VersionTLS13 || len(groupConfig.CipherSuites) > 0 {
+		for _, group := range scanGroups {
+			config := groupConfig.Clone()
+			config.CurvePreferences = []tls.CurveID{group}
+			if _, ok := handshake(config); ok {
+				scan.Groups = append(scan.Groups, group.String())
+			}
+		}
+	}
+
+	info := describeCertificate(leaf)
+	scan.KeyType, scan.KeyBits = info.KeyType, info.KeyBits
+	scan.SignatureAlgorithm = leaf.SignatureAlgorithm.String()
+
+	gradeScan(scan, leaf.SignatureAlgorithm)
+	return scan, nil
+}
+
+func supportsPreTLS13(suite *tls.CipherSuite) bool {
+	for _, v := range suite.SupportedVersions {
+		if v < tls.VersionTLS13 {
+			return true
+		}
+	}
+	return false
+}
+
+// gradeScan fills in the findings and the resulting grade.
+func gradeScan(scan *TLSScan, signature x509.SignatureAlgorithm) {
+	add := func(id, grade, detail string) {
+		scan.Findings = append(scan.Findings, Finding{ID: id, Grade: grade, Detail: detail})
+	}
+
+	supported := map[string]bool{}
+	for _, v := range scan.Versions {
+		supported[v] = true
+	}
+	if supported["TLS 1.0"] {
+		add("tls10-enabled", "B", "TLS 1.0 is enabled")
+	}
+	if supported["TLS 1.1"] {
+		add("tls11-enabled", "B", "TLS 1.1 is enabled")
+	}
+	if !supported["TLS 1.2"] && !supported["TLS 1.3"] {
+		add("no-modern-tls", "F", "neither TLS 1.2 nor TLS 1.3 is supported")
+	}
+
+	insecure := map[string]bool{}
+	for _, suite := range tls.InsecureCipherSuites() {
+		insecure[suite.Name] = true
+	}
+	for _, name := range scan.CipherSuites {
+		switch {
+		case insecure[name]:
+			add("weak-cipher:"+name, "C", name+" is an insecure cipher suite")
+		case strings.HasPrefix(name, "TLS_RSA_"):
+			add("no-forward-secrecy:"+name, "B", name+" does not provide forward secrecy")
+		}
+	}
+
+	switch {
+	case scan.KeyType == "RSA" && scan.KeyBits < 2048:
+		add("weak-key", "F", fmt.Sprintf("RSA-%d key", scan.KeyBits))
+	case scan.KeyType == "ECDSA" && scan.KeyBits < 256:
+		add("weak-key", "F", fmt.Sprintf("ECDSA-%d key", scan.KeyBits))
+	}
+
+	switch signature {
+	case x509.MD2WithRSA, x509.MD5WithRSA:
+		add("weak-signature", "F", signature.String()+" signature")
+	case x509.SHA1WithRSA, x509.DSAWithSHA1, x509.ECDSAWithSHA1:
+		add("weak-signature", "C", signature.String()+" signature")
+	}
+
+	sort.Slice(scan.Findings, func(i, j int) bool { return scan.Findings[i].ID < scan.Findings[j].ID })
+
+	scan.Grade = grades[0]
+	for _, finding := range scan.Findings {
+		if GradeWorse(finding.Grade, scan.Grade) {
+			scan.Grade = finding.Grade
+		}
+	}
+}
+
+// RecordTLSScan stores the scan and returns a message for each regression
+// since the previous scan of the same backend: a lower grade or new findings.
+func RecordTLSScan(db *sqlx.DB, scan *TLSScan) ([]string, error) {
+	var previous struct {
+		Grade    string `db:"grade"`
+		Findings string `db:"findings"`
+	}
+	err := db.Get(&previous, `
+		SELECT grade, findings FROM tls_scan
+		WHERE domain = $1 AND address = $2
+		ORDER BY scanned_at DESC LIMIT 1
+	`, scan.Domain, scan.Address)
+	hasPrevious := err == nil
+	if err != nil && err != sql.ErrNoRows {
+		log.Printf("Error retrieving previous TLS scan for %s: %s", scan.Domain, err)
+		return nil, err
+	}
+
+	findings, err := json.Marshal(scan.Findings)
+	if err != nil {
+		return nil, err
+	}
+	_, err = db.Exec(`
+		INSERT INTO tls_scan (domain, address, grade, versions, cipher_suites, groups, key_type, key_bits, signature_algorithm, findings, scanned_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`, scan.Domain, scan.Address, scan.Grade, strings.Join(scan.Versions, ","), strings.Join(scan.CipherSuites, ","),
+		strings.Join(scan.Groups, ","), scan.KeyType, scan.KeyBits, scan.SignatureAlgorithm, string(findings), time.Now())
+	if err != nil {
+		log.Printf("Error recording TLS scan for %s: %s", scan.Domain, err)
+		return nil, err
+	}
+
+	if !hasPrevious {
+		return nil, nil
+	}
+	return compareScans(scan, previous.Grade, previous.Findings), nil
+}
+
+// PruneTLSScans deletes the scans of a backend taken before cutoff.
+func PruneTLSScans(db *sqlx.DB, domain, address string, cutoff time.Time) error {
+	_, err := db.Exec("DELETE FROM tls_scan WHERE domain = $1 AND address = $2 AND scanned_at < $3", domain, address, cutoff)
+	if err != nil {
+		log.Printf("Error pruning TLS scans for %s: %s", domain, err)
+		return err
+	}
+	return nil
+}
+
+func compareScans(scan *TLSScan, previousGrade string, previousFindings string) []string {
+	var regressions []string
+	if GradeWorse(scan.Grade, previousGrade) {
+		regressions = append(regressions, fmt.Sprintf("The TLS grade for %s (%s) dropped from %s to %s.", scan.Domain, scan.Address, previousGrade, scan.Grade))
+	}
+
+	var before []Finding
+	json.Unmarshal([]byte(previousFindings), &before)
+	seen := map[string]bool{}
+	for _, finding := range before {
+		seen[finding.ID] = true
+	}
+	for _, finding := range scan.Findings {
+		if !seen[finding.ID] {
+			regressions = append(regressions, fmt.Sprintf("New TLS finding for %s (%s): %s.", scan.Domain, scan.Address, finding.Detail))
+		}
+	}
+	return regressions
+}
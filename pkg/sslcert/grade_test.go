@@ -0,0 +1,179 @@
+package sslcert
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+)
+
+func TestScanModernServer(t *testing.T) {
+	_, intermediate, leaf := newTestChain(t, "swan.test")
+	addr := serveTLS(t, &tls.Config{MinVersion: tls.VersionTLS12}, leaf, intermediate)
+
+	scan, err := scanAddr(addr, "https", "swan.test")
+	if err != nil {
+		t.Fatalf("scanAddr() returned error: %v", err)
+	}
+
+	if strings.Join(scan.Versions, ",") != "TLS 1.2,TLS 1.3" {
+		t.Errorf("Unexpected versions: %v", scan.Versions)
+	}
+	if scan.Grade != "A" || len(scan.Findings) != 0 {
+		t.Errorf("Unexpected grade %s with findings %+v", scan.Grade, scan.Findings)
+	}
+	if scan.KeyType != "ECDSA" || scan.KeyBits != 256 || scan.SignatureAlgorithm != "ECDSA-SHA256" {
+		t.Errorf("Unexpected key: %s-%d %s", scan.KeyType, scan.KeyBits, scan.SignatureAlgorithm)
+	}
+	if len(scan.Groups) == 0 || scan.Groups[0] != "X25519" {
+		t.Errorf("Unexpected groups: %v", scan.Groups)
+	}
+}
+
+func TestScanLegacyServer(t *testing.T) {
+	_, intermediate, leaf := newTestChain(t, "swan.test")
+	addr := serveTLS(t, &tls.Config{
+		MinVersion: tls.VersionTLS10,
+		MaxVersion: tls.VersionTLS12,
+		CipherSuites: []uint16{
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_ECDSA_WITH_RC4_128_SHA,
+		},
+	}, leaf, intermediate)
+
+	scan, err := scanAddr(addr, "https", "swan.test")
+	if err != nil {
+		t.Fatalf("scanAddr() returned error: %v", err)
+	}
+
+	if strings.Join(scan.Versions, ",") != "TLS 1.0,TLS 1.1,TLS 1.2" {
+		t.Errorf("Unexpected versions: %v", scan.Versions)
+	}
+	if len(scan.CipherSuites) != 2 {
+		t.Errorf("Unexpected cipher suites: %v", scan.CipherSuites)
+	}
+
+	var ids []string
+	for _, finding := range scan.Findings {
+		ids = append(ids, finding.ID)
+	}
+	if strings.Join(ids, ",") != "tls10-enabled,tls11-enabled,weak-cipher:TLS_ECDHE_ECDSA_WITH_RC4_128_SHA" {
+		t.Errorf("Unexpected findings: %v", ids)
+	}
+	if scan.Grade != "C" {
+		t.Errorf("Grade = %s, want C", scan.Grade)
+	}
+}
+
+func TestScanRSAKeyExchangeServer(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	_, intermediate, _ := newTestChain(t, "swan.test")
+	leaf := newTestCertWithKey(t, &x509.Certificate{
+		Subject:  pkix.Name{CommonName: "swan.test"},
+		DNSNames: []string{"swan.test"},
+	}, intermediate, key)
+	addr := serveTLS(t, &tls.Config{
+		MaxVersion:   tls.VersionTLS12,
+		CipherSuites: []uint16{tls.TLS_RSA_WITH_AES_128_GCM_SHA256, tls.TLS_RSA_WITH_AES_256_GCM_SHA384},
+	}, leaf, intermediate)
+
+	scan, err := scanAddr(addr, "https", "swan.test")
+	if err != nil {
+		t.Fatalf("scanAddr() returned error: %v", err)
+	}
+
+	if len(scan.CipherSuites) != 2 {
+		t.Errorf("Unexpected cipher suites: %v", scan.CipherSuites)
+	}
+	if len(scan.Groups) != 0 {
+		t.Errorf("Groups = %v, want none without ECDHE suites", scan.Groups)
+	}
+}
+
+func TestScanGroupsTLS12(t *testing.T) {
+	_, intermediate, leaf := newTestChain(t, "swan.test")
+	addr := serveTLS(t, &tls.Config{
+		MaxVersion:       tls.VersionTLS12,
+		CipherSuites:     []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256},
+		CurvePreferences: []tls.CurveID{tls.CurveP384},
+	}, leaf, intermediate)
+
+	scan, err := scanAddr(addr, "https", "swan.test")
+	if err != nil {
+		t.Fatalf("scanAddr() returned error: %v", err)
+	}
+
+	if strings.Join(scan.Groups, ",") != "CurveP384" {
+		t.Errorf("Groups = %v, want CurveP384", scan.Groups)
+	}
+}
+
+func TestGradeWeakKeyAndSignature(t *testing.T) {
+	scan := &TLSScan{Versions: []string{"TLS 1.2"}, KeyType: "RSA", KeyBits: 1024}
+	gradeScan(scan, x509.SHA1WithRSA)
+
+	if scan.Grade != "F" || len(scan.Findings) != 2 || scan.Findings[0].ID != "weak-key" || scan.Findings[1].ID != "weak-signature" {
+		t.Errorf("Unexpected grade %s with findings %+v", scan.Grade, scan.Findings)
+	}
+}
+
+func TestRecordTLSScanRegression(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	mock.ExpectQuery("SELECT grade, findings FROM tls_scan").
+		WithArgs("https://swan.test/", "127.0.0.1:443").
+		WillReturnRows(sqlmock.NewRows([]string{"grade", "findings"}).AddRow("A", "[]"))
+	mock.ExpectExec("INSERT INTO tls_scan").WillReturnResult(sqlmock.NewResult(1, 1))
+
+	scan := &TLSScan{
+		Domain:   "https://swan.test/",
+		Address:  "127.0.0.1:443",
+		Grade:    "B",
+		Findings: []Finding{{ID: "tls10-enabled", Grade: "B", Detail: "TLS 1.0 is enabled"}},
+	}
+	regressions, err := RecordTLSScan(sqlxDB, scan)
+	if err != nil {
+		t.Fatalf("RecordTLSScan() returned error: %v", err)
+	}
+
+	if len(regressions) != 2 || !strings.Contains(regressions[0], "dropped from A to B") || !strings.Contains(regressions[1], "TLS 1.0 is enabled") {
+		t.Errorf("Unexpected regressions: %v", regressions)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unmet expectations: %v", err)
+	}
+}
+
+func TestPruneTLSScans(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	cutoff := time.Now().Add(-90 * 24 * time.Hour)
+	mock.ExpectExec("DELETE FROM tls_scan WHERE domain = \\$1 AND address = \\$2 AND scanned_at < \\$3").
+		WithArgs("https://swan.test/", "127.0.0.1:443", cutoff).WillReturnResult(sqlmock.NewResult(0, 3))
+	if err := PruneTLSScans(sqlx.NewDb(db, "sqlmock"), "https://swan.test/", "127.0.0.1:443", cutoff); err != nil {
+		t.Errorf("PruneTLSScans() returned error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unmet expectations: %v", err)
+	}
+}
@@ -0,0 +1,159 @@
+package sslcert
+
+import (
+	"bytes"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/swanchain/domain-check/pkg/database"
+	"golang.org/x/crypto/ocsp"
+)
+
+const (
+	OCSPSourceStapled   = "stapled"
+	OCSPSourceResponder = "responder"
+)
+
+var ocspClient = &http.Client{Timeout: 10 * time.Second}
+
+// OCSPResult is the stapled or fetched revocation status of a leaf certificate.
+type OCSPResult struct {
+	Source     string
+	Responder  string
+	Status     string
+	RevokedAt  time.Time
+	ProducedAt time.Time
+	ThisUpdate time.Time
+	NextUpdate time.Time
+	Error      string
+}
+
+// Problems describes failing responders, unknown certificates and stale responses.
+func (r OCSPResult) Problems(domain string, now time.Time) []string {
+	var problems []string
+	switch {
+	case r.Error != "":
+		problems = append(problems, fmt.Sprintf("The %s OCSP check for %s failed: %s.", r.Source, domain, r.Error))
+	case r.Status == "revoked":
+		// Reported by Revocation.
+	case r.Status == "unknown":
+		problems = append(problems, fmt.Sprintf("The %s OCSP response for %s reports the certificate status as unknown.", r.Source, domain))
+	case !r.NextUpdate.IsZero() && now.After(r.NextUpdate):
+		problems = append(problems, fmt.Sprintf("The %s OCSP response for %s is stale: next update was due %s.", r.Source, domain, r.NextUpdate.String()))
+	}
+	return problems
+}
+
+// Revocation describes the certificate if the response says it was revoked.
+func (r OCSPResult) Revocation(domain string) (string, bool) {
+	if r.Error != "" || r.Status != "revoked" {
+		return "", false
+	}
+	return fmt.Sprintf("The certificate for %s was revoked on %s (%s OCSP response).", domain, r.RevokedAt.String(), r.Source), true
+}
+
+// checkRevocation fills in report.OCSP from the stapled response and the
+// responders of the leaf certificate.
+func checkRevocation(report *CertificateReport) {
+	if len(report.certs) < 2 {
+		return
+	}
+	leaf, issuer := report.certs[0], findIssuer(report.certs[0], report.certs[1:])
+	if issuer == nil {
+		return
+	}
+
+	if len(report.stapledOCSP) > 0 {
+		result := parseOCSP(report.stapledOCSP, leaf, issuer)
+		result.Source = OCSPSourceStapled
+		report.OCSP = append(report.OCSP, result)
+	}
+
+	for _, responder := range leaf.OCSPServer {
+		result := queryOCSP(responder, leaf, issuer)
+		result.Source = OCSPSourceResponder
+		result.Responder = responder
+		report.OCSP = append(report.OCSP, result)
+	}
+}
+
+// findIssuer returns the certificate in candidates that signed cert.
+func findIssuer(cert *x509.Certificate, candidates []*x509.Certificate) *x509.Certificate {
+	for _, candidate := range candidates {
+		if cert.CheckSignatureFrom(candidate) == nil {
+			return candidate
+		}
+	}
+	return nil
+}
+
+func queryOCSP(responder string, leaf, issuer *x509.Certificate) OCSPResult {
+	request, err := ocsp.CreateRequest(leaf, issuer, nil)
+	if err != nil {
+		return OCSPResult{Error: err.Error()}
+	}
+
+	resp, err := ocspClient.Post(responder, "application/ocsp-request", bytes.NewReader(request))
+	if err != nil {
+		return OCSPResult{Error: err.Error()}
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return OCSPResult{Error: err.Error()}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return OCSPResult{Error: fmt.Sprintf("responder returned %s", resp.Status)}
+	}
+
+	return parseOCSP(body, leaf, issuer)
+}
+
+func parseOCSP(der []byte, leaf, issuer *x509.Certificate) OCSPResult {
+	response, err := ocsp.ParseResponseForCert(der, leaf, issuer)
+	if err != nil {
+		return OCSPResult{Error: err.Error()}
+	}
+
+	result := OCSPResult{
+		ProducedAt: response.ProducedAt,
+		ThisUpdate: response.ThisUpdate,
+		NextUpdate: response.NextUpdate,
+	}
+	switch response.Status {
+	case ocsp.Good:
+		result.Status = "good"
+	case ocsp.Revoked:
+		result.Status = "revoked"
+		result.RevokedAt = response.RevokedAt
+	default:
+		result.Status = "unknown"
+	}
+	return result
+}
+
+func RecordOCSP(db *sqlx.DB, report *CertificateReport) error {
+	for _, result := range report.OCSP {
+		_, err := db.Exec(`
+			INSERT INTO ocsp_status (domain, address, source, responder, status, revoked_at, produced_at, this_update, next_update, error, checked_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+			ON CONFLICT (domain, address, source, responder) DO UPDATE
+			SET status = EXCLUDED.status, revoked_at = EXCLUDED.revoked_at, produced_at = EXCLUDED.produced_at,
+				this_update = EXCLUDED.this_update, next_update = EXCLUDED.next_update,
+				error = EXCLUDED.error, checked_at = EXCLUDED.checked_at
+		`, report.Domain, report.Address, result.Source, result.Responder, result.Status,
+			database.NullTime(result.RevokedAt), database.NullTime(result.ProducedAt), database.NullTime(result.ThisUpdate), database.NullTime(result.NextUpdate),
+			result.Error, time.Now())
+		if err != nil {
+			log.Printf("Error recording OCSP status for %s: %s", report.Domain, err)
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+package sslcert
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+// newOCSPResponse signs a response for cert on behalf of its issuer.
+func newOCSPResponse(t *testing.T, issuer, cert *testCert, status int, nextUpdate time.Time) []byte {
+	t.Helper()
+
+	template := ocsp.Response{
+		Status:       status,
+		SerialNumber: cert.Cert.SerialNumber,
+		ThisUpdate:   time.Now().Add(-time.Hour),
+		NextUpdate:   nextUpdate,
+	}
+	if status == ocsp.Revoked {
+		template.RevokedAt = time.Now().Add(-30 * time.Minute)
+	}
+
+	der, err := ocsp.CreateResponse(issuer.Cert, issuer.Cert, template, issuer.Key)
+	if err != nil {
+		t.Fatalf("Failed to create OCSP response: %v", err)
+	}
+	return der
+}
+
+// newOCSPResponder starts a local OCSP responder that answers every request
+// with respond's result for the requested serial.
+func newOCSPResponder(t *testing.T, respond func(w http.ResponseWriter, req *ocsp.Request)) string {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		req, err := ocsp.ParseRequest(body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		respond(w, req)
+	}))
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+func newOCSPChain(t *testing.T, responder string) (root, intermediate, leaf *testCert) {
+	root = newTestCert(t, &x509.Certificate{Subject: pkix.Name{CommonName: "Test Root"}, IsCA: true}, nil)
+	intermediate = newTestCert(t, &x509.Certificate{Subject: pkix.Name{CommonName: "Test Intermediate"}, IsCA: true}, root)
+	leaf = newTestCert(t, &x509.Certificate{
+		Subject:    pkix.Name{CommonName: "swan.test"},
+		DNSNames:   []string{"swan.test"},
+		OCSPServer: []string{responder},
+	}, intermediate)
+	return root, intermediate, leaf
+}
+
+func TestOCSPResponder(t *testing.T) {
+	statuses := map[string]int{}
+	var intermediate *testCert
+	var leaves []*testCert
+	responder := newOCSPResponder(t, func(w http.ResponseWriter, req *ocsp.Request) {
+		for _, leaf := range leaves {
+			if leaf.Cert.SerialNumber.Cmp(req.SerialNumber) == 0 {
+				w.Write(newOCSPResponse(t, intermediate, leaf, statuses[leaf.Cert.Subject.CommonName], time.Now().Add(24*time.Hour)))
+				return
+			}
+		}
+		w.Write(ocsp.UnauthorizedErrorResponse)
+	})
+
+	root, intermediate, good := newOCSPChain(t, responder)
+	revoked := newTestCert(t, &x509.Certificate{
+		Subject:    pkix.Name{CommonName: "revoked.swan.test"},
+		DNSNames:   []string{"revoked.swan.test"},
+		OCSPServer: []string{responder},
+	}, intermediate)
+	leaves = []*testCert{good, revoked}
+	statuses["swan.test"] = ocsp.Good
+	statuses["revoked.swan.test"] = ocsp.Revoked
+
+	target, _ := ParseTarget("https://swan.test:" + portOf(serveTLS(t, nil, good, intermediate)) + "/?ip=127.0.0.1")
+	reports, err := inspectTarget(target, certPool(root), time.Now())
+	if err != nil {
+		t.Fatalf("inspectTarget() returned error: %v", err)
+	}
+	result := reports[0].OCSP
+	if len(result) != 1 || result[0].Status != "good" || result[0].Source != OCSPSourceResponder || result[0].Responder != responder {
+		t.Errorf("Unexpected OCSP result: %+v", result)
+	}
+	if problems := reports[0].Problems(); len(problems) != 0 {
+		t.Errorf("Unexpected problems: %v", problems)
+	}
+
+	target, _ = ParseTarget("https://revoked.swan.test:" + portOf(serveTLS(t, nil, revoked, intermediate)) + "/?ip=127.0.0.1")
+	reports, err = inspectTarget(target, certPool(root), time.Now())
+	if err != nil {
+		t.Fatalf("inspectTarget() returned error: %v", err)
+	}
+	if problems := reports[0].Problems(); len(problems) != 0 {
+		t.Errorf("Unexpected problems for a revoked certificate: %v", problems)
+	}
+	if revocations := reports[0].Revocations(); len(revocations) != 1 || !strings.Contains(revocations[0], "was revoked") {
+		t.Errorf("Unexpected revocations: %v", revocations)
+	}
+}
+
+func TestOCSPResponderFailing(t *testing.T) {
+	responder := newOCSPResponder(t, func(w http.ResponseWriter, req *ocsp.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	})
+	root, intermediate, leaf := newOCSPChain(t, responder)
+
+	report, err := inspectAddr(serveTLS(t, nil, leaf, intermediate), "https", "swan.test", certPool(root), time.Now())
+	if err != nil {
+		t.Fatalf("inspectAddr() returned error: %v", err)
+	}
+	checkRevocation(report)
+
+	if len(report.OCSP) != 1 || !strings.Contains(report.OCSP[0].Error, "503") {
+		t.Errorf("Unexpected OCSP result: %+v", report.OCSP)
+	}
+	if problems := report.Problems(); len(problems) != 1 || !strings.Contains(problems[0], "OCSP check for swan.test failed") {
+		t.Errorf("Unexpected problems: %v", problems)
+	}
+}
+
+func TestOCSPStapled(t *testing.T) {
+	root, intermediate, leaf := newTestChain(t, "swan.test")
+
+	for _, tt := range []struct {
+		nextUpdate time.Time
+		problems   int
+	}{
+		{time.Now().Add(time.Hour), 0},
+		{time.Now().Add(-time.Minute), 1},
+	} {
+		state := tls.ConnectionState{
+			PeerCertificates: []*x509.Certificate{leaf.Cert, intermediate.Cert},
+			OCSPResponse:     newOCSPResponse(t, intermediate, leaf, ocsp.Good, tt.nextUpdate),
+		}
+		report, err := inspectState(state, "swan.test", certPool(root), time.Now())
+		if err != nil {
+			t.Fatalf("inspectState() returned error: %v", err)
+		}
+		checkRevocation(report)
+
+		if len(report.OCSP) != 1 || report.OCSP[0].Source != OCSPSourceStapled || report.OCSP[0].Status != "good" {
+			t.Errorf("Unexpected OCSP result: %+v", report.OCSP)
+		}
+		if problems := report.Problems(); len(problems) != tt.problems {
+			t.Errorf("Unexpected problems for next update %v: %v", tt.nextUpdate, problems)
+		}
+	}
+}
+
+func TestOCSPStapledUnknownAndUnorderedChain(t *testing.T) {
+	root, intermediate, leaf := newTestChain(t, "swan.test")
+
+	// The root is sent before the intermediate, so the issuer is not certs[1].
+	state := tls.ConnectionState{
+		PeerCertificates: []*x509.Certificate{leaf.Cert, root.Cert, intermediate.Cert},
+		OCSPResponse:     newOCSPResponse(t, intermediate, leaf, ocsp.Unknown, time.Now().Add(time.Hour)),
+	}
+	report, err := inspectState(state, "swan.test", certPool(root), time.Now())
+	if err != nil {
+		t.Fatalf("inspectState() returned error: %v", err)
+	}
+	checkRevocation(report)
+
+	if len(report.OCSP) != 1 || report.OCSP[0].Status != "unknown" || report.OCSP[0].Error != "" {
+		t.Errorf("Unexpected OCSP result: %+v", report.OCSP)
+	}
+	if problems := report.Problems(); len(problems) != 1 || !strings.Contains(problems[0], "status as unknown") {
+		t.Errorf("Unexpected problems: %v", problems)
+	}
+}
@@ -0,0 +1,148 @@
+package sslcert
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/swanchain/domain-check/pkg/model"
+)
+
+// GetPins returns the pins of each domain from the 'ssl-pin' rows.
+func GetPins(db *sqlx.DB) (map[string][]string, error) {
+	var configs []model.Info
+	err := db.Select(&configs, "SELECT key, value FROM info WHERE is_active = true AND type = 'ssl-pin'")
+	if err != nil {
+		return nil, err
+	}
+
+	pins := map[string][]string{}
+	for _, config := range configs {
+		for _, pin := range strings.Split(config.Value, ",") {
+			if pin = strings.TrimSpace(pin); pin != "" {
+				pins[config.Key] = append(pins[config.Key], pin)
+			}
+		}
+	}
+	return pins, nil
+}
+
+// CheckPins reports a problem if no certificate in the chain matches pins.
+func CheckPins(report *CertificateReport, pins []string) []string {
+	if len(pins) == 0 {
+		return nil
+	}
+	for _, pin := range pins {
+		fingerprint := strings.ReplaceAll(pin, ":", "")
+		for _, cert := range report.Chain {
+			if strings.EqualFold(fingerprint, cert.Fingerprint) || pin == cert.SPKIHash {
+				return nil
+			}
+		}
+	}
+	leaf := report.Leaf()
+	return []string{fmt.Sprintf("The certificate for %s (%s) does not match any pinned value (fingerprint %s, SPKI %s).",
+		report.Domain, report.Address, leaf.Fingerprint, leaf.SPKIHash)}
+}
+
+// CertificateChange is a new leaf certificate on a backend since the last run.
+type CertificateChange struct {
+	Domain         string
+	Address        string
+	OldFingerprint string
+	NewFingerprint string
+	OldNotAfter    time.Time
+	NewNotAfter    time.Time
+	Rotated        bool
+	Reason         string
+}
+
+func (c *CertificateChange) Message() string {
+	if c.Rotated {
+		return fmt.Sprintf("The certificate for %s (%s) was rotated. Old expiry: %s, new expiry: %s.",
+			c.Domain, c.Address, c.OldNotAfter.String(), c.NewNotAfter.String())
+	}
+	return fmt.Sprintf("The certificate for %s (%s) changed unexpectedly: %s. Fingerprint %s is now %s; expiry %s is now %s.",
+		c.Domain, c.Address, c.Reason, c.OldFingerprint, c.NewFingerprint, c.OldNotAfter.String(), c.NewNotAfter.String())
+}
+
+type fingerprintRow struct {
+	Fingerprint string    `db:"fingerprint"`
+	SPKIHash    string    `db:"spki_hash"`
+	Issuer      string    `db:"issuer"`
+	IssuerOrg   string    `db:"issuer_org"`
+	Root        string    `db:"root"`
+	NotAfter    time.Time `db:"not_after"`
+}
+
+// RecordFingerprint stores the leaf fingerprint and returns the change since
+// the previous run, or nil if there is none.
+func RecordFingerprint(db *sqlx.DB, report *CertificateReport, pins []string) (*CertificateChange, error) {
+	var previous fingerprintRow
+	err := db.Get(&previous, `
+		SELECT fingerprint, spki_hash, issuer, issuer_org, root, not_after FROM cert_fingerprint
+		WHERE domain = $1 AND address = $2
+		ORDER BY checked_at DESC LIMIT 1
+	`, report.Domain, report.Address)
+	hasPrevious := err == nil
+	if err != nil && err != sql.ErrNoRows {
+		log.Printf("Error retrieving previous certificate fingerprint for %s: %s", report.Domain, err)
+		return nil, err
+	}
+
+	leaf := report.Leaf()
+	_, err = db.Exec(`
+		INSERT INTO cert_fingerprint (domain, address, fingerprint, spki_hash, subject, issuer, issuer_org, root, not_after, checked_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`, report.Domain, report.Address, leaf.Fingerprint, leaf.SPKIHash, leaf.Subject, leaf.Issuer, leaf.IssuerOrganization, report.Root,
+		leaf.NotAfter, time.Now())
+	if err != nil {
+		log.Printf("Error recording certificate fingerprint for %s: %s", report.Domain, err)
+		return nil, err
+	}
+
+	if !hasPrevious || previous.Fingerprint == leaf.Fingerprint {
+		return nil, nil
+	}
+	return compareFingerprints(report, previous, pins), nil
+}
+
+// PruneFingerprints deletes the fingerprints of a backend checked before cutoff.
+func PruneFingerprints(db *sqlx.DB, domain, address string, cutoff time.Time) error {
+	_, err := db.Exec("DELETE FROM cert_fingerprint WHERE domain = $1 AND address = $2 AND checked_at < $3", domain, address, cutoff)
+	if err != nil {
+		log.Printf("Error pruning certificate fingerprints for %s: %s", domain, err)
+		return err
+	}
+	return nil
+}
+
+// compareFingerprints treats a new certificate as a renewal unless its CA
+// organization, root or pins changed. CAs rotate intermediates between
+// renewals, so the issuer name is not compared.
+func compareFingerprints(report *CertificateReport, previous fingerprintRow, pins []string) *CertificateChange {
+	leaf := report.Leaf()
+	change := &CertificateChange{
+		Domain:         report.Domain,
+		Address:        report.Address,
+		OldFingerprint: previous.Fingerprint,
+		NewFingerprint: leaf.Fingerprint,
+		OldNotAfter:    previous.NotAfter,
+		NewNotAfter:    leaf.NotAfter,
+	}
+
+	switch {
+	case previous.IssuerOrg != "" && leaf.IssuerOrganization != previous.IssuerOrg:
+		change.Reason = fmt.Sprintf("the issuing organization changed from %s to %s", previous.IssuerOrg, leaf.IssuerOrganization)
+	case previous.Root != "" && report.Root != previous.Root:
+		change.Reason = fmt.Sprintf("the root changed from %s to %s", previous.Root, report.Root)
+	case len(CheckPins(report, pins)) > 0:
+		change.Reason = "the new certificate does not match any pinned value"
+	default:
+		change.Rotated = true
+	}
+	return change
+}
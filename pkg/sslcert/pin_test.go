@@ -0,0 +1,175 @@
+package sslcert
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+)
+
+func newPinReport(t *testing.T, roots *x509.CertPool, chain ...*testCert) *CertificateReport {
+	t.Helper()
+
+	report, err := inspectAddr(serveTLS(t, nil, chain...), "https", "swan.test", roots, time.Now())
+	if err != nil {
+		t.Fatalf("inspectAddr() returned error: %v", err)
+	}
+	return report
+}
+
+func TestCheckPins(t *testing.T) {
+	root, intermediate, leaf := newTestChain(t, "swan.test")
+	report := newPinReport(t, certPool(root), leaf, intermediate)
+
+	sum := sha256.Sum256(leaf.Cert.Raw)
+	if report.Leaf().Fingerprint != hex.EncodeToString(sum[:]) {
+		t.Fatalf("Fingerprint = %s, want %x", report.Leaf().Fingerprint, sum)
+	}
+
+	tests := []struct {
+		name     string
+		pins     []string
+		problems int
+	}{
+		{"no pins", nil, 0},
+		{"leaf fingerprint with colons", []string{"AA:BB", strings.ToUpper(report.Leaf().Fingerprint[:2]) + ":" + report.Leaf().Fingerprint[2:]}, 0},
+		{"intermediate SPKI", []string{report.Chain[1].SPKIHash}, 0},
+		{"mismatch", []string{"sha256-of-something-else"}, 1},
+	}
+	for _, tt := range tests {
+		if problems := CheckPins(report, tt.pins); len(problems) != tt.problems {
+			t.Errorf("%s: unexpected problems %v", tt.name, problems)
+		}
+	}
+}
+
+func TestCompareFingerprints(t *testing.T) {
+	ca := []string{"Test CA"}
+	root := newTestCert(t, &x509.Certificate{Subject: pkix.Name{CommonName: "Test Root", Organization: ca}, IsCA: true}, nil)
+	r11 := newTestCert(t, &x509.Certificate{Subject: pkix.Name{CommonName: "R11", Organization: ca}, IsCA: true}, root)
+	leaf := newTestCert(t, &x509.Certificate{Subject: pkix.Name{CommonName: "swan.test"}, DNSNames: []string{"swan.test"}}, r11)
+	report := newPinReport(t, certPool(root), leaf, r11)
+
+	if report.Root != "CN=Test Root,O=Test CA" || report.Leaf().IssuerOrganization != "Test CA" {
+		t.Fatalf("Unexpected root %q and issuer organization %q", report.Root, report.Leaf().IssuerOrganization)
+	}
+
+	// The previous certificate came from the CA's other intermediate.
+	renewal := fingerprintRow{
+		Fingerprint: "old",
+		Issuer:      "CN=R10,O=Test CA",
+		IssuerOrg:   "Test CA",
+		Root:        report.Root,
+		NotAfter:    time.Now().Add(10 * 24 * time.Hour),
+	}
+	otherOrg, otherRoot, legacy := renewal, renewal, renewal
+	otherOrg.IssuerOrg = "Other CA"
+	otherRoot.Root = "CN=Other Root,O=Test CA"
+	legacy.IssuerOrg, legacy.Root = "", ""
+
+	tests := []struct {
+		name     string
+		previous fingerprintRow
+		pins     []string
+		reason   string
+	}{
+		{"new intermediate of the same CA", renewal, nil, ""},
+		{"matching pin", renewal, []string{report.Leaf().SPKIHash}, ""},
+		{"row without CA details", legacy, nil, ""},
+		{"issuing organization changed", otherOrg, nil, "issuing organization changed"},
+		{"root changed", otherRoot, nil, "root changed"},
+		{"pin mismatch", renewal, []string{"sha256-of-something-else"}, "does not match any pinned value"},
+	}
+	for _, tt := range tests {
+		change := compareFingerprints(report, tt.previous, tt.pins)
+		if tt.reason == "" && (!change.Rotated || !strings.Contains(change.Message(), "was rotated")) {
+			t.Errorf("%s: expected a rotation, got %s", tt.name, change.Message())
+		}
+		if tt.reason != "" && (change.Rotated || !strings.Contains(change.Reason, tt.reason)) {
+			t.Errorf("%s: expected an unexpected change, got %s", tt.name, change.Message())
+		}
+	}
+}
+
+func TestRecordFingerprint(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	root, intermediate, leaf := newTestChain(t, "swan.test")
+	report := newPinReport(t, certPool(root), leaf, intermediate)
+	columns := []string{"fingerprint", "spki_hash", "issuer", "issuer_org", "root", "not_after"}
+
+	mock.ExpectQuery("SELECT fingerprint, spki_hash, issuer, issuer_org, root, not_after FROM cert_fingerprint").
+		WithArgs(report.Domain, report.Address).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(report.Leaf().Fingerprint, report.Leaf().SPKIHash, report.Leaf().Issuer, "", report.Root, report.Leaf().NotAfter))
+	mock.ExpectExec("INSERT INTO cert_fingerprint").WillReturnResult(sqlmock.NewResult(1, 1))
+
+	change, err := RecordFingerprint(sqlxDB, report, nil)
+	if err != nil || change != nil {
+		t.Errorf("RecordFingerprint() = %+v, %v for an unchanged certificate", change, err)
+	}
+
+	mock.ExpectQuery("SELECT fingerprint, spki_hash, issuer, issuer_org, root, not_after FROM cert_fingerprint").
+		WithArgs(report.Domain, report.Address).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow("old", "old", report.Leaf().Issuer, "", report.Root, time.Now()))
+	mock.ExpectExec("INSERT INTO cert_fingerprint").WillReturnResult(sqlmock.NewResult(1, 1))
+
+	change, err = RecordFingerprint(sqlxDB, report, nil)
+	if err != nil || change == nil || !change.Rotated || change.OldFingerprint != "old" {
+		t.Errorf("RecordFingerprint() = %+v, %v for a renewed certificate", change, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unmet expectations: %v", err)
+	}
+}
+
+func TestPruneFingerprints(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	cutoff := time.Now().Add(-90 * 24 * time.Hour)
+	mock.ExpectExec("DELETE FROM cert_fingerprint WHERE domain = \\$1 AND address = \\$2 AND checked_at < \\$3").
+		WithArgs("https://swan.test/", "127.0.0.1:443", cutoff).WillReturnResult(sqlmock.NewResult(0, 2))
+	if err := PruneFingerprints(sqlx.NewDb(db, "sqlmock"), "https://swan.test/", "127.0.0.1:443", cutoff); err != nil {
+		t.Errorf("PruneFingerprints() returned error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unmet expectations: %v", err)
+	}
+}
+
+func TestGetPins(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	mock.ExpectQuery("SELECT key, value FROM info WHERE is_active = true AND type = 'ssl-pin'").
+		WillReturnRows(sqlmock.NewRows([]string{"key", "value"}).AddRow("https://swan.test", "aa, bb ,"))
+
+	pins, err := GetPins(sqlxDB)
+	if err != nil {
+		t.Fatalf("GetPins() returned error: %v", err)
+	}
+	if got := pins["https://swan.test"]; len(got) != 2 || got[0] != "aa" || got[1] != "bb" {
+		t.Errorf("Unexpected pins: %v", pins)
+	}
+}
@@ -1,20 +1,19 @@
 package sslcert
 
 import (
-	"bytes"
 	"crypto/tls"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"log"
 	"net"
-	"net/http"
 	"net/smtp"
-	"net/url"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/robfig/cron"
 	"github.com/swanchain/domain-check/pkg/model"
 )
 
@@ -28,15 +27,6 @@ type EmailConfig struct {
 	Pass string
 }
 
-type TeamsMessage struct {
-	Type     string `json:"@type"`
-	Context  string `json:"@context"`
-	Summary  string `json:"summary"`
-	Title    string `json:"title"`
-	Text     string `json:"text"`
-	Markdown bool   `json:"markdown"`
-}
-
 type loginAuth struct {
 	username, password string
 }
@@ -72,19 +62,125 @@ func GetDomains(db *sqlx.DB) ([]model.Info, error) {
 	return domains, nil
 }
 
+// CheckCertificate returns the earliest expiry date of the leaf certificates
+// served for domain. Use InspectCertificate for the rest of the chain.
 func CheckCertificate(domain string) (time.Time, error) {
-	u, err := url.Parse(domain)
+	reports, err := InspectCertificate(domain)
 	if err != nil {
 		return time.Time{}, err
 	}
-	conn, err := tls.Dial("tcp", u.Hostname()+":443", nil)
+
+	expireDate := reports[0].Leaf().NotAfter
+	for _, report := range reports[1:] {
+		if report.Leaf().NotAfter.Before(expireDate) {
+			expireDate = report.Leaf().NotAfter
+		}
+	}
+	return expireDate, nil
+}
+
+// SSLConfig controls when the SSL job runs and how far ahead of expiry it
+// starts warning.
+type SSLConfig struct {
+	Schedule       string
+	WarningWindows []time.Duration
+	Retention      time.Duration
+}
+
+func GetSSLConfig(db *sqlx.DB) (SSLConfig, error) {
+	cfg := SSLConfig{
+		Schedule:       "0 30 9 * * *",
+		WarningWindows: []time.Duration{30 * 24 * time.Hour, 14 * 24 * time.Hour, 7 * 24 * time.Hour, 2 * 24 * time.Hour},
+		Retention:      90 * 24 * time.Hour,
+	}
+
+	var configs []model.Info
+	err := db.Select(&configs, "SELECT key, value FROM info WHERE is_active = true AND type = 'ssl'")
 	if err != nil {
-		return time.Time{}, err
+		return cfg, err
 	}
-	defer conn.Close()
 
-	cert := conn.ConnectionState().PeerCertificates[0]
-	return cert.NotAfter, nil
+	for _, config := range configs {
+		switch config.Key {
+		case "schedule":
+			if _, err := cron.Parse(config.Value); err != nil {
+				log.Printf("Invalid SSL schedule %q, using %q: %v", config.Value, cfg.Schedule, err)
+				continue
+			}
+			cfg.Schedule = config.Value
+		case "warning-days":
+			windows, err := ParseWarningDays(config.Value)
+			if err != nil {
+				return cfg, err
+			}
+			cfg.WarningWindows = windows
+		case "retention-days":
+			days, err := strconv.Atoi(config.Value)
+			if err != nil || days < 1 {
+				return cfg, fmt.Errorf("invalid %s %q", config.Key, config.Value)
+			}
+			cfg.Retention = time.Duration(days) * 24 * time.Hour
+		}
+	}
+
+	return cfg, nil
+}
+
+// ParseWarningDays parses a comma separated list of days such as "30,14,7,2"
+// into warning windows sorted from widest to narrowest, for use with
+// WarningWindow.
+func ParseWarningDays(value string) ([]time.Duration, error) {
+	var windows []time.Duration
+	for _, field := range strings.Split(value, ",") {
+		days, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || days <= 0 {
+			return nil, fmt.Errorf("invalid warning-days %q", value)
+		}
+		windows = append(windows, time.Duration(days)*24*time.Hour)
+	}
+	sort.Slice(windows, func(i, j int) bool { return windows[i] > windows[j] })
+	return windows, nil
+}
+
+// WarningWindow returns the narrowest warning window that the time left until
+// expireDate falls into, and false if it is outside all of them.
+func WarningWindow(expireDate time.Time, now time.Time, windows []time.Duration) (time.Duration, bool) {
+	remaining := expireDate.Sub(now)
+	var window time.Duration
+	found := false
+	for _, w := range windows {
+		if remaining <= w && (!found || w < window) {
+			window = w
+			found = true
+		}
+	}
+	return window, found
+}
+
+// WarningLevel returns how many of the warning windows the time left until
+// expireDate falls into, plus one once it has expired, so it rises each time
+// a narrower window is crossed.
+func WarningLevel(expireDate time.Time, now time.Time, windows []time.Duration) int {
+	remaining := expireDate.Sub(now)
+	level := 0
+	for _, w := range windows {
+		if remaining <= w {
+			level++
+		}
+	}
+	if remaining <= 0 {
+		level++
+	}
+	return level
+}
+
+// ExpiryMessage describes a certificate that is inside a warning window.
+func ExpiryMessage(domain string, expireDate time.Time, now time.Time, window time.Duration) string {
+	if !expireDate.After(now) {
+		return fmt.Sprintf("The SSL certificate for %s expired on %s.\n", domain, expireDate.String())
+	}
+	return fmt.Sprintf("The SSL certificate for %s will expire on %s, in %s (%d day warning).\n",
+		domain, expireDate.String(), FormatDuration(expireDate.Sub(now)), int(window.Hours()/24))
 }
 
 func FormatDuration(d time.Duration) string {
@@ -97,91 +193,73 @@ func FormatDuration(d time.Duration) string {
 
 	return fmt.Sprintf("%d days %d hours %d minutes", days, h, min)
 }
-func SendEmail(emailConfig EmailConfig, recipient string, message string) {
+func SendEmail(emailConfig EmailConfig, recipient string, message string) error {
+	return SendEmailWithSubject(emailConfig, recipient, "SSL Certificate Expiration Warning", message)
+}
+
+// smtpTimeout bounds connecting to the SMTP server and then sending a message.
+const smtpTimeout = 30 * time.Second
+
+func SendEmailWithSubject(emailConfig EmailConfig, recipient string, subject string, message string) error {
 	from := emailConfig.User
 	pass := emailConfig.Pass
 	to := recipient
 
 	msg := "From: " + from + "\n" +
 		"To: " + to + "\n" +
-		"Subject: SSL Certificate Expiration Warning\n\n" +
+		"Subject: " + subject + "\n\n" +
 		message
 
 	tlsconfig := &tls.Config{
 		ServerName: "smtp.office365.com",
 	}
-	conn, err := net.Dial("tcp", "smtp.office365.com:587")
+	conn, err := net.DialTimeout("tcp", "smtp.office365.com:587", smtpTimeout)
 	if err != nil {
-		log.Panic(err)
+		return fmt.Errorf("net dial error: %s", err)
 	}
+	conn.SetDeadline(time.Now().Add(smtpTimeout))
 
 	c, err := smtp.NewClient(conn, "smtp.office365.com")
 	if err != nil {
-		log.Panic(err)
+		conn.Close()
+		return fmt.Errorf("smtp new client error: %s", err)
 	}
+	defer c.Close()
 
 	if err = c.StartTLS(tlsconfig); err != nil {
-		log.Panic(err)
+		return fmt.Errorf("start tls error: %s", err)
 	}
 
 	auth := LoginAuth(from, pass)
 	if err = c.Auth(auth); err != nil {
-		log.Panic(err)
+		return fmt.Errorf("auth error: %s", err)
 	}
 
 	if err = c.Mail(from); err != nil {
-		log.Panic(err)
+		return fmt.Errorf("mail error: %s", err)
 	}
 	if err = c.Rcpt(to); err != nil {
-		log.Panic(err)
+		return fmt.Errorf("rcpt error: %s", err)
 	}
 
 	wc, err := c.Data()
 	if err != nil {
-		log.Panic(err)
+		return fmt.Errorf("data error: %s", err)
 	}
 	_, err = wc.Write([]byte(msg))
 	if err != nil {
-		log.Panic(err)
+		return fmt.Errorf("write error: %s", err)
 	}
 	err = wc.Close()
 	if err != nil {
-		log.Panic(err)
+		return fmt.Errorf("close error: %s", err)
 	}
 
 	err = c.Quit()
 	if err != nil {
-		log.Panic(err)
+		return fmt.Errorf("quit error: %s", err)
 	}
 
 	log.Print("sent email to ", to)
-}
-
-func SendTeamsNotification(webhookURL string, message string, isMarkdown bool) {
-	msg := TeamsMessage{
-		Type:     "MessageCard",
-		Context:  "http://schema.org/extensions",
-		Summary:  "SSL Certificate Expiration Warning",
-		Title:    "SSL Certificate Expiration Warning",
-		Text:     message,
-		Markdown: isMarkdown,
-	}
-
-	msgBytes, err := json.Marshal(msg)
-	if err != nil {
-		log.Printf("json marshal error: %s", err)
-		return
-	}
-
-	resp, err := http.Post(webhookURL, "application/json", bytes.NewBuffer(msgBytes))
-	if err != nil {
-		log.Printf("http post error: %s", err)
-		return
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		log.Printf("teams webhook error: %s", bodyBytes)
-	}
+	return nil
 }
@@ -13,6 +13,7 @@ import (
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/jmoiron/sqlx"
+	"github.com/swanchain/domain-check/pkg/teams"
 )
 
 func TestCheckCertificate(t *testing.T) {
@@ -143,13 +144,7 @@ func (d *MockHTTPDoer) Do(req *http.Request) (*http.Response, error) {
 }
 
 func TestableSendTeamsNotification(d HTTPDoer, webhookURL string, message string) {
-	msg := TeamsMessage{
-		Type:    "MessageCard",
-		Context: "http://schema.org/extensions",
-		Summary: "SSL Certificate Expiration Warning",
-		Title:   "SSL Certificate Expiration Warning",
-		Text:    message,
-	}
+	msg := teams.NewCard("SSL Certificate Expiration Warning", message, teams.StyleWarning).Message()
 
 	msgBytes, err := json.Marshal(msg)
 	if err != nil {
@@ -188,3 +183,83 @@ func TestSendTeamsNotification(t *testing.T) {
 		t.Errorf("SendTeamsNotification did not call Do with the correct request")
 	}
 }
+
+func TestWarningWindow(t *testing.T) {
+	now := time.Now()
+	windows := []time.Duration{30 * 24 * time.Hour, 14 * 24 * time.Hour, 7 * 24 * time.Hour, 2 * 24 * time.Hour}
+
+	tests := []struct {
+		remaining time.Duration
+		window    time.Duration
+		ok        bool
+	}{
+		{45 * 24 * time.Hour, 0, false},
+		{20 * 24 * time.Hour, 30 * 24 * time.Hour, true},
+		{7 * 24 * time.Hour, 7 * 24 * time.Hour, true},
+		{36 * time.Hour, 2 * 24 * time.Hour, true},
+		{-time.Hour, 2 * 24 * time.Hour, true},
+	}
+
+	for _, tt := range tests {
+		window, ok := WarningWindow(now.Add(tt.remaining), now, windows)
+		if window != tt.window || ok != tt.ok {
+			t.Errorf("WarningWindow(%v) = %v, %v, want %v, %v", tt.remaining, window, ok, tt.window, tt.ok)
+		}
+	}
+}
+
+func TestWarningLevel(t *testing.T) {
+	now := time.Now()
+	windows := []time.Duration{30 * 24 * time.Hour, 14 * 24 * time.Hour, 7 * 24 * time.Hour, 2 * 24 * time.Hour}
+
+	tests := []struct {
+		remaining time.Duration
+		level     int
+	}{
+		{45 * 24 * time.Hour, 0},
+		{20 * 24 * time.Hour, 1},
+		{10 * 24 * time.Hour, 2},
+		{7 * 24 * time.Hour, 3},
+		{36 * time.Hour, 4},
+		{-time.Hour, 5},
+	}
+
+	for _, tt := range tests {
+		if level := WarningLevel(now.Add(tt.remaining), now, windows); level != tt.level {
+			t.Errorf("WarningLevel(%v) = %d, want %d", tt.remaining, level, tt.level)
+		}
+	}
+}
+
+func TestGetSSLConfig(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	rows := sqlmock.NewRows([]string{"key", "value"}).
+		AddRow("schedule", "0 0 8 * * *").
+		AddRow("warning-days", "3, 21,10").
+		AddRow("retention-days", "30")
+	mock.ExpectQuery("SELECT key, value FROM info WHERE is_active = true AND type = 'ssl'").WillReturnRows(rows)
+
+	cfg, err := GetSSLConfig(sqlxDB)
+	if err != nil {
+		t.Fatalf("GetSSLConfig() returned error: %v", err)
+	}
+
+	want := []time.Duration{21 * 24 * time.Hour, 10 * 24 * time.Hour, 3 * 24 * time.Hour}
+	if cfg.Schedule != "0 0 8 * * *" || len(cfg.WarningWindows) != 3 || cfg.WarningWindows[0] != want[0] || cfg.WarningWindows[2] != want[2] || cfg.Retention != 30*24*time.Hour {
+		t.Errorf("Unexpected config: %+v", cfg)
+	}
+
+	// A schedule that does not parse keeps the default.
+	mock.ExpectQuery("SELECT key, value FROM info WHERE is_active = true AND type = 'ssl'").
+		WillReturnRows(sqlmock.NewRows([]string{"key", "value"}).AddRow("schedule", "0 30 9 * *  * *"))
+	if cfg, err = GetSSLConfig(sqlxDB); err != nil || cfg.Schedule != "0 30 9 * * *" {
+		t.Errorf("GetSSLConfig() = %+v, %v for an invalid schedule", cfg, err)
+	}
+}
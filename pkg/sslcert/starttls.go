@@ -0,0 +1,155 @@
+package sslcert
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"strings"
+)
+
+// startTLSFunc runs the plaintext part of a protocol up to the point where
+// the server expects a TLS handshake on conn.
+type startTLSFunc func(conn net.Conn) error
+
+var startTLSProtocols = map[string]startTLSFunc{
+	"smtp":     smtpStartTLS,
+	"imap":     imapStartTLS,
+	"pop3":     pop3StartTLS,
+	"ftp":      ftpAuthTLS,
+	"postgres": postgresSSLRequest,
+}
+
+// defaultPorts maps each supported URL scheme to the port used when a domain
+// entry does not name one. Schemes without a STARTTLS step speak TLS directly.
+var defaultPorts = map[string]string{
+	"https":    "443",
+	"tls":      "443",
+	"smtp":     "587",
+	"smtps":    "465",
+	"imap":     "143",
+	"imaps":    "993",
+	"pop3":     "110",
+	"pop3s":    "995",
+	"ftp":      "21",
+	"ftps":     "990",
+	"postgres": "5432",
+}
+
+func smtpStartTLS(conn net.Conn) error {
+	text := textproto.NewConn(nopCloser{conn})
+	if _, _, err := text.ReadResponse(220); err != nil {
+		return fmt.Errorf("smtp greeting: %v", err)
+	}
+	if err := text.PrintfLine("EHLO domain-check"); err != nil {
+		return err
+	}
+	_, msg, err := text.ReadResponse(250)
+	if err != nil {
+		return fmt.Errorf("smtp EHLO: %v", err)
+	}
+	if !strings.Contains(strings.ToUpper(msg), "STARTTLS") {
+		return fmt.Errorf("smtp server does not offer STARTTLS")
+	}
+	if err := text.PrintfLine("STARTTLS"); err != nil {
+		return err
+	}
+	if _, _, err := text.ReadResponse(220); err != nil {
+		return fmt.Errorf("smtp STARTTLS: %v", err)
+	}
+	return nil
+}
+
+func imapStartTLS(conn net.Conn) error {
+	text := textproto.NewConn(nopCloser{conn})
+	greeting, err := text.ReadLine()
+	if err != nil {
+		return fmt.Errorf("imap greeting: %v", err)
+	}
+	if !strings.HasPrefix(greeting, "* OK") {
+		return fmt.Errorf("imap greeting: %s", greeting)
+	}
+	if err := text.PrintfLine("a001 STARTTLS"); err != nil {
+		return err
+	}
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return fmt.Errorf("imap STARTTLS: %v", err)
+		}
+		if !strings.HasPrefix(line, "a001 ") {
+			continue
+		}
+		if !strings.HasPrefix(line, "a001 OK") {
+			return fmt.Errorf("imap STARTTLS: %s", line)
+		}
+		return nil
+	}
+}
+
+func pop3StartTLS(conn net.Conn) error {
+	text := textproto.NewConn(nopCloser{conn})
+	greeting, err := text.ReadLine()
+	if err != nil {
+		return fmt.Errorf("pop3 greeting: %v", err)
+	}
+	if !strings.HasPrefix(greeting, "+OK") {
+		return fmt.Errorf("pop3 greeting: %s", greeting)
+	}
+	if err := text.PrintfLine("STLS"); err != nil {
+		return err
+	}
+	line, err := text.ReadLine()
+	if err != nil {
+		return fmt.Errorf("pop3 STLS: %v", err)
+	}
+	if !strings.HasPrefix(line, "+OK") {
+		return fmt.Errorf("pop3 STLS: %s", line)
+	}
+	return nil
+}
+
+func ftpAuthTLS(conn net.Conn) error {
+	text := textproto.NewConn(nopCloser{conn})
+	if _, _, err := text.ReadResponse(220); err != nil {
+		return fmt.Errorf("ftp greeting: %v", err)
+	}
+	if err := text.PrintfLine("AUTH TLS"); err != nil {
+		return err
+	}
+	if _, _, err := text.ReadResponse(234); err != nil {
+		return fmt.Errorf("ftp AUTH TLS: %v", err)
+	}
+	return nil
+}
+
+// postgresSSLRequest sends the SSLRequest startup message. The server answers
+// with a single byte: 'S' to continue with TLS or 'N' to refuse.
+func postgresSSLRequest(conn net.Conn) error {
+	request := make([]byte, 8)
+	binary.BigEndian.PutUint32(request[0:4], 8)
+	binary.BigEndian.PutUint32(request[4:8], 80877103)
+	if _, err := conn.Write(request); err != nil {
+		return err
+	}
+
+	response := make([]byte, 1)
+	if _, err := io.ReadFull(conn, response); err != nil {
+		return fmt.Errorf("postgres SSLRequest: %v", err)
+	}
+	if response[0] != 'S' {
+		return fmt.Errorf("postgres server does not support SSL")
+	}
+	return nil
+}
+
+// nopCloser keeps textproto from owning the connection, which is handed on to
+// the TLS client once the plaintext exchange is done. The exchanges above end
+// with the server waiting for the client, so nothing is left in textproto's
+// read buffer.
+type nopCloser struct {
+	io.ReadWriter
+}
+
+func (nopCloser) Close() error { return nil }
@@ -0,0 +1,164 @@
+package sslcert
+
+import (
+	"bufio"
+	"crypto/tls"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// serveStartTLS accepts one connection, runs the plaintext dialog and then
+// completes a TLS handshake with the given chain if the dialog returns true.
+func serveStartTLS(t *testing.T, dialog func(conn net.Conn, r *bufio.Reader) bool, chain ...*testCert) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	certificate := tls.Certificate{PrivateKey: chain[0].Key}
+	for _, c := range chain {
+		certificate.Certificate = append(certificate.Certificate, c.Cert.Raw)
+	}
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		if !dialog(conn, bufio.NewReader(conn)) {
+			return
+		}
+		tls.Server(conn, &tls.Config{Certificates: []tls.Certificate{certificate}}).Handshake()
+	}()
+
+	return listener.Addr().String()
+}
+
+func expectLine(r *bufio.Reader, want string) bool {
+	line, err := r.ReadString('\n')
+	return err == nil && strings.TrimRight(line, "\r\n") == want
+}
+
+func TestStartTLSProtocols(t *testing.T) {
+	tests := []struct {
+		protocol string
+		dialog   func(conn net.Conn, r *bufio.Reader) bool
+	}{
+		{"smtp", func(conn net.Conn, r *bufio.Reader) bool {
+			io.WriteString(conn, "220-mail.swan.test ESMTP\r\n220 ready\r\n")
+			if !expectLine(r, "EHLO domain-check") {
+				return false
+			}
+			io.WriteString(conn, "250-mail.swan.test\r\n250-SIZE 1000000\r\n250 STARTTLS\r\n")
+			if !expectLine(r, "STARTTLS") {
+				return false
+			}
+			io.WriteString(conn, "220 go ahead\r\n")
+			return true
+		}},
+		{"imap", func(conn net.Conn, r *bufio.Reader) bool {
+			io.WriteString(conn, "* OK [CAPABILITY IMAP4rev1 STARTTLS] ready\r\n")
+			if !expectLine(r, "a001 STARTTLS") {
+				return false
+			}
+			io.WriteString(conn, "a001 OK Begin TLS negotiation now\r\n")
+			return true
+		}},
+		{"pop3", func(conn net.Conn, r *bufio.Reader) bool {
+			io.WriteString(conn, "+OK POP3 ready\r\n")
+			if !expectLine(r, "STLS") {
+				return false
+			}
+			io.WriteString(conn, "+OK Begin TLS negotiation\r\n")
+			return true
+		}},
+		{"ftp", func(conn net.Conn, r *bufio.Reader) bool {
+			io.WriteString(conn, "220 FTP ready\r\n")
+			if !expectLine(r, "AUTH TLS") {
+				return false
+			}
+			io.WriteString(conn, "234 AUTH TLS successful\r\n")
+			return true
+		}},
+		{"postgres", func(conn net.Conn, r *bufio.Reader) bool {
+			request := make([]byte, 8)
+			if _, err := io.ReadFull(r, request); err != nil || string(request) != "\x00\x00\x00\x08\x04\xd2\x16\x2f" {
+				return false
+			}
+			conn.Write([]byte("S"))
+			return true
+		}},
+	}
+
+	root, intermediate, leaf := newTestChain(t, "swan.test")
+	for _, tt := range tests {
+		addr := serveStartTLS(t, tt.dialog, leaf, intermediate)
+
+		report, err := inspectAddr(addr, tt.protocol, "swan.test", certPool(root), time.Now())
+		if err != nil {
+			t.Errorf("%s: inspectAddr() returned error: %v", tt.protocol, err)
+			continue
+		}
+		if problems := report.Problems(); len(problems) != 0 || report.Leaf().Subject != "CN=swan.test" {
+			t.Errorf("%s: unexpected report %+v, problems %v", tt.protocol, report, problems)
+		}
+	}
+}
+
+func TestStartTLSRefused(t *testing.T) {
+	root, intermediate, leaf := newTestChain(t, "swan.test")
+
+	addr := serveStartTLS(t, func(conn net.Conn, r *bufio.Reader) bool {
+		io.WriteString(conn, "220 ready\r\n")
+		expectLine(r, "EHLO domain-check")
+		io.WriteString(conn, "250-mail.swan.test\r\n250 SIZE 1000000\r\n")
+		return false
+	}, leaf, intermediate)
+	if _, err := inspectAddr(addr, "smtp", "swan.test", certPool(root), time.Now()); err == nil || !strings.Contains(err.Error(), "does not offer STARTTLS") {
+		t.Errorf("Unexpected error for smtp without STARTTLS: %v", err)
+	}
+
+	addr = serveStartTLS(t, func(conn net.Conn, r *bufio.Reader) bool {
+		io.ReadFull(r, make([]byte, 8))
+		conn.Write([]byte("N"))
+		return false
+	}, leaf, intermediate)
+	if _, err := inspectAddr(addr, "postgres", "swan.test", certPool(root), time.Now()); err == nil || !strings.Contains(err.Error(), "does not support SSL") {
+		t.Errorf("Unexpected error for postgres without SSL: %v", err)
+	}
+}
+
+func TestParseTargetProtocols(t *testing.T) {
+	tests := map[string]struct{ protocol, port string }{
+		"smtp://mail.swan.test":            {"smtp", "587"},
+		"smtp://mail.swan.test:25":         {"smtp", "25"},
+		"imaps://mail.swan.test":           {"imaps", "993"},
+		"postgresql://db.swan.test":        {"postgres", "5432"},
+		"ftp://files.swan.test":            {"ftp", "21"},
+		"http://swan.test":                 {"https", "443"},
+		"pop3://mail.swan.test/?sni=other": {"pop3", "110"},
+	}
+
+	for domain, want := range tests {
+		target, err := ParseTarget(domain)
+		if err != nil {
+			t.Errorf("ParseTarget(%q) returned error: %v", domain, err)
+			continue
+		}
+		if target.Protocol != want.protocol || target.Port != want.port {
+			t.Errorf("ParseTarget(%q) = %s:%s, want %s:%s", domain, target.Protocol, target.Port, want.protocol, want.port)
+		}
+	}
+
+	if _, err := ParseTarget("gopher://swan.test"); err == nil {
+		t.Errorf("ParseTarget() accepted an unsupported protocol")
+	}
+}
@@ -0,0 +1,138 @@
+package sslcert
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// Target describes where a domain's certificate is fetched from. Domain
+// entries are URLs; the port is taken from the URL and the remaining options
+// from its query string:
+//
+//	https://swanchain.io:8443/
+//	https://swanchain.io/?sni=api.swanchain.io
+//	https://swanchain.io/?ip=203.0.113.10&ip=203.0.113.11
+//	https://swanchain.io/?resolve=all
+//
+// With resolve=all every A and AAAA record of the host is checked separately.
+//
+// The URL scheme selects the protocol spoken before the TLS handshake, so mail
+// and database endpoints can be checked too:
+//
+//	smtp://mail.swanchain.io        STARTTLS on port 587
+//	imap://mail.swanchain.io        STARTTLS on port 143
+//	pop3://mail.swanchain.io        STLS on port 110
+//	ftp://files.swanchain.io        AUTH TLS on port 21
+//	postgres://db.swanchain.io      SSLRequest on port 5432
+//	smtps://, imaps://, pop3s://, ftps://, tls://  TLS from the first byte
+//
+// Entries without a scheme are treated as https.
+type Target struct {
+	Domain     string
+	Protocol   string
+	Host       string
+	Port       string
+	SNI        string
+	IPs        []string
+	ResolveAll bool
+}
+
+var lookupIPAddr = net.DefaultResolver.LookupIPAddr
+
+func ParseTarget(domain string) (Target, error) {
+	raw := domain
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return Target{}, err
+	}
+	if u.Hostname() == "" {
+		return Target{}, fmt.Errorf("no host in domain %q", domain)
+	}
+
+	protocol := strings.ToLower(u.Scheme)
+	switch protocol {
+	case "http":
+		protocol = "https"
+	case "postgresql":
+		protocol = "postgres"
+	}
+	if _, ok := defaultPorts[protocol]; !ok {
+		return Target{}, fmt.Errorf("unsupported protocol %q in domain %q", u.Scheme, domain)
+	}
+
+	query := u.Query()
+	target := Target{
+		Domain:     domain,
+		Protocol:   protocol,
+		Host:       u.Hostname(),
+		Port:       u.Port(),
+		SNI:        query.Get("sni"),
+		ResolveAll: query.Get("resolve") == "all",
+	}
+	if target.Port == "" {
+		target.Port = defaultPorts[protocol]
+	}
+	if target.SNI == "" {
+		target.SNI = target.Host
+	}
+
+	for _, ip := range query["ip"] {
+		if net.ParseIP(ip) == nil {
+			return Target{}, fmt.Errorf("invalid ip %q in domain %q", ip, domain)
+		}
+		target.IPs = append(target.IPs, ip)
+	}
+
+	return target, nil
+}
+
+// Addrs returns the host:port addresses to check for the target: the
+// configured IPs, every resolved address when ResolveAll is set, or just the
+// host otherwise.
+func (t Target) Addrs(ctx context.Context) ([]string, error) {
+	hosts := t.IPs
+	if len(hosts) == 0 && t.ResolveAll {
+		addrs, err := lookupIPAddr(ctx, t.Host)
+		if err != nil {
+			return nil, err
+		}
+		for _, addr := range addrs {
+			hosts = append(hosts, addr.IP.String())
+		}
+	}
+	if len(hosts) == 0 {
+		hosts = []string{t.Host}
+	}
+
+	var addrs []string
+	for _, host := range hosts {
+		addrs = append(addrs, net.JoinHostPort(host, t.Port))
+	}
+	return addrs, nil
+}
+
+// CompareBackends reports when the backends behind one target do not all
+// serve the same leaf certificate.
+func CompareBackends(reports []*CertificateReport) []string {
+	if len(reports) < 2 {
+		return nil
+	}
+
+	var problems []string
+	first := reports[0]
+	for _, report := range reports[1:] {
+		if !bytes.Equal(report.certs[0].Raw, first.certs[0].Raw) {
+			problems = append(problems, fmt.Sprintf("%s serves a different certificate than %s for %s (expires %s vs %s).",
+				report.Address, first.Address, report.Domain, report.Leaf().NotAfter.String(), first.Leaf().NotAfter.String()))
+		}
+	}
+	return problems
+}
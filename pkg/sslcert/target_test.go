@@ -0,0 +1,92 @@
+package sslcert
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTarget(t *testing.T) {
+	tests := []struct {
+		domain string
+		want   Target
+	}{
+		{"https://swanchain.io/", Target{Host: "swanchain.io", Port: "443", SNI: "swanchain.io"}},
+		{"google.com", Target{Host: "google.com", Port: "443", SNI: "google.com"}},
+		{"https://swanchain.io:8443/?sni=api.swanchain.io", Target{Host: "swanchain.io", Port: "8443", SNI: "api.swanchain.io"}},
+		{"https://swanchain.io/?ip=203.0.113.10&ip=2001:db8::1", Target{Host: "swanchain.io", Port: "443", SNI: "swanchain.io", IPs: []string{"203.0.113.10", "2001:db8::1"}}},
+		{"https://swanchain.io/?resolve=all", Target{Host: "swanchain.io", Port: "443", SNI: "swanchain.io", ResolveAll: true}},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseTarget(tt.domain)
+		if err != nil {
+			t.Errorf("ParseTarget(%q) returned error: %v", tt.domain, err)
+			continue
+		}
+		if got.Domain != tt.domain || got.Host != tt.want.Host || got.Port != tt.want.Port || got.SNI != tt.want.SNI ||
+			got.ResolveAll != tt.want.ResolveAll || strings.Join(got.IPs, ",") != strings.Join(tt.want.IPs, ",") {
+			t.Errorf("ParseTarget(%q) = %+v, want %+v", tt.domain, got, tt.want)
+		}
+	}
+
+	if _, err := ParseTarget("https://swanchain.io/?ip=not-an-ip"); err == nil {
+		t.Errorf("ParseTarget() accepted an invalid ip")
+	}
+}
+
+func TestTargetAddrs(t *testing.T) {
+	lookupIPAddr = func(ctx context.Context, host string) ([]net.IPAddr, error) {
+		return []net.IPAddr{{IP: net.ParseIP("203.0.113.10")}, {IP: net.ParseIP("2001:db8::1")}}, nil
+	}
+	defer func() { lookupIPAddr = net.DefaultResolver.LookupIPAddr }()
+
+	target, _ := ParseTarget("https://swanchain.io:8443/?resolve=all")
+	addrs, err := target.Addrs(context.Background())
+	if err != nil {
+		t.Fatalf("Addrs() returned error: %v", err)
+	}
+	if strings.Join(addrs, ",") != "203.0.113.10:8443,[2001:db8::1]:8443" {
+		t.Errorf("Unexpected addrs: %v", addrs)
+	}
+
+	target, _ = ParseTarget("https://swanchain.io/")
+	addrs, _ = target.Addrs(context.Background())
+	if strings.Join(addrs, ",") != "swanchain.io:443" {
+		t.Errorf("Unexpected addrs: %v", addrs)
+	}
+}
+
+func TestInspectTargetPerBackend(t *testing.T) {
+	root, intermediate, leaf := newTestChain(t, "swan.test")
+	_, staleIntermediate, staleLeaf := newTestChain(t, "swan.test")
+
+	first := serveTLS(t, nil, leaf, intermediate)
+	_, port, _ := net.SplitHostPort(first)
+	second := serveTLSOn(t, net.JoinHostPort("127.0.0.2", port), nil, staleLeaf, staleIntermediate)
+
+	target, err := ParseTarget("https://swan.test:" + port + "/?ip=127.0.0.1&ip=127.0.0.2")
+	if err != nil {
+		t.Fatalf("ParseTarget() returned error: %v", err)
+	}
+
+	reports, err := inspectTarget(target, certPool(root), time.Now())
+	if err != nil {
+		t.Fatalf("inspectTarget() returned error: %v", err)
+	}
+	if len(reports) != 2 || reports[0].Address != first || reports[1].Address != second {
+		t.Fatalf("Unexpected reports: %+v", reports)
+	}
+
+	if len(reports[0].Problems()) != 0 {
+		t.Errorf("Unexpected problems for the first backend: %v", reports[0].Problems())
+	}
+	if !reports[1].UntrustedRoot {
+		t.Errorf("Expected the second backend to be untrusted: %+v", reports[1])
+	}
+	if problems := CompareBackends(reports); len(problems) != 1 || !strings.Contains(problems[0], second) {
+		t.Errorf("Unexpected backend comparison: %v", problems)
+	}
+}
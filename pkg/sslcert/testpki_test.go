@@ -0,0 +1,137 @@
+package sslcert
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+)
+
+// testCert is a certificate and its private key issued by newTestCert.
+type testCert struct {
+	Cert *x509.Certificate
+	Key  crypto.Signer
+}
+
+var testSerial int64 = 1
+
+// newTestCert issues a certificate from template, signed by parent or
+// self-signed if parent is nil. Unset key, serial and subject fields are
+// filled in.
+func newTestCert(t *testing.T, template *x509.Certificate, parent *testCert) *testCert {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	return newTestCertWithKey(t, template, parent, key)
+}
+
+// newTestCertWithKey is newTestCert for a caller-supplied key.
+func newTestCertWithKey(t *testing.T, template *x509.Certificate, parent *testCert, key crypto.Signer) *testCert {
+	t.Helper()
+
+	testSerial++
+	template.SerialNumber = big.NewInt(testSerial)
+	if template.NotBefore.IsZero() {
+		template.NotBefore = time.Now().Add(-time.Hour)
+	}
+	if template.NotAfter.IsZero() {
+		template.NotAfter = time.Now().Add(90 * 24 * time.Hour)
+	}
+	if template.IsCA {
+		template.BasicConstraintsValid = true
+		template.KeyUsage |= x509.KeyUsageCertSign
+	}
+
+	signerCert, signerKey := template, crypto.Signer(key)
+	if parent != nil {
+		signerCert, signerKey = parent.Cert, parent.Key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, signerCert, key.Public(), signerKey)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("Failed to parse certificate: %v", err)
+	}
+
+	return &testCert{Cert: cert, Key: key}
+}
+
+// newTestChain issues a root, an intermediate and a leaf for dnsName.
+func newTestChain(t *testing.T, dnsName string) (root, intermediate, leaf *testCert) {
+	root = newTestCert(t, &x509.Certificate{Subject: pkix.Name{CommonName: "Test Root"}, IsCA: true}, nil)
+	intermediate = newTestCert(t, &x509.Certificate{Subject: pkix.Name{CommonName: "Test Intermediate"}, IsCA: true}, root)
+	leaf = newTestCert(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: dnsName},
+		DNSNames:    []string{dnsName},
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, intermediate)
+	return root, intermediate, leaf
+}
+
+// serveTLS accepts TLS connections on a local port and completes the
+// handshake with the given chain, starting with the leaf.
+func serveTLS(t *testing.T, config *tls.Config, chain ...*testCert) string {
+	t.Helper()
+	return serveTLSOn(t, "127.0.0.1:0", config, chain...)
+}
+
+func serveTLSOn(t *testing.T, addr string, config *tls.Config, chain ...*testCert) string {
+	t.Helper()
+
+	certificate := tls.Certificate{PrivateKey: chain[0].Key, Leaf: chain[0].Cert}
+	for _, c := range chain {
+		certificate.Certificate = append(certificate.Certificate, c.Cert.Raw)
+	}
+	if config == nil {
+		config = &tls.Config{}
+	}
+	config.Certificates = []tls.Certificate{certificate}
+
+	listener, err := tls.Listen("tcp", addr, config)
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				conn.(*tls.Conn).Handshake()
+			}()
+		}
+	}()
+
+	return listener.Addr().String()
+}
+
+func certPool(certs ...*testCert) *x509.CertPool {
+	pool := x509.NewCertPool()
+	for _, c := range certs {
+		pool.AddCert(c.Cert)
+	}
+	return pool
+}
+
+func portOf(addr string) string {
+	_, port, _ := net.SplitHostPort(addr)
+	return port
+}
@@ -0,0 +1,143 @@
+// Package teams posts Adaptive Cards to Microsoft Teams. The payload is the
+// message with a card attachment that Power Automate Workflows webhooks
+// ("Post to a channel when a webhook request is received") accept, which
+// replace the retired Office 365 connectors and their MessageCards.
+package teams
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Styles color the title band of a card.
+const (
+	StyleDefault   = "default"
+	StyleGood      = "good"
+	StyleWarning   = "warning"
+	StyleAttention = "attention"
+	StyleAccent    = "accent"
+)
+
+// maxActions is the number of buttons Teams shows on a card.
+const maxActions = 6
+
+type Fact struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+}
+
+// Action is an Action.OpenUrl button.
+type Action struct {
+	Type  string `json:"type"`
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+// Element is a TextBlock, FactSet or Container of the card body. TextBlocks
+// render the Markdown the messages use: bold text, lists and links.
+type Element struct {
+	Type   string    `json:"type"`
+	Text   string    `json:"text,omitempty"`
+	Size   string    `json:"size,omitempty"`
+	Weight string    `json:"weight,omitempty"`
+	Wrap   bool      `json:"wrap,omitempty"`
+	Style  string    `json:"style,omitempty"`
+	Bleed  bool      `json:"bleed,omitempty"`
+	Items  []Element `json:"items,omitempty"`
+	Facts  []Fact    `json:"facts,omitempty"`
+}
+
+type Card struct {
+	Schema  string    `json:"$schema"`
+	Type    string    `json:"type"`
+	Version string    `json:"version"`
+	Body    []Element `json:"body"`
+	Actions []Action  `json:"actions,omitempty"`
+	MSTeams struct {
+		Width string `json:"width"`
+	} `json:"msteams"`
+}
+
+type Attachment struct {
+	ContentType string  `json:"contentType"`
+	ContentURL  *string `json:"contentUrl"`
+	Content     Card    `json:"content"`
+}
+
+type Message struct {
+	Type        string       `json:"type"`
+	Attachments []Attachment `json:"attachments"`
+}
+
+// NewCard returns a card with title in a band colored by style and text
+// below it.
+func NewCard(title, text, style string) *Card {
+	card := &Card{
+		Schema:  "http://adaptivecards.io/schemas/adaptive-card.json",
+		Type:    "AdaptiveCard",
+		Version: "1.4",
+		Body: []Element{{
+			Type:  "Container",
+			Style: style,
+			Bleed: true,
+			Items: []Element{{Type: "TextBlock", Text: title, Size: "Large", Weight: "Bolder", Wrap: true}},
+		}},
+	}
+	card.MSTeams.Width = "Full"
+	if text != "" {
+		card.Body = append(card.Body, Element{Type: "TextBlock", Text: text, Wrap: true})
+	}
+	return card
+}
+
+// AddFacts adds a table of facts below what the card already shows.
+func (c *Card) AddFacts(facts ...Fact) *Card {
+	if len(facts) > 0 {
+		c.Body = append(c.Body, Element{Type: "FactSet", Facts: facts})
+	}
+	return c
+}
+
+// AddLink adds a button opening url. Links without a URL and beyond the
+// number Teams shows are left out.
+func (c *Card) AddLink(title, url string) *Card {
+	if url != "" && len(c.Actions) < maxActions {
+		c.Actions = append(c.Actions, Action{Type: "Action.OpenUrl", Title: title, URL: url})
+	}
+	return c
+}
+
+// Message wraps the card in the message a webhook takes.
+func (c *Card) Message() Message {
+	return Message{
+		Type:        "message",
+		Attachments: []Attachment{{ContentType: "application/vnd.microsoft.card.adaptive", Content: *c}},
+	}
+}
+
+var client = &http.Client{Timeout: 30 * time.Second}
+
+// Send posts the card to a Workflows webhook. Workflows answer 202 Accepted
+// and connectors 200 OK, so any 2xx status is a success.
+func Send(webhookURL string, card *Card) error {
+	msgBytes, err := json.Marshal(card.Message())
+	if err != nil {
+		return fmt.Errorf("json marshal error: %s", err)
+	}
+
+	resp, err := client.Post(webhookURL, "application/json", bytes.NewBuffer(msgBytes))
+	if err != nil {
+		return fmt.Errorf("http post error: %s", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("teams webhook error: %s %s", resp.Status, bodyBytes)
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package teams
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestSend(t *testing.T) {
+	var msg Message
+	status := http.StatusAccepted
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&msg)
+		w.WriteHeader(status)
+		w.Write([]byte(`{"error":{"code":"WorkflowTriggerIsNotEnabled"}}`))
+	}))
+	defer server.Close()
+
+	card := NewCard("Wallet Balance Change Update", "", StyleAccent).
+		AddFacts(Fact{Title: "0xabc (swan)", Value: "1.500000 (change -0.250000)"}).
+		AddLink("View 0xabc", "https://explorer.swanchain.io/address/0xabc").
+		AddLink("Not configured", "")
+	for i := 0; i < 10; i++ {
+		card.AddLink("Link "+strconv.Itoa(i), "https://explorer.swanchain.io")
+	}
+	if err := Send(server.URL, card); err != nil {
+		t.Fatalf("Send() = %v", err)
+	}
+
+	if msg.Type != "message" || len(msg.Attachments) != 1 || msg.Attachments[0].ContentType != "application/vnd.microsoft.card.adaptive" {
+		t.Fatalf("Unexpected message %+v", msg)
+	}
+	sent := msg.Attachments[0].Content
+	if sent.Type != "AdaptiveCard" || len(sent.Body) != 2 || sent.Body[0].Style != StyleAccent || sent.Body[1].Facts[0].Title != "0xabc (swan)" {
+		t.Errorf("Unexpected card %+v", sent)
+	}
+	if len(sent.Actions) != maxActions || sent.Actions[0].Type != "Action.OpenUrl" || sent.Actions[0].URL != "https://explorer.swanchain.io/address/0xabc" {
+		t.Errorf("Unexpected actions %+v", sent.Actions)
+	}
+
+	status = http.StatusBadRequest
+	if err := Send(server.URL, NewCard("Chain Status Warning", "rpc timeout", StyleAttention)); err == nil {
+		t.Errorf("Send() succeeded with status %d", status)
+	}
+}
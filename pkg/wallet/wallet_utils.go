@@ -15,17 +15,19 @@ import (
 	"net/http"
 	"net/smtp"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/swanchain/domain-check/pkg/alert"
 	"github.com/swanchain/domain-check/pkg/model"
 )
 
 var (
-	sepolia_rpc string
-	swan_rpc    string
-	//swan_block_explorer    string
-	//sepolia_block_explorer string
+	sepolia_rpc            string
+	swan_rpc               string
+	swan_block_explorer    string
+	sepolia_block_explorer string
 )
 
 type rpcRequest struct {
@@ -46,13 +48,12 @@ type EmailConfig struct {
 	Pass string
 }
 
-type TeamsMessage struct {
-	Type     string `json:"@type"`
-	Context  string `json:"@context"`
-	Summary  string `json:"summary"`
-	Title    string `json:"title"`
-	Text     string `json:"text"`
-	Markdown bool   `json:"markdown"`
+// BalanceFact is a wallet's row of the balance summary.
+func BalanceFact(network, address string, balance, change float64) alert.Fact {
+	return alert.Fact{
+		Title: fmt.Sprintf("%s (%s)", address, network),
+		Value: fmt.Sprintf("%f (change %+f)", balance, change),
+	}
 }
 
 type loginAuth struct {
@@ -63,6 +64,27 @@ func GetSwanRPC() string {
 	return swan_rpc
 }
 
+func GetSepoliaRPC() string {
+	return sepolia_rpc
+}
+
+// ExplorerURL returns the block explorer page of address on network, or the
+// explorer itself without an address. It is empty if the network has no
+// explorer configured.
+func ExplorerURL(network, address string) string {
+	var explorer string
+	switch network {
+	case "swan":
+		explorer = swan_block_explorer
+	case "sepolia":
+		explorer = sepolia_block_explorer
+	}
+	if explorer == "" || address == "" {
+		return explorer
+	}
+	return explorer + "/address/" + address
+}
+
 func LoginAuth(username, password string) smtp.Auth {
 	return &loginAuth{username, password}
 }
@@ -114,10 +136,10 @@ func SetExplorerAndRpcVars(db *sqlx.DB) error {
 			sepolia_rpc = config.Value
 		case "saturn-rpc":
 			swan_rpc = config.Value
-			//case "saturn-block-explorer":
-			//	swan_block_explorer = config.Value
-			//case "sepolia-block-explorer":
-			//	sepolia_block_explorer = config.Value
+		case "saturn-block-explorer":
+			swan_block_explorer = strings.TrimRight(config.Value, "/")
+		case "sepolia-block-explorer":
+			sepolia_block_explorer = strings.TrimRight(config.Value, "/")
 		}
 	}
 
@@ -328,32 +350,3 @@ func SendWalletBalanceEmail(emailConfig EmailConfig, recipient string, message s
 	log.Print("sent email to ", to)
 	return nil
 }
-
-func SendTeamsNotification(webhookURL string, message string, isMarkdown bool) error {
-	msg := TeamsMessage{
-		Type:     "MessageCard",
-		Context:  "http://schema.org/extensions",
-		Summary:  "Wallet Balance Change Update",
-		Title:    "Wallet Balance Change Update",
-		Text:     message,
-		Markdown: isMarkdown,
-	}
-
-	msgBytes, err := json.Marshal(msg)
-	if err != nil {
-		return fmt.Errorf("json marshal error: %s", err)
-	}
-
-	resp, err := http.Post(webhookURL, "application/json", bytes.NewBuffer(msgBytes))
-	if err != nil {
-		return fmt.Errorf("http post error: %s", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("teams webhook error: %s", bodyBytes)
-	}
-
-	return nil
-}